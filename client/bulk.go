@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+const contentTypeCSV = "text/csv"
+
+// Import modes, see ImportOptions.
+const (
+	ImportModeCreateOnly = "create-only"
+	ImportModeUpsert     = "upsert"
+	ImportModeReplaceAll = "replace-all"
+)
+
+type ImportOptions struct {
+	// Mode is one of the import modes. It's create-only if empty.
+	Mode string
+	// CSV reads the body as CSV rather than NDJSON.
+	CSV bool
+}
+
+type ImportLineError struct {
+	Line    int    `json:"line"`
+	Name    string `json:"name,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+type ImportResult struct {
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Deleted int               `json:"deleted"`
+	Failed  int               `json:"failed"`
+	Errors  []ImportLineError `json:"errors"`
+}
+
+// Export writes all items of the type to w as NDJSON.
+func (c *Client[T]) Export(ctx context.Context, w io.Writer) error {
+	return c.copy(ctx, c.path("")+"/_export", w)
+}
+
+// Import creates or updates items of the type from r, which is NDJSON, or CSV if opts.CSV is set.
+func (c *Client[T]) Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	query := make(url.Values)
+
+	if opts.Mode != "" {
+		query.Set("mode", opts.Mode)
+	}
+
+	contentType := contentTypeNDJSON
+	if opts.CSV {
+		contentType = contentTypeCSV
+	}
+
+	var res ImportResult
+
+	err := c.do(ctx, http.MethodPost, c.path("")+"/_import", query, contentType, r, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// ExportAll writes items of all types on the API at baseURL to w as NDJSON.
+func ExportAll(ctx context.Context, baseURL string, w io.Writer, opts ...Option) error {
+	return New[Item](baseURL, "", opts...).copy(ctx, "/_export", w)
+}
+
+func (c *Client[T]) copy(ctx context.Context, path string, w io.Writer) error {
+	rsp, err := c.send(ctx, http.MethodGet, path, nil, "", nil, contentTypeNDJSON)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = rsp.Body.Close() }()
+
+	_, err = io.Copy(w, rsp.Body)
+	if err != nil {
+		return errors.Wrap(err, "error on read export")
+	}
+
+	return nil
+}
@@ -0,0 +1,327 @@
+// Package client is a Go client of the item API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gertd/go-pluralize"
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/pkg/errors"
+)
+
+const (
+	contentTypeJSON       = "application/json"
+	contentTypeApplyPatch = "application/apply-patch+yaml"
+	contentTypeJSONPatch  = "application/json-patch+json"
+	contentTypeMergePatch = "application/merge-patch+json"
+	contentTypeNDJSON     = "application/x-ndjson"
+)
+
+// Item is an item as the API returns it, with data next to the metadata fields.
+type Item = core.Item
+
+type Option func(*options)
+
+type options struct {
+	httpClient   *http.Client
+	header       http.Header
+	fieldManager string
+}
+
+// WithHTTPClient sets the HTTP client requests are sent with. It's http.DefaultClient by default.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithHeader adds a header to all requests, like an authorization header.
+func WithHeader(key, value string) Option {
+	return func(o *options) {
+		o.header.Add(key, value)
+	}
+}
+
+// WithFieldManager sets the field manager writes are sent as. Fields it writes are owned by it, and the API rejects
+// writes of other field managers to them with an error that wraps ErrConflict.
+func WithFieldManager(name string) Option {
+	return func(o *options) {
+		o.fieldManager = name
+	}
+}
+
+// Client works with items of one type. T is Item, or a struct that items decode into,
+// with data fields next to metadata fields like name.
+type Client[T any] struct {
+	baseURL      string
+	typePlural   string
+	httpClient   *http.Client
+	header       http.Header
+	fieldManager string
+}
+
+// New returns a client of items of the type, which is singular, like "drink", on the API at baseURL.
+func New[T any](baseURL, typ string, opts ...Option) *Client[T] {
+	o := options{
+		httpClient:   http.DefaultClient,
+		header:       make(http.Header),
+		fieldManager: "",
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Client[T]{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		typePlural:   pluralize.NewClient().Plural(typ),
+		httpClient:   o.httpClient,
+		header:       o.header,
+		fieldManager: o.fieldManager,
+	}
+}
+
+// Create creates an item. The name of the item is taken from the name field of v.
+func (c *Client[T]) Create(ctx context.Context, v T) (*T, error) {
+	var res T
+
+	err := c.do(ctx, http.MethodPost, c.path(""), c.writeQuery(), contentTypeJSON, v, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (c *Client[T]) Get(ctx context.Context, name string) (*T, error) {
+	var res T
+
+	err := c.do(ctx, http.MethodGet, c.path(name), nil, "", nil, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// Replace replaces data of the item with data of v.
+func (c *Client[T]) Replace(ctx context.Context, name string, v T) (*T, error) {
+	var res T
+
+	err := c.do(ctx, http.MethodPut, c.path(name), c.writeQuery(), contentTypeJSON, v, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// MergePatch applies a JSON merge patch, like a map or a struct with omitempty fields, to the item.
+func (c *Client[T]) MergePatch(ctx context.Context, name string, patch interface{}) (*T, error) {
+	var res T
+
+	err := c.do(ctx, http.MethodPatch, c.path(name), c.writeQuery(), contentTypeMergePatch, patch, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// PatchOperation is an operation of a JSON patch.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON keeps the value of operations that take one even if it's null.
+func (op PatchOperation) MarshalJSON() ([]byte, error) {
+	type operation PatchOperation
+
+	switch op.Op {
+	case "add", "replace", "test":
+		b, err := json.Marshal(struct {
+			operation
+			Value interface{} `json:"value"`
+		}{
+			operation: operation(op),
+			Value:     op.Value,
+		})
+
+		return b, errors.Wrap(err, "error on marshal patch operation")
+	default:
+		b, err := json.Marshal(operation(op))
+
+		return b, errors.Wrap(err, "error on marshal patch operation")
+	}
+}
+
+// JSONPatch applies the operations of a JSON patch to the item.
+func (c *Client[T]) JSONPatch(ctx context.Context, name string, ops ...PatchOperation) (*T, error) {
+	var res T
+
+	err := c.do(ctx, http.MethodPatch, c.path(name), c.writeQuery(), contentTypeJSONPatch, ops, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// Apply creates the item, or merges v into it: fields of v are set, fields of the previous apply that v doesn't have
+// are removed, and fields other writers set are kept.
+func (c *Client[T]) Apply(ctx context.Context, name string, v T) (*T, error) {
+	var res T
+
+	err := c.do(ctx, http.MethodPatch, c.path(name), c.writeQuery(), contentTypeApplyPatch, v, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// ReplaceStatus replaces status of the item with the _status field of v. Other fields of v are ignored.
+func (c *Client[T]) ReplaceStatus(ctx context.Context, name string, v T) (*T, error) {
+	var res T
+
+	err := c.do(ctx, http.MethodPut, c.path(name)+"/status", nil, contentTypeJSON, v, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// MergePatchStatus applies a JSON merge patch to the item, keeping only the changes to its _status field.
+func (c *Client[T]) MergePatchStatus(ctx context.Context, name string, patch interface{}) (*T, error) {
+	var res T
+
+	err := c.do(ctx, http.MethodPatch, c.path(name)+"/status", nil, contentTypeMergePatch, patch, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// Transition moves the item to the state of the workflow of its type. Transitions the workflow doesn't allow fail with
+// an error that wraps ErrConflict.
+func (c *Client[T]) Transition(ctx context.Context, name, to string) (*T, error) {
+	var res T
+
+	query := c.writeQuery()
+	if query == nil {
+		query = make(url.Values)
+	}
+
+	query.Set("to", to)
+
+	err := c.do(ctx, http.MethodPost, c.path(name)+":transition", query, "", nil, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (c *Client[T]) Delete(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, c.path(name), nil, "", nil, nil)
+}
+
+func (c *Client[T]) path(name string) string {
+	if name == "" {
+		return "/" + url.PathEscape(c.typePlural)
+	}
+
+	return "/" + url.PathEscape(c.typePlural) + "/" + url.PathEscape(name)
+}
+
+func (c *Client[T]) writeQuery() url.Values {
+	if c.fieldManager == "" {
+		return nil
+	}
+
+	return url.Values{"fieldManager": []string{c.fieldManager}}
+}
+
+// send sends a request, returning the response if it succeeded and an *Error if it didn't.
+func (c *Client[T]) send(ctx context.Context, method, path string, query url.Values, contentType string, body interface{}, accept string) (*http.Response, error) {
+	var rd io.Reader
+
+	switch body := body.(type) {
+	case nil:
+	case io.Reader:
+		rd = body
+	default:
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, errors.Wrap(err, "error on marshal request body")
+		}
+
+		rd = bytes.NewReader(b)
+	}
+
+	u := c.baseURL + path
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on create request")
+	}
+
+	for k, v := range c.header {
+		req.Header[k] = append([]string(nil), v...)
+	}
+
+	req.Header.Set("Accept", accept)
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	rsp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on send request")
+	}
+
+	if rsp.StatusCode >= http.StatusBadRequest {
+		defer func() { _ = rsp.Body.Close() }()
+
+		return nil, newError(rsp)
+	}
+
+	return rsp, nil
+}
+
+// do sends a request and decodes the JSON response into res, if it's not nil.
+func (c *Client[T]) do(ctx context.Context, method, path string, query url.Values, contentType string, body, res interface{}) error {
+	rsp, err := c.send(ctx, method, path, query, contentType, body, contentTypeJSON)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = rsp.Body.Close() }()
+
+	if res == nil {
+		return nil
+	}
+
+	err = json.NewDecoder(rsp.Body).Decode(res)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("error on decode response of %s %s", method, path))
+	}
+
+	return nil
+}
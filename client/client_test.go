@@ -0,0 +1,280 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nasermirzaei89/core/client"
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Drink struct {
+	Name  string   `json:"name"`
+	Price float64  `json:"price"`
+	Sizes []string `json:"sizes,omitempty"`
+}
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(transport.New(memory.NewItemRepository()))
+
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	srv := newServer(t)
+
+	drinks := client.New[Drink](srv.URL, "drink", client.WithFieldManager("agent-a"))
+
+	created, err := drinks.Create(ctx, Drink{Name: "tea", Price: 2, Sizes: []string{"small"}})
+	require.NoError(t, err)
+	assert.Equal(t, Drink{Name: "tea", Price: 2, Sizes: []string{"small"}}, *created)
+
+	res, err := drinks.Get(ctx, "tea")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, res.Price)
+
+	res, err = drinks.Replace(ctx, "tea", Drink{Name: "tea", Price: 3})
+	require.NoError(t, err)
+	assert.Equal(t, Drink{Name: "tea", Price: 3}, *res)
+
+	res, err = drinks.MergePatch(ctx, "tea", map[string]interface{}{"sizes": []string{"large"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"large"}, res.Sizes)
+
+	res, err = drinks.JSONPatch(ctx, "tea", client.PatchOperation{Op: "replace", Path: "/price", Value: 4})
+	require.NoError(t, err)
+	assert.Equal(t, 4.0, res.Price)
+
+	// fields of other writers are kept by apply
+	res, err = drinks.Apply(ctx, "tea", Drink{Name: "tea", Price: 5})
+	require.NoError(t, err)
+	assert.Equal(t, Drink{Name: "tea", Price: 5, Sizes: []string{"large"}}, *res)
+
+	res, err = drinks.Apply(ctx, "tea", Drink{Name: "tea", Price: 4})
+	require.NoError(t, err)
+	assert.Equal(t, 4.0, res.Price)
+
+	_, err = client.New[Drink](srv.URL, "drink", client.WithFieldManager("agent-a")).MergePatch(ctx, "tea", map[string]interface{}{"price": 5})
+	require.NoError(t, err)
+
+	_, err = client.New[Drink](srv.URL, "drink", client.WithFieldManager("agent-b")).MergePatch(ctx, "tea", map[string]interface{}{"price": 6})
+	assert.True(t, errors.Is(err, client.ErrConflict))
+
+	res, err = drinks.MergePatch(ctx, "tea", map[string]interface{}{"price": 4})
+	require.NoError(t, err)
+	assert.Equal(t, 4.0, res.Price)
+
+	items := client.New[client.Item](srv.URL, "drink")
+
+	item, err := items.Get(ctx, "tea")
+	require.NoError(t, err)
+	assert.Equal(t, "drink", item.Type)
+	assert.NotEmpty(t, item.UUID)
+	assert.Equal(t, 4.0, item.Data["price"])
+
+	item, err = items.ReplaceStatus(ctx, "tea", client.Item{Status: map[string]interface{}{"stock": 10}})
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, item.Status["stock"])
+	assert.Equal(t, 4.0, item.Data["price"])
+
+	item, err = items.MergePatchStatus(ctx, "tea", map[string]interface{}{"_status": map[string]interface{}{"stock": 9}})
+	require.NoError(t, err)
+	assert.Equal(t, 9.0, item.Status["stock"])
+
+	require.NoError(t, drinks.Delete(ctx, "tea"))
+
+	_, err = drinks.Get(ctx, "tea")
+	assert.True(t, errors.Is(err, client.ErrNotFound))
+}
+
+func TestClient_Transition(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	h := transport.New(memory.NewItemRepository())
+
+	err := h.LoadSchemas(strings.NewReader(`
+- type: order
+  workflow:
+    field: status
+    states: [draft, submitted]
+    initial: draft
+    transitions:
+      - from: [draft]
+        to: submitted
+`))
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	orders := client.New[client.Item](srv.URL, "order", client.WithHeader("X-User", "alice"))
+
+	_, err = orders.Create(ctx, client.Item{Name: "order-0"})
+	require.NoError(t, err)
+
+	item, err := orders.Transition(ctx, "order-0", "submitted")
+	require.NoError(t, err)
+	assert.Equal(t, "submitted", item.Data["status"])
+	require.Len(t, item.Transitions, 2)
+	assert.Equal(t, "draft", item.Transitions[0].To)
+	assert.Equal(t, "alice", item.Transitions[1].Actor)
+
+	_, err = orders.Transition(ctx, "order-0", "draft")
+	assert.True(t, errors.Is(err, client.ErrConflict))
+}
+
+func TestClient_Errors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	srv := newServer(t)
+
+	drinks := client.New[Drink](srv.URL, "drink")
+
+	_, err := drinks.Create(ctx, Drink{Name: "tea"})
+	require.NoError(t, err)
+
+	_, err = drinks.Create(ctx, Drink{Name: "tea"})
+	assert.True(t, errors.Is(err, client.ErrAlreadyExists))
+
+	var apiErr *client.Error
+
+	_, err = drinks.Create(ctx, Drink{Name: "Tea"})
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 400, apiErr.StatusCode)
+	assert.Equal(t, transport.CodeNameInvalid, apiErr.Code)
+	assert.True(t, errors.Is(err, client.ErrInvalid))
+
+	_, err = drinks.JSONPatch(ctx, "tea", client.PatchOperation{Op: "remove", Path: "/missing"})
+	assert.True(t, errors.Is(err, client.ErrPatchFailed))
+
+	_, err = client.New[Drink]("http://127.0.0.1:0", "drink").Get(ctx, "tea")
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &apiErr))
+}
+
+func TestClient_List(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	srv := newServer(t)
+
+	drinks := client.New[Drink](srv.URL, "drink")
+
+	names := []string{"coffee", "juice", "milk", "tea", "water"}
+
+	for _, name := range names {
+		_, err := drinks.Create(ctx, Drink{Name: name})
+		require.NoError(t, err)
+	}
+
+	for _, pageSize := range []int{0, 1, 2, 5, 10} {
+		it := drinks.List(ctx, client.ListOptions{PageSize: pageSize, LabelSelector: ""})
+
+		res := make([]string, 0)
+
+		for it.Next() {
+			res = append(res, it.Value().Name)
+		}
+
+		require.NoError(t, it.Err())
+		assert.Equal(t, names, res, "page size %d", pageSize)
+	}
+
+	it := client.New[Drink](srv.URL, "Drinks").List(ctx, client.ListOptions{PageSize: 0, LabelSelector: ""})
+	assert.False(t, it.Next())
+	assert.True(t, errors.Is(it.Err(), client.ErrInvalid))
+}
+
+func TestClient_Watch(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := newServer(t)
+
+	drinks := client.New[Drink](srv.URL, "drink")
+
+	w, err := drinks.Watch(ctx)
+	require.NoError(t, err)
+
+	defer func() { _ = w.Close() }()
+
+	// the server subscribes before it sends the response headers, so no change is missed
+	_, err = drinks.Create(ctx, Drink{Name: "tea", Price: 1})
+	require.NoError(t, err)
+
+	require.NoError(t, drinks.Delete(ctx, "tea"))
+
+	require.True(t, w.Next(), w.Err())
+	assert.Equal(t, client.EventAdded, w.Event().Type)
+	assert.Equal(t, Drink{Name: "tea", Price: 1}, w.Event().Item)
+
+	require.True(t, w.Next(), w.Err())
+	assert.Equal(t, client.EventDeleted, w.Event().Type)
+}
+
+func TestClient_ExportImport(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	srv := newServer(t)
+
+	drinks := client.New[Drink](srv.URL, "drink")
+
+	_, err := drinks.Create(ctx, Drink{Name: "tea", Price: 2})
+	require.NoError(t, err)
+
+	res, err := drinks.JSONPatch(ctx, "tea", client.PatchOperation{Op: "add", Path: "/sizes", Value: nil})
+	require.NoError(t, err)
+	assert.Nil(t, res.Sizes)
+
+	var exported bytes.Buffer
+
+	require.NoError(t, drinks.Export(ctx, &exported))
+	assert.Contains(t, exported.String(), `"name":"tea"`)
+
+	var all bytes.Buffer
+
+	require.NoError(t, client.ExportAll(ctx, srv.URL, &all))
+	assert.Equal(t, exported.String(), all.String())
+
+	other := client.New[Drink](newServer(t).URL, "drink")
+
+	result, err := other.Import(ctx, &exported, client.ImportOptions{Mode: client.ImportModeUpsert, CSV: false})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+
+	result, err = other.Import(ctx, strings.NewReader("name,price\ncoffee,3\n"), client.ImportOptions{Mode: "", CSV: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+
+	coffee, err := other.Get(ctx, "coffee")
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, coffee.Price)
+
+	_, err = other.Import(ctx, strings.NewReader(""), client.ImportOptions{Mode: "all", CSV: false})
+	assert.True(t, errors.Is(err, client.ErrInvalid))
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Errors an *Error wraps, by its code or status, for use with errors.Is.
+var (
+	ErrInvalid       = errors.New("invalid request")
+	ErrNotFound      = errors.New("item not found")
+	ErrAlreadyExists = errors.New("item already exists")
+	ErrConflict      = errors.New("conflict")
+	ErrPatchFailed   = errors.New("patch failed")
+	ErrUnsupported   = errors.New("unsupported")
+	ErrServer        = errors.New("server error")
+)
+
+const maxErrorBodySize = 4096
+
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Error is an error response of the API. Code is the stable error code of the problem details, if the API sent them.
+type Error struct {
+	StatusCode    int            `json:"-"`
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Code          string         `json:"code"`
+	Detail        string         `json:"detail"`
+	Instance      string         `json:"instance"`
+	InvalidParams []InvalidParam `json:"invalidParams"`
+}
+
+func (err *Error) Error() string {
+	var sb strings.Builder
+
+	_, _ = fmt.Fprintf(&sb, "core: %d %s", err.StatusCode, http.StatusText(err.StatusCode))
+
+	if err.Code != "" {
+		_, _ = fmt.Fprintf(&sb, " (%s)", err.Code)
+	}
+
+	if err.Detail != "" {
+		_, _ = fmt.Fprintf(&sb, ": %s", err.Detail)
+	}
+
+	return sb.String()
+}
+
+func (err *Error) Unwrap() error {
+	switch err.Code {
+	case "item.not_found":
+		return ErrNotFound
+	case "item.already_exists":
+		return ErrAlreadyExists
+	case "patch.failed":
+		return ErrPatchFailed
+	case "field.conflict":
+		return ErrConflict
+	}
+
+	switch {
+	case err.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case err.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case err.StatusCode == http.StatusNotImplemented, err.StatusCode == http.StatusUnsupportedMediaType, err.StatusCode == http.StatusNotAcceptable:
+		return ErrUnsupported
+	case err.StatusCode >= http.StatusInternalServerError:
+		return ErrServer
+	default:
+		return ErrInvalid
+	}
+}
+
+// newError reads an error response. Responses without problem details keep the start of their body as the detail.
+func newError(rsp *http.Response) *Error {
+	res := Error{StatusCode: rsp.StatusCode}
+
+	body, _ := io.ReadAll(io.LimitReader(rsp.Body, maxErrorBodySize))
+
+	if json.Unmarshal(body, &res) != nil || res.Code == "" {
+		res.Detail = strings.TrimSpace(string(body))
+	}
+
+	return &res
+}
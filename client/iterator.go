@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type ListOptions struct {
+	// PageSize is the number of items fetched per request. Zero fetches all items at once.
+	PageSize int
+	// LabelSelector selects items by their labels, like "env=prod,tier in (web,api)". Empty selects all items.
+	LabelSelector string
+}
+
+// Iterator iterates over items, fetching pages as it goes.
+//
+//	it := c.List(ctx, client.ListOptions{PageSize: 100})
+//	for it.Next() {
+//		item := it.Value()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type Iterator[T any] struct {
+	ctx    context.Context
+	c      *Client[T]
+	opts   ListOptions
+	page   []T
+	index  int
+	cursor string
+	last   bool
+	err    error
+}
+
+func (c *Client[T]) List(ctx context.Context, opts ListOptions) *Iterator[T] {
+	return &Iterator[T]{
+		ctx:    ctx,
+		c:      c,
+		opts:   opts,
+		page:   nil,
+		index:  -1,
+		cursor: "",
+		last:   false,
+		err:    nil,
+	}
+}
+
+// Next advances to the next item, returning false when there are no more items or an error occurred.
+func (it *Iterator[T]) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	it.index++
+
+	for it.index >= len(it.page) {
+		if it.last {
+			return false
+		}
+
+		it.err = it.fetch()
+		if it.err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Value returns the current item.
+func (it *Iterator[T]) Value() T {
+	return it.page[it.index]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+func (it *Iterator[T]) fetch() error {
+	query := make(url.Values)
+
+	if it.opts.PageSize > 0 {
+		query.Set("limit", strconv.Itoa(it.opts.PageSize))
+	}
+
+	if it.cursor != "" {
+		query.Set("cursor", it.cursor)
+	}
+
+	if it.opts.LabelSelector != "" {
+		query.Set("labelSelector", it.opts.LabelSelector)
+	}
+
+	var res struct {
+		Items []T    `json:"items"`
+		Next  string `json:"next"`
+	}
+
+	err := it.c.do(it.ctx, http.MethodGet, it.c.path(""), query, "", nil, &res)
+	if err != nil {
+		return err
+	}
+
+	it.page = res.Items
+	it.index = 0
+	it.cursor = res.Next
+	it.last = res.Next == ""
+
+	return nil
+}
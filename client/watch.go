@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+type EventType string
+
+const (
+	EventAdded    EventType = "ADDED"
+	EventModified EventType = "MODIFIED"
+	EventDeleted  EventType = "DELETED"
+)
+
+type Event[T any] struct {
+	Type EventType `json:"type"`
+	Item T         `json:"item"`
+}
+
+// Watcher reads change events of items. It stops when its context is done, Close is called,
+// or the server ends the stream, which it does to watchers that fall behind.
+type Watcher[T any] struct {
+	body  io.ReadCloser
+	dec   *json.Decoder
+	event Event[T]
+	err   error
+}
+
+// Watch starts watching changes to items. It returns after the server accepted the watch.
+func (c *Client[T]) Watch(ctx context.Context) (*Watcher[T], error) {
+	rsp, err := c.send(ctx, http.MethodGet, c.path(""), url.Values{"watch": []string{"true"}}, "", nil, contentTypeNDJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher[T]{
+		body:  rsp.Body,
+		dec:   json.NewDecoder(rsp.Body),
+		event: Event[T]{Type: "", Item: *new(T)},
+		err:   nil,
+	}, nil
+}
+
+// Next waits for the next event, returning false when the stream ended.
+func (w *Watcher[T]) Next() bool {
+	if w.err != nil {
+		return false
+	}
+
+	var event Event[T]
+
+	err := w.dec.Decode(&event)
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			w.err = errors.Wrap(err, "error on decode event")
+		} else {
+			w.err = io.EOF
+		}
+
+		return false
+	}
+
+	w.event = event
+
+	return true
+}
+
+// Event returns the current event.
+func (w *Watcher[T]) Event() Event[T] {
+	return w.event
+}
+
+// Err returns the error that ended the stream, or nil if the server ended it.
+func (w *Watcher[T]) Err() error {
+	if errors.Is(w.err, io.EOF) {
+		return nil
+	}
+
+	return w.err
+}
+
+func (w *Watcher[T]) Close() error {
+	return errors.Wrap(w.body.Close(), "error on close watch stream")
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nasermirzaei89/core/client"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newExportCommand(o *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "export [TYPE]",
+		Short: "Writes items of a type, or of all types, as NDJSON",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return client.ExportAll(cmd.Context(), o.cfg.Server, cmd.OutOrStdout(), o.cfg.clientOptions()...)
+			}
+
+			return o.items(args[0]).Export(cmd.Context(), cmd.OutOrStdout())
+		},
+	}
+}
+
+func newImportCommand(o *rootOptions) *cobra.Command {
+	var (
+		filename string
+		mode     string
+		csv      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import TYPE -f FILE",
+		Short: "Imports items of a type from an NDJSON or CSV file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var r io.Reader
+
+			switch filename {
+			case "":
+				return errors.New("no file is given, set one with -f")
+			case "-":
+				r = cmd.InOrStdin()
+			default:
+				f, err := os.Open(filename)
+				if err != nil {
+					return errors.Wrap(err, "error on open file")
+				}
+
+				defer func() { _ = f.Close() }()
+
+				r = f
+			}
+
+			res, err := o.items(args[0]).Import(cmd.Context(), r, client.ImportOptions{
+				Mode: mode,
+				CSV:  csv || strings.HasSuffix(filename, ".csv"),
+			})
+			if err != nil {
+				return err
+			}
+
+			err = o.printImportResult(cmd.OutOrStdout(), res)
+			if err != nil {
+				return err
+			}
+
+			if res.Failed != 0 {
+				return errors.Errorf("%d items failed to import", res.Failed)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&filename, "filename", "f", "", "file of the items, - reads stdin")
+	cmd.Flags().StringVar(&mode, "mode", client.ImportModeCreateOnly,
+		fmt.Sprintf("one of %s, %s or %s", client.ImportModeCreateOnly, client.ImportModeUpsert, client.ImportModeReplaceAll))
+	cmd.Flags().BoolVar(&csv, "csv", false, "read the file as CSV, the default for files ending in .csv")
+
+	return cmd
+}
+
+func (o *rootOptions) printImportResult(w io.Writer, res *client.ImportResult) error {
+	switch o.output {
+	case outputJSON:
+		return writeJSON(w, res, true)
+	case outputYAML:
+		return writeYAML(w, res)
+	}
+
+	_, err := fmt.Fprintf(w, "%d created, %d updated, %d deleted, %d failed\n", res.Created, res.Updated, res.Deleted, res.Failed)
+	if err != nil {
+		return errors.Wrap(err, "error on write output")
+	}
+
+	for _, lineErr := range res.Errors {
+		_, err = fmt.Fprintf(w, "line %d: %s (%s)\n", lineErr.Line, lineErr.Message, lineErr.Code)
+		if err != nil {
+			return errors.Wrap(err, "error on write output")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+
+	"github.com/nasermirzaei89/core/client"
+	"github.com/nasermirzaei89/env"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultServer = "http://localhost"
+
+// config is read from the config file, and then overridden by environment variables and flags.
+type config struct {
+	Server   string `yaml:"server"`
+	Token    string `yaml:"token"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// defaultConfigPath is $XDG_CONFIG_HOME/corectl/config.yaml, or its equivalent on the OS.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "corectl", "config.yaml")
+}
+
+// loadConfig reads the config file at path. The file may be missing unless its path was given explicitly.
+func loadConfig(path string, explicit bool) (*config, error) {
+	cfg := config{
+		Server:   defaultServer,
+		Token:    "",
+		Username: "",
+		Password: "",
+	}
+
+	if path != "" {
+		b, err := os.ReadFile(path)
+
+		switch {
+		case err == nil:
+			err = yaml.Unmarshal(b, &cfg)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error on parse config file '%s'", path)
+			}
+		case !explicit && errors.Is(err, os.ErrNotExist):
+		default:
+			return nil, errors.Wrap(err, "error on read config file")
+		}
+	}
+
+	cfg.Server = env.GetString("CORECTL_SERVER", cfg.Server)
+	cfg.Token = env.GetString("CORECTL_TOKEN", cfg.Token)
+	cfg.Username = env.GetString("CORECTL_USERNAME", cfg.Username)
+	cfg.Password = env.GetString("CORECTL_PASSWORD", cfg.Password)
+
+	return &cfg, nil
+}
+
+// clientOptions returns the options of clients, with the credentials of the config.
+func (cfg *config) clientOptions() []client.Option {
+	switch {
+	case cfg.Token != "":
+		return []client.Option{client.WithHeader("Authorization", "Bearer "+cfg.Token)}
+	case cfg.Username != "":
+		auth := base64.StdEncoding.EncodeToString([]byte(cfg.Username + ":" + cfg.Password))
+
+		return []client.Option{client.WithHeader("Authorization", "Basic "+auth)}
+	default:
+		return nil
+	}
+}
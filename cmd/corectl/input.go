@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/nasermirzaei89/core/client"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// readItemFiles reads items from the files, where "-" is stdin.
+func readItemFiles(stdin io.Reader, filenames []string) ([]client.Item, error) {
+	if len(filenames) == 0 {
+		return nil, errors.New("no file is given, set one with -f")
+	}
+
+	res := make([]client.Item, 0)
+
+	for _, filename := range filenames {
+		items, err := readItemFile(stdin, filename)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error on read file '%s'", filename)
+		}
+
+		for i := range items {
+			if items[i].Type == "" {
+				return nil, errors.Errorf("item %d of file '%s' has no type", i+1, filename)
+			}
+		}
+
+		res = append(res, items...)
+	}
+
+	return res, nil
+}
+
+func readItemFile(stdin io.Reader, filename string) ([]client.Item, error) {
+	if filename == "-" {
+		return readItems(stdin)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on open file")
+	}
+
+	defer func() { _ = f.Close() }()
+
+	return readItems(f)
+}
+
+// readItems reads YAML documents, or JSON, of items. A document may also be a list of items under an items key,
+// like the output of list.
+func readItems(r io.Reader) ([]client.Item, error) {
+	res := make([]client.Item, 0)
+
+	dec := yaml.NewDecoder(r)
+
+	for {
+		var doc interface{}
+
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return res, nil
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "error on decode yaml")
+		}
+
+		if doc == nil {
+			continue
+		}
+
+		docs := []interface{}{doc}
+
+		if m, ok := doc.(map[string]interface{}); ok && len(m) == 1 {
+			if list, ok := m["items"].([]interface{}); ok {
+				docs = list
+			}
+		}
+
+		for i := range docs {
+			item, err := decodeItem(docs[i])
+			if err != nil {
+				return nil, err
+			}
+
+			res = append(res, *item)
+		}
+	}
+}
+
+// decodeItem converts a decoded document to an item through JSON, so numbers are float64 as they are in responses.
+func decodeItem(doc interface{}) (*client.Item, error) {
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return nil, errors.New("item is not an object with string keys")
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on marshal item")
+	}
+
+	var item client.Item
+
+	err = json.Unmarshal(b, &item)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on unmarshal item")
+	}
+
+	return &item, nil
+}
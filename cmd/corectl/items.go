@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"github.com/nasermirzaei89/core/client"
+	"github.com/nasermirzaei89/env"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const defaultPageSize = 100
+
+const editHeader = "# Edit the item below, only its data can be changed.\n# Lines beginning with a '#' are ignored, and an empty file cancels the edit.\n"
+
+func newGetCommand(o *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get TYPE NAME",
+		Short: "Shows an item",
+		Args:  cobra.ExactArgs(2), //nolint:gomnd
+		RunE: func(cmd *cobra.Command, args []string) error {
+			item, err := o.items(args[0]).Get(cmd.Context(), args[1])
+			if err != nil {
+				return err
+			}
+
+			return o.printItem(cmd.OutOrStdout(), item)
+		},
+	}
+}
+
+func newListCommand(o *rootOptions) *cobra.Command {
+	var (
+		pageSize int
+		selector string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list TYPE",
+		Short: "Lists items of a type",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			it := o.items(args[0]).List(cmd.Context(), client.ListOptions{PageSize: pageSize, LabelSelector: selector})
+
+			items := make([]client.Item, 0)
+
+			for it.Next() {
+				items = append(items, it.Value())
+			}
+
+			if err := it.Err(); err != nil {
+				return err
+			}
+
+			return o.printItems(cmd.OutOrStdout(), items)
+		},
+	}
+
+	cmd.Flags().IntVar(&pageSize, "page-size", defaultPageSize, "number of items fetched per request, 0 fetches all at once")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector items should match, like env=prod,tier in (web,api)")
+
+	return cmd
+}
+
+func newCreateCommand(o *rootOptions) *cobra.Command {
+	var filenames []string
+
+	cmd := &cobra.Command{
+		Use:   "create -f FILE",
+		Short: "Creates items of YAML or JSON files",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			items, err := readItemFiles(cmd.InOrStdin(), filenames)
+			if err != nil {
+				return err
+			}
+
+			for i := range items {
+				item, err := o.items(items[i].Type).Create(cmd.Context(), items[i])
+				if err != nil {
+					return errors.Wrapf(err, "error on create %s/%s", items[i].Type, items[i].Name)
+				}
+
+				err = o.printDone(cmd, item, "created")
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&filenames, "filename", "f", nil, "files of the items, - reads stdin")
+
+	return cmd
+}
+
+func newApplyCommand(o *rootOptions) *cobra.Command {
+	var filenames []string
+
+	cmd := &cobra.Command{
+		Use:   "apply -f FILE",
+		Short: "Creates items of YAML or JSON files, or merges them into the ones that exist",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			items, err := readItemFiles(cmd.InOrStdin(), filenames)
+			if err != nil {
+				return err
+			}
+
+			for i := range items {
+				item, done, err := apply(cmd.Context(), o.items(items[i].Type), items[i])
+				if err != nil {
+					return errors.Wrapf(err, "error on apply %s/%s", items[i].Type, items[i].Name)
+				}
+
+				err = o.printDone(cmd, item, done)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&filenames, "filename", "f", nil, "files of the items, - reads stdin")
+
+	return cmd
+}
+
+// apply applies the item on the server, which removes fields of the previous apply that the item doesn't have.
+func apply(ctx context.Context, c *client.Client[client.Item], item client.Item) (*client.Item, string, error) {
+	current, err := c.Get(ctx, item.Name)
+	if err != nil && !errors.Is(err, client.ErrNotFound) {
+		return nil, "", err
+	}
+
+	res, err := c.Apply(ctx, item.Name, item)
+
+	switch {
+	case err != nil:
+		return nil, "", err
+	case current == nil:
+		return res, "created", nil
+	case reflect.DeepEqual(current.Data, res.Data):
+		return res, "unchanged", nil
+	default:
+		return res, "configured", nil
+	}
+}
+
+func newEditCommand(o *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "edit TYPE NAME",
+		Short: "Edits an item in $EDITOR and sends the changes as a JSON patch",
+		Args:  cobra.ExactArgs(2), //nolint:gomnd
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := o.items(args[0])
+
+			item, err := c.Get(cmd.Context(), args[1])
+			if err != nil {
+				return err
+			}
+
+			edited, err := editItem(cmd, item)
+			if err != nil {
+				return err
+			}
+
+			if edited == nil {
+				_, err = fmt.Fprintln(cmd.ErrOrStderr(), "Edit cancelled, no changes made.")
+
+				return errors.Wrap(err, "error on write output")
+			}
+
+			if edited.UUID != item.UUID || edited.Type != item.Type || edited.Name != item.Name ||
+				!edited.CreatedAt.Equal(item.CreatedAt) || !edited.UpdatedAt.Equal(item.UpdatedAt) {
+				return errors.New("metadata fields of items can't be edited")
+			}
+
+			ops := diffPatch("", item.Data, edited.Data)
+			if len(ops) == 0 {
+				_, err = fmt.Fprintln(cmd.ErrOrStderr(), "Edit cancelled, no changes made.")
+
+				return errors.Wrap(err, "error on write output")
+			}
+
+			res, err := c.JSONPatch(cmd.Context(), item.Name, ops...)
+			if err != nil {
+				return err
+			}
+
+			return o.printDone(cmd, res, "edited")
+		},
+	}
+}
+
+// editItem opens the item as YAML in the editor and returns the edited item, or nil if the file was emptied.
+func editItem(cmd *cobra.Command, item *client.Item) (*client.Item, error) {
+	f, err := os.CreateTemp("", "corectl-edit-*.yaml")
+	if err != nil {
+		return nil, errors.Wrap(err, "error on create temp file")
+	}
+
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	_, err = f.WriteString(editHeader)
+	if err == nil {
+		err = writeYAML(f, item)
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "error on write temp file")
+	}
+
+	editor := env.GetString("CORECTL_EDITOR", env.GetString("EDITOR", "vi"))
+
+	// the editor may have arguments, like "code --wait", so it runs through the shell
+	c := exec.CommandContext(cmd.Context(), "sh", "-c", editor+" "+shellQuote(f.Name()))
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = cmd.ErrOrStderr()
+
+	err = c.Run()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error on run editor '%s'", editor)
+	}
+
+	edited, err := readItemFile(nil, f.Name())
+	if err != nil {
+		return nil, errors.Wrap(err, "error on read edited item")
+	}
+
+	switch len(edited) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &edited[0], nil
+	default:
+		return nil, errors.New("edited file should have one item")
+	}
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func newDeleteCommand(o *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete TYPE NAME...",
+		Short: "Deletes items",
+		Args:  cobra.MinimumNArgs(2), //nolint:gomnd
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := o.items(args[0])
+
+			for _, name := range args[1:] {
+				err := c.Delete(cmd.Context(), name)
+				if err != nil {
+					return errors.Wrapf(err, "error on delete %s", name)
+				}
+
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s/%s deleted\n", singular(args[0]), name)
+				if err != nil {
+					return errors.Wrap(err, "error on write output")
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+func newWatchCommand(o *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "watch TYPE",
+		Short: "Prints changes to items of a type until interrupted",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w, err := o.items(args[0]).Watch(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			defer func() { _ = w.Close() }()
+
+			for first := true; w.Next(); first = false {
+				err = o.printEvent(cmd.OutOrStdout(), w.Event(), first)
+				if err != nil {
+					return err
+				}
+			}
+
+			if cmd.Context().Err() != nil {
+				return nil
+			}
+
+			return w.Err()
+		},
+	}
+}
@@ -0,0 +1,21 @@
+// Command corectl works with items of a core API from the command line.
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	err := newRootCommand().ExecuteContext(ctx)
+
+	stop()
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nasermirzaei89/core/client"
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+const drinksYAML = `type: drink
+name: tea
+price: 2
+sizes: [small, large]
+---
+type: drink
+name: coffee
+price: 3
+`
+
+// newConfig starts a server and returns the path of a config file of it.
+func newConfig(t *testing.T) string {
+	t.Helper()
+
+	srv := httptest.NewServer(transport.New(memory.NewItemRepository()))
+	t.Cleanup(srv.Close)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	require.NoError(t, os.WriteFile(path, []byte("server: "+srv.URL+"\ntoken: secret\n"), 0o600))
+
+	return path
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "items.yaml")
+
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func run(t *testing.T, configPath string, stdin io.Reader, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	cmd := newRootCommand()
+	cmd.SetArgs(append([]string{"--config", configPath}, args...))
+	cmd.SetIn(stdin)
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+
+	err := cmd.Execute()
+
+	return out.String(), err
+}
+
+func TestItemCommands(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig(t)
+
+	out, err := run(t, cfg, nil, "create", "-f", writeFile(t, drinksYAML))
+	require.NoError(t, err)
+	assert.Equal(t, "drink/tea created\ndrink/coffee created\n", out)
+
+	_, err = run(t, cfg, nil, "create", "-f", writeFile(t, drinksYAML))
+	assert.ErrorIs(t, err, client.ErrAlreadyExists)
+
+	out, err = run(t, cfg, nil, "get", "drinks", "tea", "-o", "json")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, gjson.Get(out, "price").Float())
+	assert.Equal(t, "small", gjson.Get(out, "sizes.0").String())
+
+	out, err = run(t, cfg, nil, "list", "drink", "--page-size", "1")
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "NAME"))
+	assert.True(t, strings.HasPrefix(lines[1], "coffee"))
+	assert.True(t, strings.HasPrefix(lines[2], "tea"))
+
+	list, err := run(t, cfg, nil, "list", "drinks", "-o", "yaml")
+	require.NoError(t, err)
+
+	// the list output can be applied back as is
+	out, err = run(t, cfg, strings.NewReader(list), "apply", "-f", "-")
+	require.NoError(t, err)
+	assert.Equal(t, "drink/coffee unchanged\ndrink/tea unchanged\n", out)
+
+	out, err = run(t, cfg, nil, "apply", "-f", writeFile(t, "type: drink\nname: tea\nprice: 4\n---\ntype: drink\nname: milk\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "drink/tea configured\ndrink/milk created\n", out)
+
+	out, err = run(t, cfg, nil, "get", "drink", "tea", "-o", "yaml")
+	require.NoError(t, err)
+	assert.Contains(t, out, "price: 4\n")
+	assert.NotContains(t, out, "sizes")
+
+	out, err = run(t, cfg, nil, "delete", "drinks", "tea", "milk")
+	require.NoError(t, err)
+	assert.Equal(t, "drink/tea deleted\ndrink/milk deleted\n", out)
+
+	_, err = run(t, cfg, nil, "get", "drink", "tea")
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	_, err = run(t, cfg, nil, "list", "drink", "-o", "xml")
+	assert.Error(t, err)
+
+	_, err = run(t, cfg, nil, "create", "-f", writeFile(t, "name: tea\n"))
+	assert.Error(t, err)
+}
+
+func TestEditCommand(t *testing.T) {
+	cfg := newConfig(t)
+
+	_, err := run(t, cfg, nil, "create", "-f", writeFile(t, drinksYAML))
+	require.NoError(t, err)
+
+	t.Setenv("CORECTL_EDITOR", `sed -i -e 's/^price: 2$/price: 5/' -e '/^  - small$/d' -e '$a hot: true'`)
+
+	out, err := run(t, cfg, nil, "edit", "drink", "tea", "-o", "json")
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, gjson.Get(out, "price").Float())
+	assert.Equal(t, "large", gjson.Get(out, "sizes.0").String())
+	assert.Len(t, gjson.Get(out, "sizes").Array(), 1)
+	assert.True(t, gjson.Get(out, "hot").Bool())
+
+	t.Setenv("CORECTL_EDITOR", "true")
+
+	out, err = run(t, cfg, nil, "edit", "drink", "tea")
+	require.NoError(t, err)
+	assert.Empty(t, out)
+
+	t.Setenv("CORECTL_EDITOR", `sed -i 's/^name: tea$/name: milk/'`)
+
+	_, err = run(t, cfg, nil, "edit", "drink", "tea")
+	assert.Error(t, err)
+}
+
+func TestExportImportCommands(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig(t)
+
+	_, err := run(t, cfg, nil, "create", "-f", writeFile(t, drinksYAML))
+	require.NoError(t, err)
+
+	exported, err := run(t, cfg, nil, "export", "drinks")
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(strings.TrimSpace(exported), "\n"), 2)
+
+	all, err := run(t, cfg, nil, "export")
+	require.NoError(t, err)
+	assert.Equal(t, exported, all)
+
+	other := newConfig(t)
+
+	out, err := run(t, other, strings.NewReader(exported), "import", "drink", "-f", "-")
+	require.NoError(t, err)
+	assert.Equal(t, "2 created, 0 updated, 0 deleted, 0 failed\n", out)
+
+	out, err = run(t, other, strings.NewReader(exported), "import", "drink", "-f", "-", "-o", "json")
+	assert.Error(t, err)
+	assert.Equal(t, int64(2), gjson.Get(out, "failed").Int())
+
+	out, err = run(t, other, strings.NewReader(exported), "import", "drink", "-f", "-", "--mode", "upsert")
+	require.NoError(t, err)
+	assert.Equal(t, "0 created, 2 updated, 0 deleted, 0 failed\n", out)
+}
+
+func TestDiffPatch(t *testing.T) {
+	t.Parallel()
+
+	from := map[string]interface{}{
+		"a":   1.0,
+		"b":   map[string]interface{}{"c": "d", "e": "f"},
+		"g":   []interface{}{1.0},
+		"x/y": true,
+	}
+	to := map[string]interface{}{
+		"a": 2.0,
+		"b": map[string]interface{}{"c": "d", "h": nil},
+		"g": []interface{}{1.0},
+		"i": "j",
+	}
+
+	assert.Equal(t, []client.PatchOperation{
+		{Op: "remove", Path: "/x~1y"},
+		{Op: "replace", Path: "/a", Value: 2.0},
+		{Op: "remove", Path: "/b/e"},
+		{Op: "add", Path: "/b/h", Value: nil},
+		{Op: "add", Path: "/i", Value: "j"},
+	}, diffPatch("", from, to))
+}
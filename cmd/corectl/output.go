@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nasermirzaei89/core/client"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const eventTableFormat = "%-10s %-40s %s\n"
+
+func (o *rootOptions) printItem(w io.Writer, item *client.Item) error {
+	switch o.output {
+	case outputJSON:
+		return writeJSON(w, item, true)
+	case outputYAML:
+		return writeYAML(w, item)
+	default:
+		return writeItemTable(w, []client.Item{*item})
+	}
+}
+
+// printItems prints the items as a list, like the list response of the API in the json and yaml formats.
+func (o *rootOptions) printItems(w io.Writer, items []client.Item) error {
+	list := struct {
+		Items []client.Item `json:"items"`
+	}{
+		Items: items,
+	}
+
+	switch o.output {
+	case outputJSON:
+		return writeJSON(w, list, true)
+	case outputYAML:
+		return writeYAML(w, list)
+	default:
+		return writeItemTable(w, items)
+	}
+}
+
+// printEvent prints an event as a line of a table, a line of NDJSON, or a YAML document.
+func (o *rootOptions) printEvent(w io.Writer, event client.Event[client.Item], first bool) error {
+	switch o.output {
+	case outputJSON:
+		return writeJSON(w, event, false)
+	case outputYAML:
+		if !first {
+			_, err := io.WriteString(w, "---\n")
+			if err != nil {
+				return errors.Wrap(err, "error on write output")
+			}
+		}
+
+		return writeYAML(w, event)
+	default:
+		if first {
+			_, err := fmt.Fprintf(w, eventTableFormat, "EVENT", "NAME", "UPDATED")
+			if err != nil {
+				return errors.Wrap(err, "error on write output")
+			}
+		}
+
+		_, err := fmt.Fprintf(w, eventTableFormat, event.Type, event.Item.Name, formatTime(event.Item.UpdatedAt))
+
+		return errors.Wrap(err, "error on write output")
+	}
+}
+
+func writeItemTable(w io.Writer, items []client.Item) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0) //nolint:gomnd
+
+	_, _ = fmt.Fprintln(tw, "NAME\tCREATED\tUPDATED")
+
+	for i := range items {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", items[i].Name, formatTime(items[i].CreatedAt), formatTime(items[i].UpdatedAt))
+	}
+
+	return errors.Wrap(tw.Flush(), "error on write output")
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+func writeJSON(w io.Writer, v interface{}, indent bool) error {
+	enc := json.NewEncoder(w)
+
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+
+	return errors.Wrap(enc.Encode(v), "error on write json output")
+}
+
+// writeYAML writes v as YAML in the shape it has as JSON, so items keep their data next to their metadata.
+func writeYAML(w io.Writer, v interface{}) error {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2) //nolint:gomnd
+
+	err = enc.Encode(generic)
+	if err != nil {
+		return errors.Wrap(err, "error on write yaml output")
+	}
+
+	return errors.Wrap(enc.Close(), "error on write yaml output")
+}
+
+func toGeneric(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on marshal json")
+	}
+
+	var res interface{}
+
+	err = json.Unmarshal(b, &res)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on unmarshal json")
+	}
+
+	return res, nil
+}
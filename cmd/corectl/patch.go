@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/nasermirzaei89/core/client"
+)
+
+// diffPatch returns the JSON patch operations that change from into to. Objects are compared field by field,
+// other values, arrays included, are replaced as a whole.
+func diffPatch(path string, from, to map[string]interface{}) []client.PatchOperation {
+	res := make([]client.PatchOperation, 0)
+
+	for _, k := range sortedKeys(from) {
+		if _, ok := to[k]; !ok {
+			res = append(res, client.PatchOperation{Op: "remove", Path: path + "/" + escapePointer(k), From: "", Value: nil})
+		}
+	}
+
+	for _, k := range sortedKeys(to) {
+		p := path + "/" + escapePointer(k)
+
+		v, ok := from[k]
+		if !ok {
+			res = append(res, client.PatchOperation{Op: "add", Path: p, From: "", Value: to[k]})
+
+			continue
+		}
+
+		fromMap, fromOK := v.(map[string]interface{})
+		toMap, toOK := to[k].(map[string]interface{})
+
+		switch {
+		case fromOK && toOK:
+			res = append(res, diffPatch(p, fromMap, toMap)...)
+		case !reflect.DeepEqual(v, to[k]):
+			res = append(res, client.PatchOperation{Op: "replace", Path: p, From: "", Value: to[k]})
+		}
+	}
+
+	return res
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	res := make([]string, 0, len(m))
+
+	for k := range m {
+		res = append(res, k)
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gertd/go-pluralize"
+	"github.com/nasermirzaei89/core/client"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// rootOptions are the options of all commands.
+type rootOptions struct {
+	configPath   string
+	server       string
+	token        string
+	fieldManager string
+	output       string
+	cfg          *config
+}
+
+func newRootCommand() *cobra.Command {
+	o := rootOptions{
+		configPath:   "",
+		server:       "",
+		token:        "",
+		fieldManager: "",
+		output:       outputTable,
+		cfg:          nil,
+	}
+
+	cmd := &cobra.Command{
+		Use:           "corectl",
+		Short:         "corectl works with items of a core API",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return o.complete(cmd)
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&o.configPath, "config", "", "path of the config file (default $CORECTL_CONFIG or "+defaultConfigPath()+")")
+	cmd.PersistentFlags().StringVar(&o.server, "server", "", "URL of the API, overriding the config file")
+	cmd.PersistentFlags().StringVar(&o.token, "token", "", "bearer token sent to the API, overriding the config file")
+	cmd.PersistentFlags().StringVar(&o.fieldManager, "field-manager", "", "field manager writes are sent as, so fields other managers own aren't overwritten")
+	cmd.PersistentFlags().StringVarP(&o.output, "output", "o", outputTable, "output format, one of table, json or yaml")
+
+	cmd.AddCommand(
+		newGetCommand(&o),
+		newListCommand(&o),
+		newCreateCommand(&o),
+		newApplyCommand(&o),
+		newEditCommand(&o),
+		newDeleteCommand(&o),
+		newWatchCommand(&o),
+		newExportCommand(&o),
+		newImportCommand(&o),
+	)
+
+	return cmd
+}
+
+func (o *rootOptions) complete(cmd *cobra.Command) error {
+	switch o.output {
+	case outputTable, outputJSON, outputYAML:
+	default:
+		return errors.Errorf("output format '%s' is not valid, it should be one of %s, %s or %s", o.output, outputTable, outputJSON, outputYAML)
+	}
+
+	path, explicit := o.configPath, cmd.Flags().Changed("config")
+
+	if !explicit {
+		path, explicit = os.LookupEnv("CORECTL_CONFIG")
+		if !explicit {
+			path = defaultConfigPath()
+		}
+	}
+
+	cfg, err := loadConfig(path, explicit)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Flags().Changed("server") {
+		cfg.Server = o.server
+	}
+
+	if cmd.Flags().Changed("token") {
+		cfg.Token = o.token
+	}
+
+	o.cfg = cfg
+
+	return nil
+}
+
+// items returns a client of items of the type, which may be singular or plural.
+func (o *rootOptions) items(typ string) *client.Client[client.Item] {
+	opts := o.cfg.clientOptions()
+
+	if o.fieldManager != "" {
+		opts = append(opts, client.WithFieldManager(o.fieldManager))
+	}
+
+	return client.New[client.Item](o.cfg.Server, singular(typ), opts...)
+}
+
+func singular(typ string) string {
+	pc := pluralize.NewClient()
+
+	if pc.IsPlural(typ) {
+		return pc.Singular(typ)
+	}
+
+	return typ
+}
+
+// printDone reports that something was done to the item, or prints the item in the json and yaml formats.
+func (o *rootOptions) printDone(cmd *cobra.Command, item *client.Item, done string) error {
+	if o.output == outputTable {
+		_, err := fmt.Fprintf(cmd.OutOrStdout(), "%s/%s %s\n", item.Type, item.Name, done)
+
+		return errors.Wrap(err, "error on write output")
+	}
+
+	return o.printItem(cmd.OutOrStdout(), item)
+}
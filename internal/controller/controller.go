@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nasermirzaei89/core/internal/repository"
+)
+
+const (
+	defaultWorkers    = 1
+	defaultMaxRetries = 15
+	defaultBaseDelay  = 5 * time.Millisecond
+	defaultMaxDelay   = time.Minute
+)
+
+// Reconciler makes the world match the item of the type and name. It's called after every change to the item, and
+// again with a growing delay while it returns an error. The item may be gone, which the informer tells.
+type Reconciler interface {
+	Reconcile(ctx context.Context, typ, name string) error
+}
+
+// ReconcilerFunc is a function that is a Reconciler.
+type ReconcilerFunc func(ctx context.Context, typ, name string) error
+
+func (fn ReconcilerFunc) Reconcile(ctx context.Context, typ, name string) error {
+	return fn(ctx, typ, name)
+}
+
+type Option func(*options)
+
+type options struct {
+	workers     int
+	maxRetries  int
+	rateLimiter RateLimiter
+}
+
+// WithWorkers sets the number of items reconciled at the same time. It's 1 by default.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// WithMaxRetries sets the number of retries of an item that keeps failing, before it's dropped until it changes
+// again. It's 15 by default, and negative values retry forever.
+func WithMaxRetries(n int) Option {
+	return func(o *options) {
+		o.maxRetries = n
+	}
+}
+
+// WithRateLimiter sets the rate limiter of retries. It's a backoff from 5ms up to a minute by default.
+func WithRateLimiter(rl RateLimiter) Option {
+	return func(o *options) {
+		o.rateLimiter = rl
+	}
+}
+
+// Controller reconciles the items of the informer as they change.
+type Controller struct {
+	informer   *Informer
+	reconciler Reconciler
+	queue      *Queue
+	workers    int
+	maxRetries int
+}
+
+// New returns a controller of the items of the informer. The informer is run by the controller, and the reconciler
+// can read items from its cache.
+func New(informer *Informer, reconciler Reconciler, opts ...Option) *Controller {
+	o := options{
+		workers:     defaultWorkers,
+		maxRetries:  defaultMaxRetries,
+		rateLimiter: nil,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.rateLimiter == nil {
+		o.rateLimiter = NewBackoffRateLimiter(defaultBaseDelay, defaultMaxDelay)
+	}
+
+	c := &Controller{
+		informer:   informer,
+		reconciler: reconciler,
+		queue:      NewQueue(o.rateLimiter),
+		workers:    o.workers,
+		maxRetries: o.maxRetries,
+	}
+
+	informer.AddHandler(func(event repository.Event) {
+		c.queue.Add(Request{Type: event.Item.Type, Name: event.Item.Name})
+	})
+
+	return c
+}
+
+// Queue returns the work queue of the controller, for adding requests of items other than the changed ones.
+func (c *Controller) Queue() *Queue {
+	return c.queue
+}
+
+// Run runs the informer and the workers until ctx is done or the informer fails. Items are reconciled once the cache
+// is filled.
+func (c *Controller) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- c.informer.Run(ctx)
+	}()
+
+	defer c.queue.ShutDown()
+
+	select {
+	case <-c.informer.synced:
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				if !c.processNext(ctx) {
+					return
+				}
+			}
+		}()
+	}
+
+	var err error
+
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+	}
+
+	c.queue.ShutDown()
+	wg.Wait()
+
+	return err
+}
+
+func (c *Controller) processNext(ctx context.Context) bool {
+	req, ok := c.queue.Get()
+	if !ok {
+		return false
+	}
+
+	defer c.queue.Done(req)
+
+	err := c.reconciler.Reconcile(ctx, req.Type, req.Name)
+	if err == nil || (c.maxRetries >= 0 && c.queue.Retries(req) >= c.maxRetries) {
+		c.queue.Forget(req)
+
+		return true
+	}
+
+	c.queue.AddRateLimited(req)
+
+	return true
+}
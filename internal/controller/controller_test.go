@@ -0,0 +1,209 @@
+package controller_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/core/internal/controller"
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/nasermirzaei89/core/internal/repository"
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newItem(typ, name string, data map[string]interface{}) core.Item {
+	return core.Item{
+		UUID:      uuid.NewString(),
+		Type:      typ,
+		Name:      name,
+		Data:      data,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func TestController(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	itemRepo := memory.NewItemRepository()
+
+	// items that exist before the controller starts are reconciled too
+	job := newItem("job", "job-0", map[string]interface{}{"image": "alpine"})
+	require.NoError(t, itemRepo.Insert(ctx, job))
+
+	informer := controller.NewInformer(itemRepo, "job")
+
+	var mu sync.Mutex
+
+	seen := make(map[string]string)
+
+	ctrl := controller.New(informer, controller.ReconcilerFunc(func(ctx context.Context, typ, name string) error {
+		item, err := informer.GetByTypeAndName(ctx, typ, name)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if errors.Is(err, repository.ErrItemNotFound) {
+			seen[name] = "gone"
+
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		seen[name], _ = item.Data["image"].(string)
+
+		return nil
+	}), controller.WithWorkers(2))
+
+	done := make(chan error)
+
+	go func() { done <- ctrl.Run(ctx) }()
+
+	image := func(name string) string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return seen[name]
+	}
+
+	assert.Eventually(t, func() bool { return image("job-0") == "alpine" }, time.Second, 10*time.Millisecond)
+	assert.True(t, informer.HasSynced())
+
+	job.Data["image"] = "busybox"
+	require.NoError(t, itemRepo.Replace(ctx, job.UUID, job))
+
+	assert.Eventually(t, func() bool { return image("job-0") == "busybox" }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, itemRepo.Insert(ctx, newItem("job", "job-1", map[string]interface{}{"image": "nginx"})))
+	require.NoError(t, itemRepo.Insert(ctx, newItem("task", "task-0", map[string]interface{}{"image": "redis"})))
+
+	assert.Eventually(t, func() bool { return image("job-1") == "nginx" }, time.Second, 10*time.Millisecond)
+
+	items, err := informer.ListByType(ctx, "job")
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	require.NoError(t, itemRepo.Delete(ctx, job.UUID))
+
+	assert.Eventually(t, func() bool { return image("job-0") == "gone" }, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	assert.NotContains(t, seen, "task-0")
+	mu.Unlock()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("controller didn't stop")
+	}
+}
+
+func TestController_Retry(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	itemRepo := memory.NewItemRepository()
+	require.NoError(t, itemRepo.Insert(ctx, newItem("job", "job-0", nil)))
+	require.NoError(t, itemRepo.Insert(ctx, newItem("job", "job-1", nil)))
+
+	var mu sync.Mutex
+
+	calls := make(map[string]int)
+
+	ctrl := controller.New(controller.NewInformer(itemRepo, "job"), controller.ReconcilerFunc(func(ctx context.Context, typ, name string) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		calls[name]++
+
+		// job-0 succeeds on its third try, and job-1 never does
+		if name == "job-1" || calls[name] < 3 {
+			return errors.New("not ready")
+		}
+
+		return nil
+	}), controller.WithMaxRetries(4), controller.WithRateLimiter(controller.NewBackoffRateLimiter(time.Millisecond, 10*time.Millisecond)))
+
+	go func() { _ = ctrl.Run(ctx) }()
+
+	count := func(name string) int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return calls[name]
+	}
+
+	assert.Eventually(t, func() bool { return count("job-0") == 3 && count("job-1") == 5 }, time.Second, 10*time.Millisecond)
+
+	// failing items are dropped after the last retry
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 3, count("job-0"))
+	assert.Equal(t, 5, count("job-1"))
+}
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	q := controller.NewQueue(controller.NewBackoffRateLimiter(time.Millisecond, 4*time.Millisecond))
+
+	a := controller.Request{Type: "job", Name: "job-0"}
+	b := controller.Request{Type: "job", Name: "job-1"}
+
+	q.Add(a)
+	q.Add(b)
+	q.Add(a)
+	assert.Equal(t, 2, q.Len())
+
+	req, ok := q.Get()
+	require.True(t, ok)
+	assert.Equal(t, a, req)
+
+	// a request that is processed waits until it's done
+	q.Add(a)
+	assert.Equal(t, 1, q.Len())
+
+	q.Done(a)
+	assert.Equal(t, 2, q.Len())
+
+	q.AddRateLimited(b)
+	q.AddRateLimited(b)
+	assert.Equal(t, 2, q.Retries(b))
+
+	q.Forget(b)
+	assert.Equal(t, 0, q.Retries(b))
+
+	q.ShutDown()
+
+	_, ok = q.Get()
+	assert.False(t, ok)
+}
+
+func TestBackoffRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	rl := controller.NewBackoffRateLimiter(time.Millisecond, 5*time.Millisecond)
+	req := controller.Request{Type: "job", Name: "job-0"}
+
+	for _, want := range []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 5 * time.Millisecond, 5 * time.Millisecond} {
+		assert.Equal(t, want, rl.When(req))
+	}
+
+	rl.Forget(req)
+	assert.Equal(t, time.Millisecond, rl.When(req))
+}
@@ -0,0 +1,204 @@
+// Package controller runs reconcilers of items, with an informer that caches items of a type and a work queue of
+// the items to reconcile.
+package controller
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/nasermirzaei89/core/internal/repository"
+	"github.com/pkg/errors"
+)
+
+// ListWatcher lists and watches items, like the memory repository does.
+type ListWatcher interface {
+	ListByType(ctx context.Context, typ string) (items []core.Item, err error)
+	repository.ItemWatcher
+}
+
+// Informer keeps a local cache of the items of a type, from a list and then a watch of them. The list is done again
+// when the watch falls behind, so the cache catches up.
+type Informer struct {
+	lw       ListWatcher
+	typ      string
+	mu       sync.RWMutex
+	items    map[string]core.Item
+	handlers []func(event repository.Event)
+	synced   chan struct{}
+	syncOnce sync.Once
+}
+
+func NewInformer(lw ListWatcher, typ string) *Informer {
+	return &Informer{
+		lw:       lw,
+		typ:      typ,
+		mu:       sync.RWMutex{},
+		items:    make(map[string]core.Item),
+		handlers: nil,
+		synced:   make(chan struct{}),
+		syncOnce: sync.Once{},
+	}
+}
+
+// AddHandler adds a function that is called with the changes to the cache. Handlers should be added before Run, and
+// are called one at a time.
+func (inf *Informer) AddHandler(fn func(event repository.Event)) {
+	inf.handlers = append(inf.handlers, fn)
+}
+
+// Run fills the cache and keeps it up to date until ctx is done.
+func (inf *Informer) Run(ctx context.Context) error {
+	for {
+		err := inf.listAndWatch(ctx)
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// listAndWatch returns when ctx is done or the watch falls behind. The watch starts before the list, so no change is
+// missed, and changes the list already has are applied again on the way.
+func (inf *Informer) listAndWatch(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := inf.lw.Watch(ctx, inf.typ)
+	if err != nil {
+		return errors.Wrap(err, "error on watch items")
+	}
+
+	items, err := inf.lw.ListByType(ctx, inf.typ)
+	if err != nil {
+		return errors.Wrap(err, "error on list items by type")
+	}
+
+	inf.replace(items)
+
+	for event := range events {
+		inf.apply(event)
+	}
+
+	return nil
+}
+
+// replace sets the listed items as the cache, and notifies handlers of the differences.
+func (inf *Informer) replace(items []core.Item) {
+	inf.mu.Lock()
+
+	old := inf.items
+	inf.items = make(map[string]core.Item, len(items))
+	changes := make([]repository.Event, 0)
+
+	for i := range items {
+		inf.items[items[i].Name] = items[i]
+
+		previous, ok := old[items[i].Name]
+
+		switch {
+		case !ok:
+			changes = append(changes, repository.Event{Type: repository.EventAdded, Item: items[i]})
+		case !reflect.DeepEqual(previous, items[i]):
+			changes = append(changes, repository.Event{Type: repository.EventModified, Item: items[i]})
+		}
+	}
+
+	for name := range old {
+		if _, ok := inf.items[name]; !ok {
+			changes = append(changes, repository.Event{Type: repository.EventDeleted, Item: old[name]})
+		}
+	}
+
+	inf.mu.Unlock()
+
+	inf.syncOnce.Do(func() { close(inf.synced) })
+
+	for _, event := range changes {
+		inf.notify(event)
+	}
+}
+
+func (inf *Informer) apply(event repository.Event) {
+	inf.mu.Lock()
+
+	switch event.Type {
+	case repository.EventDeleted:
+		// a delete of an item that was created again is older than the cached item
+		if cached, ok := inf.items[event.Item.Name]; ok && cached.UUID == event.Item.UUID {
+			delete(inf.items, event.Item.Name)
+		}
+	default:
+		inf.items[event.Item.Name] = event.Item
+	}
+
+	inf.mu.Unlock()
+
+	inf.notify(event)
+}
+
+func (inf *Informer) notify(event repository.Event) {
+	for _, fn := range inf.handlers {
+		fn(repository.Event{Type: event.Type, Item: event.Item.DeepCopy()})
+	}
+}
+
+// HasSynced reports whether the cache was filled by the first list.
+func (inf *Informer) HasSynced() bool {
+	select {
+	case <-inf.synced:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitForSync waits until the cache is filled by the first list, or ctx is done.
+func (inf *Informer) WaitForSync(ctx context.Context) error {
+	select {
+	case <-inf.synced:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "error on wait for sync")
+	}
+}
+
+// GetByTypeAndName returns the cached item, or an error that wraps repository.ErrItemNotFound.
+func (inf *Informer) GetByTypeAndName(_ context.Context, typ, name string) (*core.Item, error) {
+	inf.mu.RLock()
+	defer inf.mu.RUnlock()
+
+	item, ok := inf.items[name]
+	if !ok || typ != inf.typ {
+		return nil, errors.Wrapf(repository.ErrItemNotFound, "error on find %s '%s' in cache", typ, name)
+	}
+
+	res := item.DeepCopy()
+
+	return &res, nil
+}
+
+// ListByType returns the cached items of the type in name order.
+func (inf *Informer) ListByType(_ context.Context, typ string) ([]core.Item, error) {
+	inf.mu.RLock()
+	defer inf.mu.RUnlock()
+
+	res := make([]core.Item, 0, len(inf.items))
+
+	if typ != inf.typ {
+		return res, nil
+	}
+
+	for _, item := range inf.items {
+		res = append(res, item.DeepCopy())
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res, nil
+}
@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// Request identifies an item to reconcile.
+type Request struct {
+	Type string
+	Name string
+}
+
+// RateLimiter decides how long requests wait before they're retried.
+type RateLimiter interface {
+	// When returns how long the request waits, and counts it as a retry.
+	When(req Request) time.Duration
+	// Forget stops counting retries of the request.
+	Forget(req Request)
+	// Retries returns the number of retries of the request.
+	Retries(req Request) int
+}
+
+// backoffRateLimiter doubles the wait of a request on every retry, from base up to max.
+type backoffRateLimiter struct {
+	base     time.Duration
+	max      time.Duration
+	mu       sync.Mutex
+	failures map[Request]int
+}
+
+// NewBackoffRateLimiter returns a RateLimiter that waits base on the first retry of a request, and doubles the wait on
+// every retry after that, up to max.
+func NewBackoffRateLimiter(base, max time.Duration) RateLimiter {
+	return &backoffRateLimiter{
+		base:     base,
+		max:      max,
+		mu:       sync.Mutex{},
+		failures: make(map[Request]int),
+	}
+}
+
+func (rl *backoffRateLimiter) When(req Request) time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	failures := rl.failures[req]
+	rl.failures[req] = failures + 1
+
+	res := rl.base
+
+	for i := 0; i < failures && res < rl.max; i++ {
+		res *= 2
+	}
+
+	if res > rl.max {
+		return rl.max
+	}
+
+	return res
+}
+
+func (rl *backoffRateLimiter) Forget(req Request) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	delete(rl.failures, req)
+}
+
+func (rl *backoffRateLimiter) Retries(req Request) int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	return rl.failures[req]
+}
+
+// Queue is a work queue of requests. A request is in the queue once however many times it's added, and isn't handed
+// out again while it's processed, so an item is reconciled by one worker at a time.
+type Queue struct {
+	limiter      RateLimiter
+	mu           sync.Mutex
+	cond         *sync.Cond
+	queue        []Request
+	dirty        map[Request]struct{}
+	processing   map[Request]struct{}
+	shuttingDown bool
+}
+
+func NewQueue(limiter RateLimiter) *Queue {
+	q := &Queue{
+		limiter:      limiter,
+		mu:           sync.Mutex{},
+		cond:         nil,
+		queue:        nil,
+		dirty:        make(map[Request]struct{}),
+		processing:   make(map[Request]struct{}),
+		shuttingDown: false,
+	}
+
+	q.cond = sync.NewCond(&q.mu)
+
+	return q
+}
+
+// Add adds the request, unless it's already waiting in the queue. Requests that are processed are added back once
+// they're done.
+func (q *Queue) Add(req Request) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.shuttingDown {
+		return
+	}
+
+	if _, ok := q.dirty[req]; ok {
+		return
+	}
+
+	q.dirty[req] = struct{}{}
+
+	if _, ok := q.processing[req]; ok {
+		return
+	}
+
+	q.queue = append(q.queue, req)
+	q.cond.Signal()
+}
+
+// AddAfter adds the request once the duration passes.
+func (q *Queue) AddAfter(req Request, d time.Duration) {
+	if d <= 0 {
+		q.Add(req)
+
+		return
+	}
+
+	time.AfterFunc(d, func() { q.Add(req) })
+}
+
+// AddRateLimited adds the request after the wait the rate limiter gives it.
+func (q *Queue) AddRateLimited(req Request) {
+	q.AddAfter(req, q.limiter.When(req))
+}
+
+// Forget stops counting retries of the request, like after it succeeds.
+func (q *Queue) Forget(req Request) {
+	q.limiter.Forget(req)
+}
+
+// Retries returns the number of times the request was added rate limited since it was last forgotten.
+func (q *Queue) Retries(req Request) int {
+	return q.limiter.Retries(req)
+}
+
+// Get waits for a request and marks it as processed. It returns false once the queue shuts down. Done should be
+// called with the request when it's processed.
+func (q *Queue) Get() (Request, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.queue) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+
+	if q.shuttingDown {
+		return Request{Type: "", Name: ""}, false
+	}
+
+	req := q.queue[0]
+	q.queue = q.queue[1:]
+
+	q.processing[req] = struct{}{}
+	delete(q.dirty, req)
+
+	return req, true
+}
+
+// Done marks the request as processed, and adds it back if it was added while it was processed.
+func (q *Queue) Done(req Request) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.processing, req)
+
+	if _, ok := q.dirty[req]; ok {
+		q.queue = append(q.queue, req)
+		q.cond.Signal()
+	}
+}
+
+// Len returns the number of requests waiting in the queue.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.queue)
+}
+
+// ShutDown stops the queue. Get returns false from then on, and waiting and later requests are dropped.
+func (q *Queue) ShutDown() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
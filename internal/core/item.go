@@ -10,8 +10,22 @@ import (
 const (
 	NameRegex = "^[a-z][a-z0-9-]{1,254}[a-z0-9]$"
 	TypeRegex = "^[a-z][a-z0-9-]{1,254}[a-z0-9]$"
+	// LabelKeyRegex matches label keys, which are names with an optional DNS subdomain prefix, like "example.com/tier".
+	// Names are up to 63 characters and prefixes up to 253.
+	LabelKeyRegex = `^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`
+	// LabelValueRegex matches label values, which are up to 63 characters.
+	LabelValueRegex = `^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`
 )
 
+// reservedFields are the keys of the json form of items that UnmarshalJSON decodes into fields other than Data. Fields
+// of items other than the ones of the first version are under the metadata key, so they don't take more keys of data.
+var reservedFields = []string{"uuid", "type", "name", "createdAt", "updatedAt", "_status", "metadata"} //nolint:gochecknoglobals
+
+// ReservedFields returns the keys of the json form of items that aren't data, so data can't have them.
+func ReservedFields() []string {
+	return append([]string{}, reservedFields...)
+}
+
 type Item struct {
 	UUID      string `json:"uuid"`
 	Type      string `json:"type"`
@@ -19,6 +33,25 @@ type Item struct {
 	Data      map[string]interface{}
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+	// Status is what controllers report about the item. It's only written through the status endpoints, and is the
+	// _status key of the json form, so data can have a status field of its own.
+	Status map[string]interface{} `json:"_status,omitempty"`
+	// Transitions are the latest changes of the workflow state of the item, oldest first.
+	Transitions []TransitionRecord `json:"transitions,omitempty"`
+	// Labels are identifying key/value pairs items are selected by.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are free-form key/value pairs for tools and people.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// OwnerReferences are the items the item belongs to. It's deleted once all of them are gone.
+	OwnerReferences []OwnerReference `json:"ownerReferences,omitempty"`
+	// Finalizers hold off deleting the item until they're all removed.
+	Finalizers []string `json:"finalizers,omitempty"`
+	// DeletionTimestamp is when deleting the item was asked for, if it's waiting for its finalizers.
+	DeletionTimestamp *time.Time `json:"deletionTimestamp,omitempty"`
+	// LastApplied is what the last apply of the item asked for, if it was ever applied.
+	LastApplied *AppliedItem `json:"lastApplied,omitempty"`
+	// ManagedFields maps dotted paths of data fields to the field managers that last wrote them.
+	ManagedFields map[string]string `json:"managedFields,omitempty"`
 }
 
 func (item Item) MarshalJSON() ([]byte, error) {
@@ -31,8 +64,16 @@ func (item Item) MarshalJSON() ([]byte, error) {
 	m["uuid"] = item.UUID
 	m["type"] = item.Type
 	m["name"] = item.Name
-	m["createdAt"] = item.CreatedAt.Format(time.RFC3339)
-	m["updatedAt"] = item.UpdatedAt.Format(time.RFC3339)
+	m["createdAt"] = item.CreatedAt.Format(time.RFC3339Nano)
+	m["updatedAt"] = item.UpdatedAt.Format(time.RFC3339Nano)
+
+	if item.Status != nil {
+		m["_status"] = item.Status
+	}
+
+	if meta := item.metadata(); len(meta) != 0 {
+		m["metadata"] = meta
+	}
 
 	res, err := json.Marshal(m)
 	if err != nil {
@@ -99,6 +140,31 @@ func (item *Item) UnmarshalJSON(data []byte) error {
 			}
 
 			item.UpdatedAt = t
+		case "_status":
+			if v == nil {
+				continue
+			}
+
+			f, ok := v.(map[string]interface{})
+			if !ok {
+				return errors.New("field _status is not object")
+			}
+
+			item.Status = f
+		case "metadata":
+			if v == nil {
+				continue
+			}
+
+			f, ok := v.(map[string]interface{})
+			if !ok {
+				return errors.New("field metadata is not object")
+			}
+
+			err = item.setMetadata(f)
+			if err != nil {
+				return errors.Wrap(err, "field metadata is not valid")
+			}
 		default:
 			item.Data[k] = v
 		}
@@ -107,6 +173,358 @@ func (item *Item) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// metadata returns the json form of the fields of the item that are under the metadata key.
+func (item Item) metadata() map[string]interface{} {
+	m := make(map[string]interface{})
+
+	if len(item.Transitions) != 0 {
+		m["transitions"] = item.Transitions
+	}
+
+	if len(item.Labels) != 0 {
+		m["labels"] = item.Labels
+	}
+
+	if len(item.Annotations) != 0 {
+		m["annotations"] = item.Annotations
+	}
+
+	if len(item.OwnerReferences) != 0 {
+		m["ownerReferences"] = item.OwnerReferences
+	}
+
+	if len(item.Finalizers) != 0 {
+		m["finalizers"] = item.Finalizers
+	}
+
+	if item.DeletionTimestamp != nil {
+		m["deletionTimestamp"] = item.DeletionTimestamp.Format(time.RFC3339Nano)
+	}
+
+	if item.LastApplied != nil {
+		m["lastApplied"] = item.LastApplied
+	}
+
+	if len(item.ManagedFields) != 0 {
+		m["managedFields"] = item.ManagedFields
+	}
+
+	return m
+}
+
+// setMetadata decodes the json form of the metadata key into the fields of the item. Unknown keys are rejected, so
+// they aren't dropped silently.
+func (item *Item) setMetadata(meta map[string]interface{}) error {
+	for k, v := range meta {
+		if v == nil {
+			continue
+		}
+
+		switch k {
+		case "transitions":
+			f, err := transitionRecords(v)
+			if err != nil {
+				return errors.Wrap(err, "field transitions is not valid")
+			}
+
+			item.Transitions = f
+		case "lastApplied":
+			f, err := appliedItem(v)
+			if err != nil {
+				return errors.Wrap(err, "field lastApplied is not valid")
+			}
+
+			item.LastApplied = f
+		case "labels":
+			f, err := stringMap(v)
+			if err != nil {
+				return errors.Wrap(err, "field labels is not valid")
+			}
+
+			item.Labels = f
+		case "annotations":
+			f, err := stringMap(v)
+			if err != nil {
+				return errors.Wrap(err, "field annotations is not valid")
+			}
+
+			item.Annotations = f
+		case "ownerReferences":
+			f, err := ownerReferences(v)
+			if err != nil {
+				return errors.Wrap(err, "field ownerReferences is not valid")
+			}
+
+			item.OwnerReferences = f
+		case "finalizers":
+			f, err := stringList(v)
+			if err != nil {
+				return errors.Wrap(err, "field finalizers is not valid")
+			}
+
+			item.Finalizers = f
+		case "deletionTimestamp":
+			f, ok := v.(string)
+			if !ok {
+				return errors.New("field deletionTimestamp is not string")
+			}
+
+			t, err := time.Parse(time.RFC3339, f)
+			if err != nil {
+				return errors.Wrap(err, "error on parse deletionTimestamp time string")
+			}
+
+			item.DeletionTimestamp = &t
+		case "managedFields":
+			f, err := stringMap(v)
+			if err != nil {
+				return errors.Wrap(err, "field managedFields is not valid")
+			}
+
+			item.ManagedFields = f
+		default:
+			return errors.Errorf("field %s is not known", k)
+		}
+	}
+
+	return nil
+}
+
 type ItemList struct {
 	Items []Item `json:"items"`
+	// Next is the cursor of the next page, empty on the last page.
+	Next string `json:"next,omitempty"`
+}
+
+func (item Item) DeepCopy() Item {
+	res := item
+	res.Data = copyMap(item.Data)
+	res.Status = copyMap(item.Status)
+	res.LastApplied = item.LastApplied.DeepCopy()
+	res.ManagedFields = copyStrings(item.ManagedFields)
+	res.Labels = copyStrings(item.Labels)
+	res.Annotations = copyStrings(item.Annotations)
+
+	if item.OwnerReferences != nil {
+		res.OwnerReferences = append([]OwnerReference{}, item.OwnerReferences...)
+	}
+
+	if item.Transitions != nil {
+		res.Transitions = append([]TransitionRecord{}, item.Transitions...)
+	}
+
+	if item.Finalizers != nil {
+		res.Finalizers = append([]string{}, item.Finalizers...)
+	}
+
+	if item.DeletionTimestamp != nil {
+		t := *item.DeletionTimestamp
+		res.DeletionTimestamp = &t
+	}
+
+	return res
+}
+
+// AppliedItem is what an apply of an item asked for. Apply removes the data fields, labels and annotations of the last
+// one that the next one doesn't have.
+type AppliedItem struct {
+	Data        map[string]interface{} `json:"data"`
+	Labels      map[string]string      `json:"labels,omitempty"`
+	Annotations map[string]string      `json:"annotations,omitempty"`
+}
+
+func (applied *AppliedItem) DeepCopy() *AppliedItem {
+	if applied == nil {
+		return nil
+	}
+
+	return &AppliedItem{Data: copyMap(applied.Data), Labels: copyStrings(applied.Labels), Annotations: copyStrings(applied.Annotations)}
+}
+
+func appliedItem(v interface{}) (*AppliedItem, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("value is not object")
+	}
+
+	res := AppliedItem{Data: make(map[string]interface{}), Labels: nil, Annotations: nil}
+
+	if data, ok := m["data"]; ok {
+		f, ok := data.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("field data is not object")
+		}
+
+		res.Data = f
+	}
+
+	if labels, ok := m["labels"]; ok {
+		f, err := stringMap(labels)
+		if err != nil {
+			return nil, errors.Wrap(err, "field labels is not valid")
+		}
+
+		res.Labels = f
+	}
+
+	if annotations, ok := m["annotations"]; ok {
+		f, err := stringMap(annotations)
+		if err != nil {
+			return nil, errors.Wrap(err, "field annotations is not valid")
+		}
+
+		res.Annotations = f
+	}
+
+	return &res, nil
+}
+
+// OwnerReference identifies an owner of an item. The uuid tells the owner apart from a later item with its name.
+type OwnerReference struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	UUID string `json:"uuid"`
+}
+
+func ownerReferences(v interface{}) ([]OwnerReference, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("value is not list")
+	}
+
+	res := make([]OwnerReference, 0, len(list))
+
+	for i := range list {
+		m, err := stringMap(list[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "element %d is not valid", i)
+		}
+
+		res = append(res, OwnerReference{Type: m["type"], Name: m["name"], UUID: m["uuid"]})
+	}
+
+	return res, nil
+}
+
+// TransitionRecord is a change of the workflow state of an item.
+type TransitionRecord struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Actor is the field manager of the write, if it had one.
+	Actor string    `json:"actor,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+func transitionRecords(v interface{}) ([]TransitionRecord, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("value is not list")
+	}
+
+	res := make([]TransitionRecord, 0, len(list))
+
+	for i := range list {
+		m, err := stringMap(list[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "element %d is not valid", i)
+		}
+
+		at, err := time.Parse(time.RFC3339, m["at"])
+		if err != nil {
+			return nil, errors.Wrapf(err, "error on parse at time string of element %d", i)
+		}
+
+		res = append(res, TransitionRecord{From: m["from"], To: m["to"], Actor: m["actor"], At: at})
+	}
+
+	return res, nil
+}
+
+func stringList(v interface{}) ([]string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("value is not list")
+	}
+
+	res := make([]string, 0, len(list))
+
+	for i := range list {
+		s, ok := list[i].(string)
+		if !ok {
+			return nil, errors.Errorf("element %d is not string", i)
+		}
+
+		res = append(res, s)
+	}
+
+	return res, nil
+}
+
+func stringMap(v interface{}) (map[string]string, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("value is not object")
+	}
+
+	res := make(map[string]string, len(m))
+
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("value of '%s' is not string", k)
+		}
+
+		res[k] = s
+	}
+
+	return res, nil
+}
+
+func copyStrings(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	res := make(map[string]string, len(m))
+
+	for k, v := range m {
+		res[k] = v
+	}
+
+	return res
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+
+	res := make(map[string]interface{}, len(m))
+
+	for k, v := range m {
+		res[k] = copyValue(v)
+	}
+
+	return res
+}
+
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return copyMap(v)
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+
+		res := make([]interface{}, len(v))
+
+		for i := range v {
+			res[i] = copyValue(v[i])
+		}
+
+		return res
+	default:
+		return v
+	}
 }
@@ -0,0 +1,110 @@
+package core
+
+// DeletePolicy decides what happens to items referencing an item that is deleted.
+type DeletePolicy string
+
+const (
+	// DeleteRestrict rejects deleting items that are referenced.
+	DeleteRestrict DeletePolicy = "Restrict"
+	// DeleteCascade deletes the referencing items too.
+	DeleteCascade DeletePolicy = "Cascade"
+	// DeleteSetNull sets the referencing fields to null.
+	DeleteSetNull DeletePolicy = "SetNull"
+)
+
+// Schema declares rules of items of a type.
+type Schema struct {
+	Type string `json:"type"`
+	// References maps dotted paths of data fields to the items they reference. Paths go through lists, so
+	// "lines.product" is the product field of every element of the lines list.
+	References map[string]Reference `json:"references,omitempty"`
+	// Webhooks review writes of items of the type before they're stored.
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+	// Script is a Starlark script with hooks that validate and change writes of items of the type, named
+	// beforeCreate, beforeUpdate, beforeDelete and afterWrite.
+	Script string `json:"script,omitempty"`
+	// Rules are checked on creates and updates of items of the type.
+	Rules []Rule `json:"rules,omitempty"`
+	// Workflow is a state machine over a data field of items of the type.
+	Workflow *Workflow `json:"workflow,omitempty"`
+}
+
+// Rule is a CEL expression that items should satisfy, like "self.endDate > self.startDate". Self is data of the item,
+// and oldSelf is data of the stored item. Rules that use oldSelf are only checked on updates.
+type Rule struct {
+	Name string `json:"name"`
+	Rule string `json:"rule"`
+	// Message tells why items that don't satisfy the rule are rejected.
+	Message string `json:"message,omitempty"`
+}
+
+// Reference declares a data field holding the name or uuid of an item of another type.
+type Reference struct {
+	Type string `json:"type"`
+	// OnDelete is the policy applied when the referenced item is deleted. It's DeleteRestrict if empty.
+	OnDelete DeletePolicy `json:"onDelete,omitempty"`
+}
+
+// WebhookKind decides whether a webhook can change the items it reviews.
+type WebhookKind string
+
+const (
+	// WebhookValidating accepts or rejects writes.
+	WebhookValidating WebhookKind = "Validating"
+	// WebhookMutating can also change the written item with a JSON patch. Mutating webhooks go before validating ones.
+	WebhookMutating WebhookKind = "Mutating"
+)
+
+// Operation is a kind of write that webhooks review.
+type Operation string
+
+const (
+	OperationCreate Operation = "CREATE"
+	OperationUpdate Operation = "UPDATE"
+	OperationDelete Operation = "DELETE"
+)
+
+// FailurePolicy decides what happens to writes when a webhook can't be reached or doesn't answer in time.
+type FailurePolicy string
+
+const (
+	// FailurePolicyFail rejects the write.
+	FailurePolicyFail FailurePolicy = "Fail"
+	// FailurePolicyIgnore lets the write through as if the webhook accepted it.
+	FailurePolicyIgnore FailurePolicy = "Ignore"
+)
+
+// Webhook is an HTTP endpoint that reviews writes of items before they're stored.
+type Webhook struct {
+	Name string      `json:"name"`
+	Kind WebhookKind `json:"kind"`
+	URL  string      `json:"url"`
+	// Operations are the writes the webhook reviews. It reviews all of them if empty.
+	Operations []Operation `json:"operations,omitempty"`
+	// TimeoutSeconds is how long a review may take. It's 10 if zero.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// FailurePolicy is FailurePolicyFail if empty.
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// Workflow is a state machine over a data field. Items start in the initial state, and the field only changes along
+// the transitions.
+type Workflow struct {
+	// Field is the data field holding the state, like "state".
+	Field   string   `json:"field"`
+	States  []string `json:"states"`
+	Initial string   `json:"initial"`
+	// Transitions are the allowed changes of the state.
+	Transitions []Transition `json:"transitions"`
+}
+
+// Transition allows changing the state to To from any of From.
+type Transition struct {
+	From []string `json:"from"`
+	To   string   `json:"to"`
+	// Guard is a CEL expression the transition should satisfy, like rules. Self is data of the item after the
+	// transition, and oldSelf before it.
+	Guard string `json:"guard,omitempty"`
+	// Roles are the roles allowed to make the transition. Anyone can make it if empty.
+	Roles []string `json:"roles,omitempty"`
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nasermirzaei89/core/internal/core"
+)
+
+type EventType string
+
+const (
+	EventAdded    EventType = "ADDED"
+	EventModified EventType = "MODIFIED"
+	EventDeleted  EventType = "DELETED"
+)
+
+type Event struct {
+	Type EventType `json:"type"`
+	Item core.Item `json:"item"`
+}
+
+// ItemWatcher is implemented by repositories that can stream changes to their items.
+// An empty type watches all types. The channel is closed when ctx is done, or when the watcher falls too far behind.
+type ItemWatcher interface {
+	Watch(ctx context.Context, typ string) (events <-chan Event, err error)
+}
+
+const watchBufferSize = 256
+
+type subscription struct {
+	typ    string
+	events chan Event
+}
+
+// Broadcaster fans out events to watchers. Its zero value is ready to use.
+type Broadcaster struct {
+	subs map[*subscription]struct{}
+	mu   sync.Mutex
+}
+
+func (b *Broadcaster) Watch(ctx context.Context, typ string) (<-chan Event, error) {
+	sub := &subscription{
+		typ:    typ,
+		events: make(chan Event, watchBufferSize),
+	}
+
+	b.mu.Lock()
+
+	if b.subs == nil {
+		b.subs = make(map[*subscription]struct{})
+	}
+
+	b.subs[sub] = struct{}{}
+
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.unsubscribe(sub)
+	}()
+
+	return sub.events, nil
+}
+
+// Publish sends the event to matching watchers without blocking. Watchers that can't keep up are dropped.
+func (b *Broadcaster) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if sub.typ != "" && sub.typ != event.Item.Type {
+			continue
+		}
+
+		select {
+		case sub.events <- Event{Type: event.Type, Item: event.Item.DeepCopy()}:
+		default:
+			b.unsubscribe(sub)
+		}
+	}
+}
+
+func (b *Broadcaster) unsubscribe(sub *subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+
+	delete(b.subs, sub)
+	close(sub.events)
+}
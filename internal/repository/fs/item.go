@@ -0,0 +1,739 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/nasermirzaei89/core/internal/repository"
+	"github.com/pkg/errors"
+)
+
+var (
+	_ repository.ItemRepository = &ItemRepository{}
+	_ repository.ItemWatcher    = &ItemRepository{}
+)
+
+const fileExt = ".json"
+
+// uuidNamespace derives stable uuids for hand-written files that don't carry one.
+var uuidNamespace = uuid.MustParse("6b0ef5a4-3c57-4d3b-9a3e-2c1f0d6e8b71") //nolint:gochecknoglobals
+
+type Options struct {
+	Root       string
+	WatchFiles bool
+	// OnError is called with errors of item files that are skipped, like unreadable or corrupt ones, so one bad file
+	// doesn't fail listing the others. They're logged if it's nil.
+	OnError func(err error)
+}
+
+type itemKey struct {
+	typ  string
+	name string
+}
+
+type fileState struct {
+	sum  [sha256.Size]byte
+	item core.Item
+}
+
+// ItemRepository stores each item as <root>/<type>/<name>.json. Files are the source of truth:
+// reads go to disk, and the in-memory uuid index is refreshed from whatever they find there.
+type ItemRepository struct {
+	root    string
+	onError func(err error)
+	index   map[string]itemKey
+	files   map[itemKey]fileState
+	mu      sync.Mutex
+	events  repository.Broadcaster
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func (repo *ItemRepository) Insert(_ context.Context, item core.Item) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.index[item.UUID]; ok {
+		return errors.Errorf("item with uuid '%s' already exists", item.UUID)
+	}
+
+	key := itemKey{typ: item.Type, name: item.Name}
+
+	err := repo.refresh(key)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := repo.files[key]; ok {
+		return errors.Errorf("item with type '%s' and name '%s' already exists", item.Type, item.Name)
+	}
+
+	err = repo.write(item)
+	if err != nil {
+		return err
+	}
+
+	repo.events.Publish(repository.Event{Type: repository.EventAdded, Item: item})
+
+	return nil
+}
+
+func (repo *ItemRepository) ListByType(_ context.Context, typ string) ([]core.Item, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	err := repo.refreshType(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]core.Item, 0)
+
+	for key, state := range repo.files {
+		if key.typ == typ {
+			res = append(res, state.item.DeepCopy())
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res, nil
+}
+
+// ListTypes returns the types that have items. Watched repositories have them in memory already, and others find them
+// by listing directories, without reading item files.
+func (repo *ItemRepository) ListTypes(_ context.Context) ([]string, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	types := make(map[string]struct{})
+
+	if repo.watcher != nil {
+		for key := range repo.files {
+			types[key.typ] = struct{}{}
+		}
+	} else {
+		var err error
+
+		types, err = repo.listTypeDirs()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res := make([]string, 0, len(types))
+
+	for typ := range types {
+		res = append(res, typ)
+	}
+
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// EachByType calls fn for the items of the type in name order. Files that can't be read are skipped and reported to
+// the error handler of the repository.
+func (repo *ItemRepository) EachByType(ctx context.Context, typ string, fn func(item core.Item) error) error {
+	entries, err := os.ReadDir(filepath.Join(repo.root, typ))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return errors.Wrap(err, "error on read type directory")
+	}
+
+	// file names don't sort like names, as in "a-b.json" and "a.json"
+	names := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		if name, ok := itemName(entry.Name()); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		item, err := repo.GetByTypeAndName(ctx, typ, name)
+		if err != nil {
+			if !errors.Is(err, repository.ErrItemNotFound) {
+				repo.onError(err)
+			}
+
+			continue
+		}
+
+		err = fn(*item)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repo *ItemRepository) GetByTypeAndName(_ context.Context, typ, name string) (*core.Item, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := itemKey{typ: typ, name: name}
+
+	err := repo.refresh(key)
+	if err != nil {
+		return nil, err
+	}
+
+	state, ok := repo.files[key]
+	if !ok {
+		return nil, repository.ErrItemNotFound
+	}
+
+	res := state.item.DeepCopy()
+
+	return &res, nil
+}
+
+func (repo *ItemRepository) Replace(_ context.Context, itemUUID string, item core.Item) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if item.UUID != itemUUID {
+		return errors.New("field uuid is immutable")
+	}
+
+	oldKey, ok := repo.index[itemUUID]
+	if !ok {
+		return errors.Errorf("item with uuid '%s' doesn't exist", itemUUID)
+	}
+
+	key := itemKey{typ: item.Type, name: item.Name}
+
+	if key != oldKey {
+		err := repo.refresh(key)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := repo.files[key]; ok {
+			return errors.Errorf("item with type '%s' and name '%s' already exists", item.Type, item.Name)
+		}
+	}
+
+	err := repo.write(item)
+	if err != nil {
+		return err
+	}
+
+	if key != oldKey {
+		err = repo.removeFile(oldKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	repo.events.Publish(repository.Event{Type: repository.EventModified, Item: item})
+
+	return nil
+}
+
+func (repo *ItemRepository) Delete(_ context.Context, itemUUID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key, ok := repo.index[itemUUID]
+	if !ok {
+		return errors.Errorf("item with uuid '%s' doesn't exist", itemUUID)
+	}
+
+	item := repo.files[key].item
+
+	err := repo.removeFile(key)
+	if err != nil {
+		return err
+	}
+
+	repo.events.Publish(repository.Event{Type: repository.EventDeleted, Item: item})
+
+	return nil
+}
+
+func (repo *ItemRepository) Watch(ctx context.Context, typ string) (<-chan repository.Event, error) {
+	return repo.events.Watch(ctx, typ)
+}
+
+func (repo *ItemRepository) path(key itemKey) string {
+	return filepath.Join(repo.root, key.typ, key.name+fileExt)
+}
+
+// write stores the item atomically by writing a temp file next to the target and renaming it over.
+func (repo *ItemRepository) write(item core.Item) error {
+	key := itemKey{typ: item.Type, name: item.Name}
+
+	data, err := encodeItem(item)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(repo.root, key.typ)
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return errors.Wrap(err, "error on create type directory")
+	}
+
+	err = repo.watchDir(dir)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+key.name+fileExt+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "error on create temp file")
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		_ = tmp.Close()
+
+		return errors.Wrap(err, "error on write temp file")
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		_ = tmp.Close()
+
+		return errors.Wrap(err, "error on sync temp file")
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return errors.Wrap(err, "error on close temp file")
+	}
+
+	err = os.Chmod(tmp.Name(), 0o644)
+	if err != nil {
+		return errors.Wrap(err, "error on change temp file mode")
+	}
+
+	err = os.Rename(tmp.Name(), repo.path(key))
+	if err != nil {
+		return errors.Wrap(err, "error on rename temp file")
+	}
+
+	err = syncDir(dir)
+	if err != nil {
+		return err
+	}
+
+	repo.set(key, fileState{sum: sha256.Sum256(data), item: item.DeepCopy()})
+
+	return nil
+}
+
+func (repo *ItemRepository) removeFile(key itemKey) error {
+	err := os.Remove(repo.path(key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(err, "error on remove item file")
+	}
+
+	repo.unset(key)
+
+	return nil
+}
+
+func (repo *ItemRepository) set(key itemKey, state fileState) {
+	if old, ok := repo.files[key]; ok && old.item.UUID != state.item.UUID {
+		delete(repo.index, old.item.UUID)
+	}
+
+	repo.files[key] = state
+	repo.index[state.item.UUID] = key
+}
+
+func (repo *ItemRepository) unset(key itemKey) {
+	if old, ok := repo.files[key]; ok {
+		delete(repo.index, old.item.UUID)
+		delete(repo.files, key)
+	}
+}
+
+// refresh reconciles the known state of a key with its file, publishing an event if the file was changed by someone else.
+func (repo *ItemRepository) refresh(key itemKey) error {
+	old, known := repo.files[key]
+
+	data, err := os.ReadFile(repo.path(key))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return errors.Wrap(err, "error on read item file")
+		}
+
+		if known {
+			repo.unset(key)
+			repo.events.Publish(repository.Event{Type: repository.EventDeleted, Item: old.item})
+		}
+
+		return nil
+	}
+
+	sum := sha256.Sum256(data)
+
+	if known && sum == old.sum {
+		return nil
+	}
+
+	info, err := os.Stat(repo.path(key))
+	if err != nil {
+		return errors.Wrap(err, "error on stat item file")
+	}
+
+	item, err := decodeItem(key, data, info.ModTime())
+	if err != nil {
+		return errors.Wrapf(err, "error on decode item file '%s'", repo.path(key))
+	}
+
+	if other, ok := repo.index[item.UUID]; ok && other != key {
+		return errors.Errorf("item file '%s' has the same uuid as '%s'", repo.path(key), repo.path(other))
+	}
+
+	repo.set(key, fileState{sum: sum, item: item})
+
+	if known {
+		repo.events.Publish(repository.Event{Type: repository.EventModified, Item: item})
+	} else {
+		repo.events.Publish(repository.Event{Type: repository.EventAdded, Item: item})
+	}
+
+	return nil
+}
+
+func (repo *ItemRepository) refreshType(typ string) error {
+	keys := make(map[itemKey]struct{})
+
+	for key := range repo.files {
+		if key.typ == typ {
+			keys[key] = struct{}{}
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(repo.root, typ))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(err, "error on read type directory")
+	}
+
+	for _, entry := range entries {
+		if name, ok := itemName(entry.Name()); ok && !entry.IsDir() {
+			keys[itemKey{typ: typ, name: name}] = struct{}{}
+		}
+	}
+
+	// a bad file only hides its own item
+	for key := range keys {
+		err = repo.refresh(key)
+		if err != nil {
+			repo.onError(err)
+		}
+	}
+
+	return nil
+}
+
+// listTypeDirs returns the types whose directories have item files.
+func (repo *ItemRepository) listTypeDirs() (map[string]struct{}, error) {
+	entries, err := os.ReadDir(repo.root)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on read root directory")
+	}
+
+	res := make(map[string]struct{})
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !isValid(core.TypeRegex, entry.Name()) {
+			continue
+		}
+
+		files, err := os.ReadDir(filepath.Join(repo.root, entry.Name()))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, errors.Wrap(err, "error on read type directory")
+		}
+
+		for _, file := range files {
+			if _, ok := itemName(file.Name()); ok && !file.IsDir() {
+				res[entry.Name()] = struct{}{}
+
+				break
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func (repo *ItemRepository) refreshAll() error {
+	types := make(map[string]struct{})
+
+	for key := range repo.files {
+		types[key.typ] = struct{}{}
+	}
+
+	entries, err := os.ReadDir(repo.root)
+	if err != nil {
+		return errors.Wrap(err, "error on read root directory")
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && isValid(core.TypeRegex, entry.Name()) {
+			types[entry.Name()] = struct{}{}
+		}
+	}
+
+	for typ := range types {
+		err = repo.refreshType(typ)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repo *ItemRepository) watchDir(dir string) error {
+	if repo.watcher == nil {
+		return nil
+	}
+
+	err := repo.watcher.Add(dir)
+	if err != nil {
+		return errors.Wrapf(err, "error on watch directory '%s'", dir)
+	}
+
+	return nil
+}
+
+func (repo *ItemRepository) watch() {
+	defer repo.wg.Done()
+
+	for {
+		select {
+		case <-repo.done:
+			return
+		case event, ok := <-repo.watcher.Events:
+			if !ok {
+				return
+			}
+
+			repo.handleFileEvent(event)
+		case _, ok := <-repo.watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+func (repo *ItemRepository) handleFileEvent(event fsnotify.Event) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	rel, err := filepath.Rel(repo.root, event.Name)
+	if err != nil {
+		return
+	}
+
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+
+	switch len(parts) {
+	case 1:
+		if !isValid(core.TypeRegex, parts[0]) {
+			return
+		}
+
+		if event.Op&fsnotify.Create != 0 {
+			_ = repo.watchDir(event.Name)
+		}
+
+		_ = repo.refreshType(parts[0])
+	case 2: //nolint:gomnd
+		name, ok := itemName(parts[1])
+		if !ok || !isValid(core.TypeRegex, parts[0]) {
+			return
+		}
+
+		_ = repo.refresh(itemKey{typ: parts[0], name: name})
+	}
+}
+
+// Close stops watching the root directory. It's a no-op if the repository doesn't watch files.
+func (repo *ItemRepository) Close() error {
+	if repo.watcher == nil {
+		return nil
+	}
+
+	close(repo.done)
+
+	err := repo.watcher.Close()
+
+	repo.wg.Wait()
+
+	if err != nil {
+		return errors.Wrap(err, "error on close file watcher")
+	}
+
+	return nil
+}
+
+// NewItemRepository indexes the items under opts.Root and, if opts.WatchFiles is set,
+// keeps watching it so changes made outside the API are picked up and published to watchers.
+func NewItemRepository(opts Options) (*ItemRepository, error) {
+	err := os.MkdirAll(opts.Root, 0o755)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on create root directory")
+	}
+
+	repo := &ItemRepository{
+		root:    opts.Root,
+		onError: opts.OnError,
+		index:   make(map[string]itemKey),
+		files:   make(map[itemKey]fileState),
+		mu:      sync.Mutex{},
+		events:  repository.Broadcaster{},
+		watcher: nil,
+		done:    make(chan struct{}),
+		wg:      sync.WaitGroup{},
+	}
+
+	if repo.onError == nil {
+		repo.onError = func(err error) { log.Printf("fs item repository: %s", err.Error()) }
+	}
+
+	if opts.WatchFiles {
+		repo.watcher, err = fsnotify.NewWatcher()
+		if err != nil {
+			return nil, errors.Wrap(err, "error on create file watcher")
+		}
+
+		err = repo.watchDir(repo.root)
+		if err != nil {
+			_ = repo.watcher.Close()
+
+			return nil, err
+		}
+	}
+
+	err = repo.refreshAll()
+	if err != nil {
+		_ = repo.Close()
+
+		return nil, err
+	}
+
+	if repo.watcher != nil {
+		for key := range repo.files {
+			err = repo.watchDir(filepath.Join(repo.root, key.typ))
+			if err != nil {
+				_ = repo.Close()
+
+				return nil, err
+			}
+		}
+
+		repo.wg.Add(1)
+
+		go repo.watch()
+	}
+
+	return repo, nil
+}
+
+func itemName(fileName string) (string, bool) {
+	if strings.HasPrefix(fileName, ".") || !strings.HasSuffix(fileName, fileExt) {
+		return "", false
+	}
+
+	name := strings.TrimSuffix(fileName, fileExt)
+
+	return name, isValid(core.NameRegex, name)
+}
+
+func isValid(regex, s string) bool {
+	return regexp.MustCompile(regex).MatchString(s)
+}
+
+func encodeItem(item core.Item) ([]byte, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on marshal item")
+	}
+
+	var buf bytes.Buffer
+
+	err = json.Indent(&buf, data, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "error on indent item json")
+	}
+
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// decodeItem reads an item file. Type and name always come from the path; a missing uuid is derived from them
+// and missing timestamps fall back to the file's modification time.
+func decodeItem(key itemKey, data []byte, modTime time.Time) (core.Item, error) {
+	var item core.Item
+
+	err := json.Unmarshal(data, &item)
+	if err != nil {
+		return core.Item{}, errors.Wrap(err, "error on unmarshal item")
+	}
+
+	item.Type = key.typ
+	item.Name = key.name
+
+	if item.UUID == "" {
+		item.UUID = uuid.NewSHA1(uuidNamespace, []byte(key.typ+"/"+key.name)).String()
+	}
+
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = modTime
+	}
+
+	if item.UpdatedAt.IsZero() {
+		item.UpdatedAt = modTime
+	}
+
+	return item, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrap(err, "error on open directory")
+	}
+
+	defer func() { _ = d.Close() }()
+
+	err = d.Sync()
+	if err != nil {
+		return errors.Wrap(err, "error on sync directory")
+	}
+
+	return nil
+}
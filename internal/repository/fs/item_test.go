@@ -0,0 +1,257 @@
+package fs_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/nasermirzaei89/core/internal/repository"
+	"github.com/nasermirzaei89/core/internal/repository/fs"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newItem(typ, name string) core.Item {
+	now := time.Now().Truncate(time.Second)
+
+	return core.Item{
+		UUID:      uuid.NewString(),
+		Type:      typ,
+		Name:      name,
+		Data:      map[string]interface{}{"foo": "bar"},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func TestItemRepository_CRUD(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	itemRepo, err := fs.NewItemRepository(fs.Options{Root: root})
+	require.NoError(t, err)
+
+	item := newItem("bar", "foo")
+
+	err = itemRepo.Insert(ctx, item)
+	require.NoError(t, err)
+
+	assert.FileExists(t, filepath.Join(root, "bar", "foo.json"))
+
+	err = itemRepo.Insert(ctx, item)
+	assert.Error(t, err)
+
+	item2 := newItem("bar", "foo")
+
+	err = itemRepo.Insert(ctx, item2)
+	assert.Error(t, err)
+
+	item.Data["foo"] = "baz"
+
+	err = itemRepo.Replace(ctx, item.UUID, item)
+	require.NoError(t, err)
+
+	res, err := itemRepo.GetByTypeAndName(ctx, "bar", "foo")
+	require.NoError(t, err)
+	assert.EqualValues(t, item, *res)
+
+	err = itemRepo.Replace(ctx, uuid.NewString(), item)
+	assert.Error(t, err)
+
+	err = itemRepo.Delete(ctx, item.UUID)
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, filepath.Join(root, "bar", "foo.json"))
+
+	_, err = itemRepo.GetByTypeAndName(ctx, "bar", "foo")
+	assert.True(t, errors.Is(err, repository.ErrItemNotFound))
+
+	err = itemRepo.Delete(ctx, item.UUID)
+	assert.Error(t, err)
+}
+
+func TestItemRepository_RebuildIndex(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	itemRepo, err := fs.NewItemRepository(fs.Options{Root: root})
+	require.NoError(t, err)
+
+	item := newItem("bar", "foo")
+
+	require.NoError(t, itemRepo.Insert(ctx, item))
+	require.NoError(t, itemRepo.Insert(ctx, newItem("bar", "fee")))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "baz"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "baz", "qux.json"), []byte(`{"color": "red"}`), 0o600))
+
+	itemRepo, err = fs.NewItemRepository(fs.Options{Root: root})
+	require.NoError(t, err)
+
+	res, err := itemRepo.ListByType(ctx, "bar")
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, "fee", res[0].Name)
+	assert.Equal(t, "foo", res[1].Name)
+
+	err = itemRepo.Delete(ctx, item.UUID)
+	assert.NoError(t, err)
+
+	hand, err := itemRepo.GetByTypeAndName(ctx, "baz", "qux")
+	require.NoError(t, err)
+	assert.Equal(t, "red", hand.Data["color"])
+	assert.NotEmpty(t, hand.UUID)
+	assert.False(t, hand.CreatedAt.IsZero())
+
+	itemRepo, err = fs.NewItemRepository(fs.Options{Root: root})
+	require.NoError(t, err)
+
+	hand2, err := itemRepo.GetByTypeAndName(ctx, "baz", "qux")
+	require.NoError(t, err)
+	assert.Equal(t, hand.UUID, hand2.UUID)
+}
+
+func TestItemRepository_ListTypes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	itemRepo, err := fs.NewItemRepository(fs.Options{Root: root})
+	require.NoError(t, err)
+
+	require.NoError(t, itemRepo.Insert(ctx, newItem("bar", "foo")))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "baz"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "baz", "qux.json"), []byte(`{"color": "red"}`), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "empty"), 0o755))
+
+	res, err := itemRepo.ListTypes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar", "baz"}, res)
+
+	require.NoError(t, os.Remove(filepath.Join(root, "bar", "foo.json")))
+
+	res, err = itemRepo.ListTypes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"baz"}, res)
+}
+
+func TestItemRepository_EachByType(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+
+	itemRepo, err := fs.NewItemRepository(fs.Options{Root: root, WatchFiles: false, OnError: func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		errs = append(errs, err)
+	}})
+	require.NoError(t, err)
+
+	require.NoError(t, itemRepo.Insert(ctx, newItem("bar", "foo")))
+	require.NoError(t, itemRepo.Insert(ctx, newItem("bar", "foo-bar")))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "bar", "fee.json"), []byte(`{"color": `), 0o600))
+
+	names := make([]string, 0)
+
+	err = itemRepo.EachByType(ctx, "bar", func(item core.Item) error {
+		names = append(names, item.Name)
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo", "foo-bar"}, names)
+
+	res, err := itemRepo.ListByType(ctx, "bar")
+	require.NoError(t, err)
+	assert.Len(t, res, 2)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.NotEmpty(t, errs)
+	assert.Contains(t, errs[0].Error(), "fee.json")
+}
+
+func TestItemRepository_Watch(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	root := t.TempDir()
+
+	itemRepo, err := fs.NewItemRepository(fs.Options{Root: root, WatchFiles: true})
+	require.NoError(t, err)
+
+	defer func() { _ = itemRepo.Close() }()
+
+	events, err := itemRepo.Watch(ctx, "bar")
+	require.NoError(t, err)
+
+	item := newItem("bar", "foo")
+
+	require.NoError(t, itemRepo.Insert(ctx, item))
+
+	event := nextEvent(t, events)
+	assert.Equal(t, repository.EventAdded, event.Type)
+	assert.Equal(t, "foo", event.Item.Name)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "bar", "foo.json"), []byte(`{"foo": "edited"}`), 0o600))
+
+	event = nextEvent(t, events)
+	assert.Equal(t, repository.EventModified, event.Type)
+	assert.Equal(t, "edited", event.Item.Data["foo"])
+
+	res, err := itemRepo.GetByTypeAndName(ctx, "bar", "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "edited", res.Data["foo"])
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "bar", "fee.json"), []byte(`{}`), 0o600))
+
+	event = nextEvent(t, events)
+	assert.Equal(t, repository.EventAdded, event.Type)
+	assert.Equal(t, "fee", event.Item.Name)
+
+	require.NoError(t, os.Remove(filepath.Join(root, "bar", "fee.json")))
+
+	event = nextEvent(t, events)
+	assert.Equal(t, repository.EventDeleted, event.Type)
+	assert.Equal(t, "fee", event.Item.Name)
+}
+
+func nextEvent(t *testing.T, events <-chan repository.Event) repository.Event {
+	t.Helper()
+
+	select {
+	case event, ok := <-events:
+		require.True(t, ok)
+
+		return event
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timeout waiting for event")
+	}
+
+	return repository.Event{}
+}
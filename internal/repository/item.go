@@ -10,9 +10,42 @@ import (
 type ItemRepository interface {
 	Insert(ctx context.Context, item core.Item) (err error)
 	ListByType(ctx context.Context, typ string) (items []core.Item, err error)
+	ListTypes(ctx context.Context) (types []string, err error)
+	// EachByType calls fn for every item of the type in name order, without loading all of them at once.
+	// Iteration stops at the first error returned by fn.
+	EachByType(ctx context.Context, typ string, fn func(item core.Item) error) (err error)
 	GetByTypeAndName(ctx context.Context, typ, name string) (item *core.Item, err error)
 	Replace(ctx context.Context, itemUUID string, item core.Item) (err error)
 	Delete(ctx context.Context, itemUUID string) (err error)
 }
 
+// ItemOwnerIndex is implemented by repositories that index items by the uuids of their owners, so dependents of an owner
+// are found without scanning items of every type.
+type ItemOwnerIndex interface {
+	// ListByOwner returns items with the owner in their owner references, sorted by type and name.
+	ListByOwner(ctx context.Context, ownerUUID string) (items []core.Item, err error)
+}
+
+// ItemLabelIndex is implemented by repositories that index items by their labels, so items with a label are found
+// without scanning items of their type.
+type ItemLabelIndex interface {
+	// ListByLabel returns items of the type that have the label with one of the values, or with any value if there are
+	// no values, sorted by name.
+	ListByLabel(ctx context.Context, typ, key string, values []string) (items []core.Item, err error)
+}
+
+// ItemUUIDIndex is implemented by repositories that index items by uuid, so an item is found by its uuid without
+// scanning items of its type.
+type ItemUUIDIndex interface {
+	GetByUUID(ctx context.Context, itemUUID string) (item *core.Item, err error)
+}
+
+// ItemReferenceIndex is implemented by repositories that index items by the values of their fields, so items that
+// reference an item are found without scanning items of their type.
+type ItemReferenceIndex interface {
+	// ListByReference returns items of the type whose field at the dotted path has one of the values, sorted by name.
+	// The path goes through lists on the way, like "lines.product" for the product of every line.
+	ListByReference(ctx context.Context, typ, path string, values []string) (items []core.Item, err error)
+}
+
 var ErrItemNotFound = errors.New("item not found")
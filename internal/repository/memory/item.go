@@ -2,6 +2,8 @@ package memory
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/nasermirzaei89/core/internal/core"
@@ -9,54 +11,156 @@ import (
 	"github.com/pkg/errors"
 )
 
-var _ repository.ItemRepository = &ItemRepository{}
+var (
+	_ repository.ItemRepository     = &ItemRepository{}
+	_ repository.ItemWatcher        = &ItemRepository{}
+	_ repository.ItemOwnerIndex     = &ItemRepository{}
+	_ repository.ItemLabelIndex     = &ItemRepository{}
+	_ repository.ItemUUIDIndex      = &ItemRepository{}
+	_ repository.ItemReferenceIndex = &ItemRepository{}
+)
 
+// ItemRepository keeps items in memory, indexed by uuid, by type and name, by uuids of their owners, by labels, and by
+// values of the fields they're listed by reference with. Items are deep copied on the way in and out, so callers can't mutate
+// stored data.
 type ItemRepository struct {
-	items []core.Item
-	mu    sync.Mutex
+	items  map[string]core.Item
+	byType map[string]map[string]string
+	// byOwner has uuids of the dependents of each owner uuid
+	byOwner map[string]map[string]struct{}
+	// byLabel has uuids of the items of each type by the values of each label key
+	byLabel map[string]map[string]map[string]map[string]struct{}
+	// byReference has uuids of the items of each type by the values of each field path, for the paths they were listed
+	// by reference with
+	byReference map[string]map[string]map[string]map[string]struct{}
+	mu          sync.RWMutex
+	events      repository.Broadcaster
+
+	log        *writeAheadLog
+	seq        uint64
+	snapshotMu sync.Mutex
+	done       chan struct{}
+	wg         sync.WaitGroup
 }
 
 func (repo *ItemRepository) Insert(_ context.Context, item core.Item) error {
 	repo.mu.Lock()
 	defer repo.mu.Unlock()
 
-	for i := range repo.items {
-		if repo.items[i].UUID == item.UUID {
-			return errors.Errorf("item with uuid '%s' already exists", item.UUID)
-		}
+	if _, ok := repo.items[item.UUID]; ok {
+		return errors.Errorf("item with uuid '%s' already exists", item.UUID)
+	}
 
-		if repo.items[i].Type == item.Type && repo.items[i].Name == item.Name {
-			return errors.Errorf("item with type '%s' and name '%s' already exists", item.Type, item.Name)
-		}
+	if _, ok := repo.byType[item.Type][item.Name]; ok {
+		return errors.Errorf("item with type '%s' and name '%s' already exists", item.Type, item.Name)
 	}
 
-	repo.items = append(repo.items, item)
+	err := repo.appendLog(opInsert, item.UUID, &item)
+	if err != nil {
+		return err
+	}
+
+	repo.put(item.DeepCopy())
+	repo.events.Publish(repository.Event{Type: repository.EventAdded, Item: item})
 
 	return nil
 }
 
+// ListByType returns items of the type sorted by name.
 func (repo *ItemRepository) ListByType(_ context.Context, typ string) ([]core.Item, error) {
-	res := make([]core.Item, 0)
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
 
-	for i := range repo.items {
-		if repo.items[i].Type == typ {
-			res = append(res, repo.items[i])
-		}
+	names := repo.byType[typ]
+
+	res := make([]core.Item, 0, len(names))
+
+	for _, itemUUID := range names {
+		res = append(res, repo.items[itemUUID].DeepCopy())
 	}
 
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
 	return res, nil
 }
 
-func (repo *ItemRepository) GetByTypeAndName(_ context.Context, typ, name string) (*core.Item, error) {
-	for i := range repo.items {
-		if repo.items[i].Type == typ && repo.items[i].Name == name {
-			res := repo.items[i]
+func (repo *ItemRepository) ListTypes(_ context.Context) ([]string, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	res := make([]string, 0, len(repo.byType))
+
+	for typ := range repo.byType {
+		res = append(res, typ)
+	}
+
+	sort.Strings(res)
+
+	return res, nil
+}
+
+func (repo *ItemRepository) EachByType(_ context.Context, typ string, fn func(item core.Item) error) error {
+	repo.mu.RLock()
+
+	names := make([]string, 0, len(repo.byType[typ]))
 
-			return &res, nil
+	for name := range repo.byType[typ] {
+		names = append(names, name)
+	}
+
+	repo.mu.RUnlock()
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		repo.mu.RLock()
+
+		item, ok := repo.items[repo.byType[typ][name]]
+		if ok {
+			item = item.DeepCopy()
+		}
+
+		repo.mu.RUnlock()
+
+		if !ok {
+			continue
+		}
+
+		err := fn(item)
+		if err != nil {
+			return err
 		}
 	}
 
-	return nil, repository.ErrItemNotFound
+	return nil
+}
+
+func (repo *ItemRepository) GetByTypeAndName(_ context.Context, typ, name string) (*core.Item, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	itemUUID, ok := repo.byType[typ][name]
+	if !ok {
+		return nil, repository.ErrItemNotFound
+	}
+
+	res := repo.items[itemUUID].DeepCopy()
+
+	return &res, nil
+}
+
+func (repo *ItemRepository) GetByUUID(_ context.Context, itemUUID string) (*core.Item, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	item, ok := repo.items[itemUUID]
+	if !ok {
+		return nil, repository.ErrItemNotFound
+	}
+
+	res := item.DeepCopy()
+
+	return &res, nil
 }
 
 func (repo *ItemRepository) Replace(_ context.Context, itemUUID string, item core.Item) error {
@@ -67,41 +171,298 @@ func (repo *ItemRepository) Replace(_ context.Context, itemUUID string, item cor
 		return errors.New("field uuid is immutable")
 	}
 
-	for i := range repo.items {
-		if repo.items[i].Type == item.Type && repo.items[i].Name == item.Name && repo.items[i].UUID != itemUUID {
-			return errors.Errorf("item with type '%s' and name '%s' already exists", item.Type, item.Name)
-		}
+	if existingUUID, ok := repo.byType[item.Type][item.Name]; ok && existingUUID != itemUUID {
+		return errors.Errorf("item with type '%s' and name '%s' already exists", item.Type, item.Name)
 	}
 
-	for i := range repo.items {
-		if repo.items[i].UUID == itemUUID {
-			repo.items[i] = item
+	old, ok := repo.items[itemUUID]
+	if !ok {
+		return errors.Errorf("item with uuid '%s' doesn't exist", itemUUID)
+	}
 
-			return nil
-		}
+	err := repo.appendLog(opReplace, itemUUID, &item)
+	if err != nil {
+		return err
 	}
 
-	return errors.Errorf("item with uuid '%s' doesn't exist", itemUUID)
+	repo.remove(old)
+	repo.put(item.DeepCopy())
+	repo.events.Publish(repository.Event{Type: repository.EventModified, Item: item})
+
+	return nil
 }
 
 func (repo *ItemRepository) Delete(_ context.Context, itemUUID string) error {
 	repo.mu.Lock()
 	defer repo.mu.Unlock()
 
-	for i := range repo.items {
-		if repo.items[i].UUID == itemUUID {
-			repo.items = append(repo.items[:i], repo.items[i+1:]...)
+	item, ok := repo.items[itemUUID]
+	if !ok {
+		return errors.Errorf("item with uuid '%s' doesn't exist", itemUUID)
+	}
 
-			return nil
+	err := repo.appendLog(opDelete, itemUUID, nil)
+	if err != nil {
+		return err
+	}
+
+	repo.remove(item)
+	repo.events.Publish(repository.Event{Type: repository.EventDeleted, Item: item})
+
+	return nil
+}
+
+func (repo *ItemRepository) ListByOwner(_ context.Context, ownerUUID string) ([]core.Item, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	dependents := repo.byOwner[ownerUUID]
+
+	res := make([]core.Item, 0, len(dependents))
+
+	for itemUUID := range dependents {
+		res = append(res, repo.items[itemUUID].DeepCopy())
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Type != res[j].Type {
+			return res[i].Type < res[j].Type
+		}
+
+		return res[i].Name < res[j].Name
+	})
+
+	return res, nil
+}
+
+func (repo *ItemRepository) ListByLabel(_ context.Context, typ, key string, values []string) ([]core.Item, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	index := repo.byLabel[typ][key]
+	found := make(map[string]struct{})
+
+	if len(values) == 0 {
+		for _, uuids := range index {
+			for itemUUID := range uuids {
+				found[itemUUID] = struct{}{}
+			}
 		}
 	}
 
-	return errors.Errorf("item with uuid '%s' doesn't exist", itemUUID)
+	for _, value := range values {
+		for itemUUID := range index[value] {
+			found[itemUUID] = struct{}{}
+		}
+	}
+
+	res := make([]core.Item, 0, len(found))
+
+	for itemUUID := range found {
+		res = append(res, repo.items[itemUUID].DeepCopy())
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res, nil
+}
+
+// ListByReference indexes the field of items of the type on the first call for it, and keeps the index up to date on
+// writes after that.
+func (repo *ItemRepository) ListByReference(_ context.Context, typ, path string, values []string) ([]core.Item, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	index, ok := repo.byReference[typ][path]
+	if !ok {
+		index = make(map[string]map[string]struct{})
+
+		for _, itemUUID := range repo.byType[typ] {
+			indexReference(index, repo.items[itemUUID], path)
+		}
+
+		paths, ok := repo.byReference[typ]
+		if !ok {
+			paths = make(map[string]map[string]map[string]struct{})
+			repo.byReference[typ] = paths
+		}
+
+		paths[path] = index
+	}
+
+	found := make(map[string]struct{})
+
+	for _, value := range values {
+		for itemUUID := range index[value] {
+			found[itemUUID] = struct{}{}
+		}
+	}
+
+	res := make([]core.Item, 0, len(found))
+
+	for itemUUID := range found {
+		res = append(res, repo.items[itemUUID].DeepCopy())
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res, nil
+}
+
+func (repo *ItemRepository) Watch(ctx context.Context, typ string) (<-chan repository.Event, error) {
+	return repo.events.Watch(ctx, typ)
+}
+
+func (repo *ItemRepository) put(item core.Item) {
+	repo.items[item.UUID] = item
+
+	names, ok := repo.byType[item.Type]
+	if !ok {
+		names = make(map[string]string)
+		repo.byType[item.Type] = names
+	}
+
+	names[item.Name] = item.UUID
+
+	for _, owner := range item.OwnerReferences {
+		dependents, ok := repo.byOwner[owner.UUID]
+		if !ok {
+			dependents = make(map[string]struct{})
+			repo.byOwner[owner.UUID] = dependents
+		}
+
+		dependents[item.UUID] = struct{}{}
+	}
+
+	for k, v := range item.Labels {
+		keys, ok := repo.byLabel[item.Type]
+		if !ok {
+			keys = make(map[string]map[string]map[string]struct{})
+			repo.byLabel[item.Type] = keys
+		}
+
+		values, ok := keys[k]
+		if !ok {
+			values = make(map[string]map[string]struct{})
+			keys[k] = values
+		}
+
+		uuids, ok := values[v]
+		if !ok {
+			uuids = make(map[string]struct{})
+			values[v] = uuids
+		}
+
+		uuids[item.UUID] = struct{}{}
+	}
+
+	for path, index := range repo.byReference[item.Type] {
+		indexReference(index, item, path)
+	}
+}
+
+func (repo *ItemRepository) remove(item core.Item) {
+	delete(repo.items, item.UUID)
+
+	names := repo.byType[item.Type]
+
+	delete(names, item.Name)
+
+	if len(names) == 0 {
+		delete(repo.byType, item.Type)
+	}
+
+	for _, owner := range item.OwnerReferences {
+		dependents := repo.byOwner[owner.UUID]
+
+		delete(dependents, item.UUID)
+
+		if len(dependents) == 0 {
+			delete(repo.byOwner, owner.UUID)
+		}
+	}
+
+	for k, v := range item.Labels {
+		keys := repo.byLabel[item.Type]
+		values := keys[k]
+
+		delete(values[v], item.UUID)
+
+		if len(values[v]) == 0 {
+			delete(values, v)
+		}
+
+		if len(values) == 0 {
+			delete(keys, k)
+		}
+
+		if len(keys) == 0 {
+			delete(repo.byLabel, item.Type)
+		}
+	}
+
+	for path, index := range repo.byReference[item.Type] {
+		for _, value := range fieldValues(item.Data, strings.Split(path, ".")) {
+			delete(index[value], item.UUID)
+
+			if len(index[value]) == 0 {
+				delete(index, value)
+			}
+		}
+	}
+}
+
+func indexReference(index map[string]map[string]struct{}, item core.Item, path string) {
+	for _, value := range fieldValues(item.Data, strings.Split(path, ".")) {
+		uuids, ok := index[value]
+		if !ok {
+			uuids = make(map[string]struct{})
+			index[value] = uuids
+		}
+
+		uuids[item.UUID] = struct{}{}
+	}
+}
+
+// fieldValues returns the string values of the field at the path, going through lists on the way.
+func fieldValues(v interface{}, path []string) []string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			if s, ok := v[path[0]].(string); ok {
+				return []string{s}
+			}
+
+			return nil
+		}
+
+		return fieldValues(v[path[0]], path[1:])
+	case []interface{}:
+		res := make([]string, 0)
+
+		for i := range v {
+			res = append(res, fieldValues(v[i], path)...)
+		}
+
+		return res
+	default:
+		return nil
+	}
 }
 
 func NewItemRepository() *ItemRepository {
 	return &ItemRepository{
-		items: make([]core.Item, 0),
-		mu:    sync.Mutex{},
+		items:       make(map[string]core.Item),
+		byType:      make(map[string]map[string]string),
+		byOwner:     make(map[string]map[string]struct{}),
+		byLabel:     make(map[string]map[string]map[string]map[string]struct{}),
+		byReference: make(map[string]map[string]map[string]map[string]struct{}),
+		mu:          sync.RWMutex{},
+		events:      repository.Broadcaster{},
+		log:         nil,
+		seq:         0,
+		snapshotMu:  sync.Mutex{},
+		done:        nil,
+		wg:          sync.WaitGroup{},
 	}
 }
@@ -2,6 +2,8 @@ package memory_test
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -176,6 +178,36 @@ func TestItemRepository_FindByTypeAndName(t *testing.T) {
 	})
 }
 
+func TestItemRepository_GetByUUID(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	itemRepo := memory.NewItemRepository()
+
+	item := core.Item{
+		UUID:      uuid.NewString(),
+		Type:      "bar",
+		Name:      "foo",
+		Data:      nil,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	err := itemRepo.Insert(ctx, item)
+	require.NoError(t, err)
+
+	res, err := itemRepo.GetByUUID(ctx, item.UUID)
+	require.NoError(t, err)
+	assert.EqualValues(t, item, *res)
+
+	err = itemRepo.Delete(ctx, item.UUID)
+	require.NoError(t, err)
+
+	_, err = itemRepo.GetByUUID(ctx, item.UUID)
+	assert.True(t, errors.Is(err, repository.ErrItemNotFound))
+}
+
 func TestItemRepository_Replace(t *testing.T) {
 	t.Parallel()
 
@@ -331,3 +363,359 @@ func TestItemRepository_Delete(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestItemRepository_DeepCopy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	itemRepo := memory.NewItemRepository()
+
+	item := core.Item{
+		UUID: uuid.NewString(),
+		Type: "bar",
+		Name: "foo",
+		Data: map[string]interface{}{
+			"nested": map[string]interface{}{"key": "value"},
+			"list":   []interface{}{"a", "b"},
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	err := itemRepo.Insert(ctx, item)
+	require.NoError(t, err)
+
+	item.Data["nested"].(map[string]interface{})["key"] = "changed"
+	item.Data["list"].([]interface{})[0] = "changed"
+
+	res, err := itemRepo.GetByTypeAndName(ctx, "bar", "foo")
+	require.NoError(t, err)
+
+	assert.Equal(t, "value", res.Data["nested"].(map[string]interface{})["key"])
+	assert.Equal(t, "a", res.Data["list"].([]interface{})[0])
+
+	res.Data["nested"].(map[string]interface{})["key"] = "changed"
+
+	res2, err := itemRepo.GetByTypeAndName(ctx, "bar", "foo")
+	require.NoError(t, err)
+
+	assert.Equal(t, "value", res2.Data["nested"].(map[string]interface{})["key"])
+}
+
+func TestItemRepository_ListByOwner(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	itemRepo := memory.NewItemRepository()
+
+	ownerUUID := uuid.NewString()
+	owner := core.OwnerReference{Type: "order", Name: "order-1", UUID: ownerUUID}
+
+	items := []core.Item{
+		{UUID: uuid.NewString(), Type: "shipment", Name: "b", OwnerReferences: []core.OwnerReference{owner}},
+		{UUID: uuid.NewString(), Type: "invoice", Name: "a", OwnerReferences: []core.OwnerReference{owner}},
+		{UUID: uuid.NewString(), Type: "shipment", Name: "a", OwnerReferences: []core.OwnerReference{owner}},
+		{UUID: uuid.NewString(), Type: "shipment", Name: "c", OwnerReferences: nil},
+	}
+
+	for i := range items {
+		err := itemRepo.Insert(ctx, items[i])
+		require.NoError(t, err)
+	}
+
+	res, err := itemRepo.ListByOwner(ctx, ownerUUID)
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+	assert.Equal(t, items[1].UUID, res[0].UUID)
+	assert.Equal(t, items[2].UUID, res[1].UUID)
+	assert.Equal(t, items[0].UUID, res[2].UUID)
+
+	items[0].OwnerReferences = nil
+
+	err = itemRepo.Replace(ctx, items[0].UUID, items[0])
+	require.NoError(t, err)
+
+	err = itemRepo.Delete(ctx, items[1].UUID)
+	require.NoError(t, err)
+
+	res, err = itemRepo.ListByOwner(ctx, ownerUUID)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, items[2].UUID, res[0].UUID)
+}
+
+func TestItemRepository_Watch(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	itemRepo := memory.NewItemRepository()
+
+	events, err := itemRepo.Watch(ctx, "bar")
+	require.NoError(t, err)
+
+	item := core.Item{
+		UUID:      uuid.NewString(),
+		Type:      "bar",
+		Name:      "foo",
+		Data:      map[string]interface{}{"foo": "bar"},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	require.NoError(t, itemRepo.Insert(ctx, item))
+
+	other := item
+	other.UUID = uuid.NewString()
+	other.Type = "baz"
+
+	require.NoError(t, itemRepo.Insert(ctx, other))
+
+	item.Data = map[string]interface{}{"foo": "baz"}
+
+	require.NoError(t, itemRepo.Replace(ctx, item.UUID, item))
+	require.NoError(t, itemRepo.Delete(ctx, item.UUID))
+
+	for _, want := range []repository.EventType{repository.EventAdded, repository.EventModified, repository.EventDeleted} {
+		event := <-events
+		assert.Equal(t, want, event.Type)
+		assert.Equal(t, "bar", event.Item.Type)
+	}
+
+	cancel()
+
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestItemRepository_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	itemRepo := memory.NewItemRepository()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				item := core.Item{
+					UUID:      uuid.NewString(),
+					Type:      "bar",
+					Name:      fmt.Sprintf("foo-%d-%d", i, j),
+					Data:      map[string]interface{}{"index": j},
+					CreatedAt: time.Now(),
+					UpdatedAt: time.Now(),
+				}
+
+				assert.NoError(t, itemRepo.Insert(ctx, item))
+
+				item.Data["index"] = j + 1
+				assert.NoError(t, itemRepo.Replace(ctx, item.UUID, item))
+			}
+		}(i)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				_, _ = itemRepo.GetByTypeAndName(ctx, "bar", fmt.Sprintf("foo-%d-%d", i, j))
+				_, err := itemRepo.ListByType(ctx, "bar")
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	res, err := itemRepo.ListByType(ctx, "bar")
+	require.NoError(t, err)
+	assert.Len(t, res, 800)
+}
+
+func newBenchmarkRepository(b *testing.B, n int) (*memory.ItemRepository, []core.Item) {
+	b.Helper()
+
+	ctx := context.Background()
+
+	itemRepo := memory.NewItemRepository()
+
+	items := make([]core.Item, n)
+
+	for i := range items {
+		items[i] = core.Item{
+			UUID:      uuid.NewString(),
+			Type:      fmt.Sprintf("type-%d", i%100),
+			Name:      fmt.Sprintf("name-%d", i),
+			Data:      map[string]interface{}{"index": i},
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+
+		err := itemRepo.Insert(ctx, items[i])
+		require.NoError(b, err)
+	}
+
+	return itemRepo, items
+}
+
+func BenchmarkItemRepository_GetByTypeAndName(b *testing.B) {
+	for _, n := range []int{1_000, 1_000_000} {
+		b.Run(fmt.Sprintf("%d items", n), func(b *testing.B) {
+			ctx := context.Background()
+
+			itemRepo, items := newBenchmarkRepository(b, n)
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				item := items[i%len(items)]
+
+				_, err := itemRepo.GetByTypeAndName(ctx, item.Type, item.Name)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkItemRepository_Replace(b *testing.B) {
+	for _, n := range []int{1_000, 1_000_000} {
+		b.Run(fmt.Sprintf("%d items", n), func(b *testing.B) {
+			ctx := context.Background()
+
+			itemRepo, items := newBenchmarkRepository(b, n)
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				item := items[i%len(items)]
+
+				err := itemRepo.Replace(ctx, item.UUID, item)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkItemRepository_ConcurrentReaders(b *testing.B) {
+	ctx := context.Background()
+
+	itemRepo, items := newBenchmarkRepository(b, 1_000_000)
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+
+		for pb.Next() {
+			item := items[i%len(items)]
+
+			_, err := itemRepo.GetByTypeAndName(ctx, item.Type, item.Name)
+			if err != nil {
+				b.Error(err)
+
+				return
+			}
+
+			i++
+		}
+	})
+}
+
+func TestItemRepository_ListByReference(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	itemRepo := memory.NewItemRepository()
+
+	items := []core.Item{
+		{UUID: uuid.NewString(), Type: "order", Name: "b", Data: map[string]interface{}{"lines": []interface{}{map[string]interface{}{"product": "anvil"}}}},
+		{UUID: uuid.NewString(), Type: "order", Name: "a", Data: map[string]interface{}{"lines": []interface{}{map[string]interface{}{"product": "rocket"}}}},
+		{UUID: uuid.NewString(), Type: "order", Name: "c", Data: map[string]interface{}{"lines": []interface{}{}}},
+	}
+
+	for i := range items {
+		err := itemRepo.Insert(ctx, items[i])
+		require.NoError(t, err)
+	}
+
+	res, err := itemRepo.ListByReference(ctx, "order", "lines.product", []string{"anvil", "rocket"})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, items[1].UUID, res[0].UUID)
+	assert.Equal(t, items[0].UUID, res[1].UUID)
+
+	items[0].Data = map[string]interface{}{"lines": []interface{}{}}
+
+	err = itemRepo.Replace(ctx, items[0].UUID, items[0])
+	require.NoError(t, err)
+
+	items[2].Data = map[string]interface{}{"lines": []interface{}{map[string]interface{}{"product": "anvil"}}}
+
+	err = itemRepo.Replace(ctx, items[2].UUID, items[2])
+	require.NoError(t, err)
+
+	err = itemRepo.Delete(ctx, items[1].UUID)
+	require.NoError(t, err)
+
+	res, err = itemRepo.ListByReference(ctx, "order", "lines.product", []string{"anvil", "rocket"})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, items[2].UUID, res[0].UUID)
+}
+
+func TestItemRepository_ListByLabel(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	itemRepo := memory.NewItemRepository()
+
+	items := []core.Item{
+		{UUID: uuid.NewString(), Type: "drink", Name: "b", Labels: map[string]string{"tier": "gold"}},
+		{UUID: uuid.NewString(), Type: "drink", Name: "a", Labels: map[string]string{"tier": "silver"}},
+		{UUID: uuid.NewString(), Type: "drink", Name: "c", Labels: nil},
+		{UUID: uuid.NewString(), Type: "food", Name: "a", Labels: map[string]string{"tier": "gold"}},
+	}
+
+	for i := range items {
+		err := itemRepo.Insert(ctx, items[i])
+		require.NoError(t, err)
+	}
+
+	res, err := itemRepo.ListByLabel(ctx, "drink", "tier", []string{"gold"})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, items[0].UUID, res[0].UUID)
+
+	res, err = itemRepo.ListByLabel(ctx, "drink", "tier", nil)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, items[1].UUID, res[0].UUID)
+	assert.Equal(t, items[0].UUID, res[1].UUID)
+
+	items[0].Labels = nil
+
+	err = itemRepo.Replace(ctx, items[0].UUID, items[0])
+	require.NoError(t, err)
+
+	err = itemRepo.Delete(ctx, items[1].UUID)
+	require.NoError(t, err)
+
+	res, err = itemRepo.ListByLabel(ctx, "drink", "tier", nil)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+}
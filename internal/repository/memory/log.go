@@ -0,0 +1,546 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/pkg/errors"
+)
+
+// SyncPolicy controls when the write-ahead log is fsynced to disk.
+type SyncPolicy string
+
+const (
+	// SyncAlways fsyncs the log after every write.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval fsyncs the log periodically, every LogOptions.SyncInterval.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves flushing to the operating system.
+	SyncNever SyncPolicy = "never"
+)
+
+const (
+	snapshotFileName = "snapshot.json"
+	logFileName      = "wal.log"
+	rotatedLogPrefix = "wal-"
+	rotatedLogSuffix = ".log"
+
+	defaultSyncInterval = time.Second
+)
+
+const (
+	opInsert  = "insert"
+	opReplace = "replace"
+	opDelete  = "delete"
+)
+
+type LogOptions struct {
+	Dir              string
+	SyncPolicy       SyncPolicy
+	SyncInterval     time.Duration
+	SnapshotInterval time.Duration
+}
+
+type logRecord struct {
+	Seq  uint64     `json:"seq"`
+	Op   string     `json:"op"`
+	UUID string     `json:"uuid"`
+	Item *core.Item `json:"item,omitempty"`
+}
+
+type snapshot struct {
+	Seq   uint64      `json:"seq"`
+	Items []core.Item `json:"items"`
+}
+
+type writeAheadLog struct {
+	dir    string
+	policy SyncPolicy
+	file   *os.File
+	dirty  bool
+	mu     sync.Mutex
+}
+
+func (wal *writeAheadLog) append(rec logRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return errors.Wrap(err, "error on marshal log record")
+	}
+
+	line = append(line, '\n')
+
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+
+	_, err = wal.file.Write(line)
+	if err != nil {
+		return errors.Wrap(err, "error on write log record")
+	}
+
+	switch wal.policy {
+	case SyncAlways:
+		err = wal.file.Sync()
+		if err != nil {
+			return errors.Wrap(err, "error on sync log file")
+		}
+	case SyncInterval:
+		wal.dirty = true
+	case SyncNever:
+	}
+
+	return nil
+}
+
+func (wal *writeAheadLog) sync() error {
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+
+	if !wal.dirty {
+		return nil
+	}
+
+	err := wal.file.Sync()
+	if err != nil {
+		return errors.Wrap(err, "error on sync log file")
+	}
+
+	wal.dirty = false
+
+	return nil
+}
+
+// rotate moves the current log aside, so it can be removed once a snapshot covering seq is written.
+func (wal *writeAheadLog) rotate(seq uint64) error {
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+
+	err := wal.file.Sync()
+	if err != nil {
+		return errors.Wrap(err, "error on sync log file")
+	}
+
+	err = wal.file.Close()
+	if err != nil {
+		return errors.Wrap(err, "error on close log file")
+	}
+
+	rotated := filepath.Join(wal.dir, fmt.Sprintf("%s%020d%s", rotatedLogPrefix, seq, rotatedLogSuffix))
+
+	err = os.Rename(filepath.Join(wal.dir, logFileName), rotated)
+	if err != nil {
+		return errors.Wrap(err, "error on rename log file")
+	}
+
+	wal.file, err = openLogFile(wal.dir)
+	if err != nil {
+		return err
+	}
+
+	wal.dirty = false
+
+	return syncDir(wal.dir)
+}
+
+func (wal *writeAheadLog) close() error {
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+
+	err := wal.file.Sync()
+	if err != nil {
+		return errors.Wrap(err, "error on sync log file")
+	}
+
+	err = wal.file.Close()
+	if err != nil {
+		return errors.Wrap(err, "error on close log file")
+	}
+
+	return nil
+}
+
+func openLogFile(dir string) (*os.File, error) {
+	f, err := os.OpenFile(filepath.Join(dir, logFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on open log file")
+	}
+
+	return f, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrap(err, "error on open directory")
+	}
+
+	defer func() { _ = d.Close() }()
+
+	err = d.Sync()
+	if err != nil {
+		return errors.Wrap(err, "error on sync directory")
+	}
+
+	return nil
+}
+
+// OpenItemRepository returns a memory repository that records every write to an append-only log in opts.Dir,
+// periodically compacts it into a snapshot, and restores its state from both on open.
+func OpenItemRepository(opts LogOptions) (*ItemRepository, error) {
+	if opts.SyncPolicy == "" {
+		opts.SyncPolicy = SyncAlways
+	}
+
+	switch opts.SyncPolicy {
+	case SyncAlways, SyncNever:
+	case SyncInterval:
+		if opts.SyncInterval <= 0 {
+			opts.SyncInterval = defaultSyncInterval
+		}
+	default:
+		return nil, errors.Errorf("invalid sync policy '%s'", opts.SyncPolicy)
+	}
+
+	err := os.MkdirAll(opts.Dir, 0o700)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on create data directory")
+	}
+
+	repo := NewItemRepository()
+
+	err = repo.restore(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := openLogFile(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	repo.log = &writeAheadLog{
+		dir:    opts.Dir,
+		policy: opts.SyncPolicy,
+		file:   f,
+		dirty:  false,
+		mu:     sync.Mutex{},
+	}
+
+	repo.done = make(chan struct{})
+
+	if opts.SyncPolicy == SyncInterval {
+		repo.runEvery(opts.SyncInterval, repo.log.sync)
+	}
+
+	if opts.SnapshotInterval > 0 {
+		repo.runEvery(opts.SnapshotInterval, repo.Snapshot)
+	}
+
+	return repo, nil
+}
+
+func (repo *ItemRepository) runEvery(d time.Duration, fn func() error) {
+	repo.wg.Add(1)
+
+	go func() {
+		defer repo.wg.Done()
+
+		ticker := time.NewTicker(d)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-repo.done:
+				return
+			case <-ticker.C:
+				_ = fn()
+			}
+		}
+	}()
+}
+
+// appendLog records a write in the log, if the repository has one. It must be called with repo.mu held.
+func (repo *ItemRepository) appendLog(op string, itemUUID string, item *core.Item) error {
+	if repo.log == nil {
+		return nil
+	}
+
+	rec := logRecord{
+		Seq:  repo.seq + 1,
+		Op:   op,
+		UUID: itemUUID,
+		Item: item,
+	}
+
+	err := repo.log.append(rec)
+	if err != nil {
+		return err
+	}
+
+	repo.seq = rec.Seq
+
+	return nil
+}
+
+// Snapshot writes the current state to the snapshot file and drops the log records it covers.
+func (repo *ItemRepository) Snapshot() error {
+	if repo.log == nil {
+		return errors.New("repository has no log")
+	}
+
+	repo.snapshotMu.Lock()
+	defer repo.snapshotMu.Unlock()
+
+	repo.mu.Lock()
+
+	items := make([]core.Item, 0, len(repo.items))
+
+	for _, item := range repo.items {
+		items = append(items, item)
+	}
+
+	seq := repo.seq
+
+	err := repo.log.rotate(seq)
+
+	repo.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].UUID < items[j].UUID })
+
+	err = writeSnapshot(repo.log.dir, snapshot{Seq: seq, Items: items})
+	if err != nil {
+		return err
+	}
+
+	rotated, err := rotatedLogs(repo.log.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range rotated {
+		err = os.Remove(filepath.Join(repo.log.dir, name))
+		if err != nil {
+			return errors.Wrap(err, "error on remove rotated log file")
+		}
+	}
+
+	return nil
+}
+
+// Close stops background syncing and snapshotting and closes the log. It's a no-op for repositories without a log.
+func (repo *ItemRepository) Close() error {
+	if repo.log == nil {
+		return nil
+	}
+
+	close(repo.done)
+	repo.wg.Wait()
+
+	return repo.log.close()
+}
+
+func writeSnapshot(dir string, snap snapshot) error {
+	tmp, err := os.CreateTemp(dir, snapshotFileName+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "error on create temp snapshot file")
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	w := bufio.NewWriter(tmp)
+
+	err = json.NewEncoder(w).Encode(snap)
+	if err != nil {
+		_ = tmp.Close()
+
+		return errors.Wrap(err, "error on encode snapshot")
+	}
+
+	err = w.Flush()
+	if err != nil {
+		_ = tmp.Close()
+
+		return errors.Wrap(err, "error on write snapshot")
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		_ = tmp.Close()
+
+		return errors.Wrap(err, "error on sync snapshot file")
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return errors.Wrap(err, "error on close snapshot file")
+	}
+
+	err = os.Rename(tmp.Name(), filepath.Join(dir, snapshotFileName))
+	if err != nil {
+		return errors.Wrap(err, "error on rename snapshot file")
+	}
+
+	return syncDir(dir)
+}
+
+func rotatedLogs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on read data directory")
+	}
+
+	res := make([]string, 0)
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), rotatedLogPrefix) && strings.HasSuffix(entry.Name(), rotatedLogSuffix) {
+			res = append(res, entry.Name())
+		}
+	}
+
+	sort.Strings(res)
+
+	return res, nil
+}
+
+func (repo *ItemRepository) restore(dir string) error {
+	f, err := os.Open(filepath.Join(dir, snapshotFileName))
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return errors.Wrap(err, "error on open snapshot file")
+	default:
+		var snap snapshot
+
+		err = json.NewDecoder(bufio.NewReader(f)).Decode(&snap)
+
+		_ = f.Close()
+
+		if err != nil {
+			return errors.Wrap(err, "error on decode snapshot")
+		}
+
+		for i := range snap.Items {
+			repo.put(snap.Items[i])
+		}
+
+		repo.seq = snap.Seq
+	}
+
+	rotated, err := rotatedLogs(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range rotated {
+		err = repo.replay(filepath.Join(dir, name), false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return repo.replay(filepath.Join(dir, logFileName), true)
+}
+
+// replay applies the log records in the file. A torn record at the end of the active log, left by a crash
+// in the middle of a write, is truncated away.
+func (repo *ItemRepository) replay(path string, active bool) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return errors.Wrap(err, "error on open log file")
+	}
+
+	defer func() { _ = f.Close() }()
+
+	r := bufio.NewReader(f)
+
+	var offset int64
+
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) != 0 && active {
+				return truncate(f, offset)
+			}
+
+			return nil
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "error on read log file")
+		}
+
+		var rec logRecord
+
+		err = json.Unmarshal(line, &rec)
+		if err != nil {
+			if _, peekErr := r.Peek(1); errors.Is(peekErr, io.EOF) && active {
+				return truncate(f, offset)
+			}
+
+			return errors.Wrapf(err, "error on decode log record at offset %d of '%s'", offset, path)
+		}
+
+		offset += int64(len(line))
+
+		if rec.Seq <= repo.seq {
+			continue
+		}
+
+		err = repo.apply(rec)
+		if err != nil {
+			return err
+		}
+
+		repo.seq = rec.Seq
+	}
+}
+
+func truncate(f *os.File, offset int64) error {
+	err := f.Truncate(offset)
+	if err != nil {
+		return errors.Wrap(err, "error on truncate torn log record")
+	}
+
+	return nil
+}
+
+func (repo *ItemRepository) apply(rec logRecord) error {
+	switch rec.Op {
+	case opInsert, opReplace:
+		if rec.Item == nil {
+			return errors.Errorf("log record %d has no item", rec.Seq)
+		}
+
+		if old, ok := repo.items[rec.UUID]; ok {
+			repo.remove(old)
+		}
+
+		repo.put(*rec.Item)
+	case opDelete:
+		if old, ok := repo.items[rec.UUID]; ok {
+			repo.remove(old)
+		}
+	default:
+		return errors.Errorf("log record %d has unknown op '%s'", rec.Seq, rec.Op)
+	}
+
+	return nil
+}
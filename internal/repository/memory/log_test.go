@@ -0,0 +1,139 @@
+package memory_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/nasermirzaei89/core/internal/repository"
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLogTestItem(name string) core.Item {
+	return core.Item{
+		UUID:      uuid.NewString(),
+		Type:      "bar",
+		Name:      name,
+		Data:      map[string]interface{}{"foo": "bar"},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func TestOpenItemRepository(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("Replay Log", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		itemRepo, err := memory.OpenItemRepository(memory.LogOptions{Dir: dir})
+		require.NoError(t, err)
+
+		item1 := newLogTestItem("foo")
+		item2 := newLogTestItem("fee")
+
+		require.NoError(t, itemRepo.Insert(ctx, item1))
+		require.NoError(t, itemRepo.Insert(ctx, item2))
+
+		item1.Data["foo"] = "baz"
+		require.NoError(t, itemRepo.Replace(ctx, item1.UUID, item1))
+		require.NoError(t, itemRepo.Delete(ctx, item2.UUID))
+		require.NoError(t, itemRepo.Close())
+
+		itemRepo, err = memory.OpenItemRepository(memory.LogOptions{Dir: dir})
+		require.NoError(t, err)
+
+		defer func() { _ = itemRepo.Close() }()
+
+		res, err := itemRepo.GetByTypeAndName(ctx, "bar", "foo")
+		require.NoError(t, err)
+
+		assert.True(t, item1.UpdatedAt.Equal(res.UpdatedAt))
+		assert.Equal(t, "baz", res.Data["foo"])
+
+		_, err = itemRepo.GetByTypeAndName(ctx, "bar", "fee")
+		assert.True(t, errors.Is(err, repository.ErrItemNotFound))
+	})
+
+	t.Run("Replay Snapshot And Log", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		itemRepo, err := memory.OpenItemRepository(memory.LogOptions{Dir: dir, SyncPolicy: memory.SyncInterval})
+		require.NoError(t, err)
+
+		item1 := newLogTestItem("foo")
+		item2 := newLogTestItem("fee")
+
+		require.NoError(t, itemRepo.Insert(ctx, item1))
+		require.NoError(t, itemRepo.Snapshot())
+		require.NoError(t, itemRepo.Insert(ctx, item2))
+		require.NoError(t, itemRepo.Close())
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+
+		itemRepo, err = memory.OpenItemRepository(memory.LogOptions{Dir: dir})
+		require.NoError(t, err)
+
+		defer func() { _ = itemRepo.Close() }()
+
+		res, err := itemRepo.ListByType(ctx, "bar")
+		require.NoError(t, err)
+		assert.Len(t, res, 2)
+	})
+
+	t.Run("Torn Record", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		itemRepo, err := memory.OpenItemRepository(memory.LogOptions{Dir: dir, SyncPolicy: memory.SyncNever})
+		require.NoError(t, err)
+
+		require.NoError(t, itemRepo.Insert(ctx, newLogTestItem("foo")))
+		require.NoError(t, itemRepo.Close())
+
+		f, err := os.OpenFile(filepath.Join(dir, "wal.log"), os.O_APPEND|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+
+		_, err = f.WriteString(`{"seq":2,"op":"ins`)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		itemRepo, err = memory.OpenItemRepository(memory.LogOptions{Dir: dir})
+		require.NoError(t, err)
+
+		require.NoError(t, itemRepo.Insert(ctx, newLogTestItem("fee")))
+		require.NoError(t, itemRepo.Close())
+
+		itemRepo, err = memory.OpenItemRepository(memory.LogOptions{Dir: dir})
+		require.NoError(t, err)
+
+		defer func() { _ = itemRepo.Close() }()
+
+		res, err := itemRepo.ListByType(ctx, "bar")
+		require.NoError(t, err)
+		assert.Len(t, res, 2)
+	})
+
+	t.Run("Invalid Sync Policy", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := memory.OpenItemRepository(memory.LogOptions{Dir: t.TempDir(), SyncPolicy: "sometimes"})
+		assert.Error(t, err)
+	})
+}
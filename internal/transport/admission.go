@@ -0,0 +1,261 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultWebhookTimeout    = 10 * time.Second
+	maxWebhookTimeoutSeconds = 30
+	// maxAdmissionResponseSize is the size of webhook responses that are read, so a broken webhook can't exhaust memory.
+	maxAdmissionResponseSize = 1 << 20
+)
+
+// AdmissionReview is what webhooks are sent about a write. Object is missing on deletes, and OldObject on creates.
+type AdmissionReview struct {
+	UID       string         `json:"uid"`
+	Operation core.Operation `json:"operation"`
+	Type      string         `json:"type"`
+	Name      string         `json:"name"`
+	// Actor is the user of the request of the write, if it has one.
+	Actor     string     `json:"actor,omitempty"`
+	Object    *core.Item `json:"object,omitempty"`
+	OldObject *core.Item `json:"oldObject,omitempty"`
+}
+
+// AdmissionResponse is what webhooks answer reviews with. Message tells why a write is rejected. Patch is a JSON patch
+// of the json form of the item, which is applied to its data, labels and annotations. Only mutating webhooks can patch.
+type AdmissionResponse struct {
+	Allowed bool          `json:"allowed"`
+	Message string        `json:"message,omitempty"`
+	Patch   []interface{} `json:"patch,omitempty"`
+}
+
+type actorKey struct{}
+
+func withActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFromRequest returns the user of the X-User header of the request. It's trusted as it is, like roles, so a proxy
+// that authenticates requests should set it.
+func actorFromRequest(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get("X-User"))
+}
+
+func actorOf(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+
+	return actor
+}
+
+// admit runs the before hooks of the script and webhooks of the type of the item on the write, which may change item.
+// New items get the initial workflow state first, then before hooks go, then webhooks, then the workflow transition is
+// checked, and rules are checked last. Item is nil on deletes, and old on creates.
+func (h *Handler) admit(ctx context.Context, op core.Operation, old, item *core.Item) error {
+	if op == core.OperationCreate {
+		h.defaultState(item)
+	}
+
+	err := h.runBeforeHook(ctx, op, old, item)
+	if err != nil {
+		return err
+	}
+
+	err = h.callWebhooks(ctx, op, old, item)
+	if err != nil {
+		return err
+	}
+
+	if item == nil {
+		return nil
+	}
+
+	err = h.checkTransition(ctx, old, item)
+	if err != nil {
+		return err
+	}
+
+	return h.checkRules(ctx, old, item)
+}
+
+// callWebhooks sends the write to the webhooks of the type of the item. Mutating webhooks go first and may change item,
+// which is checked again after them, and then validating webhooks see the result.
+func (h *Handler) callWebhooks(ctx context.Context, op core.Operation, old, item *core.Item) error {
+	subject := item
+	if subject == nil {
+		subject = old
+	}
+
+	schema, ok := h.schemaOf(subject.Type)
+	if !ok || len(schema.Webhooks) == 0 {
+		return nil
+	}
+
+	for _, kind := range []core.WebhookKind{core.WebhookMutating, core.WebhookValidating} {
+		mutated := false
+
+		for _, webhook := range schema.Webhooks {
+			if webhook.Kind != kind || !reviews(webhook, op) {
+				continue
+			}
+
+			review := AdmissionReview{
+				UID:       uuid.NewString(),
+				Operation: op,
+				Type:      subject.Type,
+				Name:      subject.Name,
+				Actor:     actorOf(ctx),
+				Object:    item,
+				OldObject: old,
+			}
+
+			rsp, err := h.review(ctx, webhook, review)
+			if err == nil && !rsp.Allowed {
+				message := rsp.Message
+				if message == "" {
+					message = "no reason given"
+				}
+
+				return newProblemError(http.StatusForbidden, CodeAdmissionDenied, fmt.Sprintf("webhook '%s' rejected the write: %s", webhook.Name, message))
+			}
+
+			if err == nil && kind == core.WebhookMutating && item != nil && len(rsp.Patch) != 0 {
+				err = applyAdmissionPatch(item, rsp.Patch)
+				if err == nil {
+					mutated = true
+				}
+			}
+
+			if err != nil {
+				if webhook.FailurePolicy == core.FailurePolicyIgnore {
+					continue
+				}
+
+				return newProblemError(http.StatusBadGateway, CodeAdmissionFailed, fmt.Sprintf("error on review of webhook '%s': %s", webhook.Name, err.Error()))
+			}
+		}
+
+		if mutated {
+			err := h.checkMutated(ctx, *item)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func reviews(webhook core.Webhook, op core.Operation) bool {
+	if len(webhook.Operations) == 0 {
+		return true
+	}
+
+	for _, o := range webhook.Operations {
+		if o == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newWebhookClient returns the client of webhooks, whose connections aren't shared with other clients of the process.
+func newWebhookClient() *http.Client {
+	transport, _ := http.DefaultTransport.(*http.Transport)
+
+	return &http.Client{
+		Transport:     transport.Clone(),
+		CheckRedirect: nil,
+		Jar:           nil,
+		Timeout:       defaultWebhookTimeout,
+	}
+}
+
+// review sends the review to the webhook, and returns its response. Requests time out after the timeout of the webhook.
+func (h *Handler) review(ctx context.Context, webhook core.Webhook, review AdmissionReview) (*AdmissionResponse, error) {
+	client := *h.webhookClient
+
+	if webhook.TimeoutSeconds > 0 {
+		client.Timeout = time.Duration(webhook.TimeoutSeconds) * time.Second
+	}
+
+	body, err := json.Marshal(review)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on marshal admission review")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "error on create request")
+	}
+
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("Accept", contentTypeJSON)
+
+	rsp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on send request")
+	}
+
+	defer func() { _ = rsp.Body.Close() }()
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("webhook responded with status %d", rsp.StatusCode)
+	}
+
+	var res AdmissionResponse
+
+	err = json.NewDecoder(io.LimitReader(rsp.Body, maxAdmissionResponseSize)).Decode(&res)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on decode admission response")
+	}
+
+	return &res, nil
+}
+
+// applyAdmissionPatch applies the JSON patch to the json form of the item, and keeps changes to data, labels and
+// annotations.
+func applyAdmissionPatch(item *core.Item, patch []interface{}) error {
+	b, err := json.Marshal(patch)
+	if err != nil {
+		return errors.Wrap(err, "error on marshal patch")
+	}
+
+	modified, err := patchItemJSON(item, contentTypeJSONPatch, b)
+	if err != nil {
+		return errors.Wrap(err, "error on apply patch")
+	}
+
+	item.Data = modified.Data
+	item.Labels = modified.Labels
+	item.Annotations = modified.Annotations
+
+	return nil
+}
+
+// checkMutated checks the item again after mutating webhooks or scripts changed it.
+func (h *Handler) checkMutated(ctx context.Context, item core.Item) error {
+	err := checkData(item.Data)
+	if err != nil {
+		return err
+	}
+
+	err = metadataOf(item).check()
+	if err != nil {
+		return err
+	}
+
+	return h.checkReferences(ctx, item.Type, item.Data)
+}
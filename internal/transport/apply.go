@@ -0,0 +1,290 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/nasermirzaei89/core/internal/repository"
+	"github.com/pkg/errors"
+)
+
+const contentTypeApplyPatch = "application/apply-patch+yaml"
+
+// ApplyItemHandler applies the item of the body, which has its type and name next to its data.
+func (h *Handler) ApplyItemHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
+
+		fw, ok := writerFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		var req core.Item
+
+		err := decodeRequest(r, &req)
+		if err != nil {
+			writeDecodeProblem(w, r, err)
+
+			return
+		}
+
+		err = checkType(req.Type)
+		if err != nil {
+			writeError(w, r, err)
+
+			return
+		}
+
+		item, created, err := h.applyItem(r.Context(), req.Type, req.Name, req.Data, metadataOf(req), fw)
+		if err != nil {
+			writeError(w, r, err)
+
+			return
+		}
+
+		writeApplyResponse(w, r, item, created)
+	}
+}
+
+// applyPatchHandler applies the item of an apply patch, which is YAML, or JSON as YAML is a superset of it.
+func (h *Handler) applyPatchHandler(w http.ResponseWriter, r *http.Request, typ, name string, body []byte, fw fieldWriter) {
+	var req core.Item
+
+	err := yamlCodec{}.Decode(bytes.NewReader(body), &req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("body is empty")
+		}
+
+		writeProblem(w, r, http.StatusBadRequest, CodeBodyInvalid, fmt.Sprintf("error on decode apply patch: %s", err.Error()))
+
+		return
+	}
+
+	if req.Type != "" && req.Type != typ {
+		writeProblem(w, r, http.StatusBadRequest, CodeTypeMismatch, fmt.Sprintf("type field '%s' doesn't match type '%s'", req.Type, typ),
+			InvalidParam{Name: "type", Reason: fmt.Sprintf("should be '%s'", typ)})
+
+		return
+	}
+
+	if req.Name != "" && req.Name != name {
+		writeProblem(w, r, http.StatusBadRequest, CodeBodyInvalid, fmt.Sprintf("name field '%s' doesn't match name '%s'", req.Name, name),
+			InvalidParam{Name: "name", Reason: fmt.Sprintf("should be '%s'", name)})
+
+		return
+	}
+
+	item, created, err := h.applyItem(r.Context(), typ, name, req.Data, metadataOf(req), fw)
+	if err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	writeApplyResponse(w, r, item, created)
+}
+
+func isApplyPatch(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	return mediaType == contentTypeApplyPatch
+}
+
+func writeApplyResponse(w http.ResponseWriter, r *http.Request, item *core.Item, created bool) {
+	if created {
+		writeResponse(w, r, http.StatusCreated, item)
+
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, item)
+}
+
+// applyItem creates the item with the desired data, or merges the desired data into data of the existing item.
+// Fields of the last applied data that are missing from the desired data are removed, and fields other writers set
+// are kept. Labels and annotations are merged the same way, and the desired data, labels and annotations are recorded
+// as the last applied ones of the item. Owner references and finalizers in meta replace the ones of the item.
+func (h *Handler) applyItem(ctx context.Context, typ, name string, desired map[string]interface{}, meta itemMetadata, fw fieldWriter) (*core.Item, bool, error) {
+	if desired == nil {
+		desired = make(map[string]interface{})
+	}
+
+	err := checkName(name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = checkData(desired)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = meta.check()
+	if err != nil {
+		return nil, false, err
+	}
+
+	item, err := h.itemRepo.GetByTypeAndName(ctx, typ, name)
+	if errors.Is(err, repository.ErrItemNotFound) {
+		res := newItem(typ, name)
+		res.LastApplied = &core.AppliedItem{Data: desired, Labels: meta.labels, Annotations: meta.annotations}
+
+		err = h.insertItem(ctx, &res, removeNulls(desired), meta, fw)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return &res, true, nil
+	}
+
+	if err != nil {
+		return nil, false, repositoryError("error on find item by type and name from the repository", err)
+	}
+
+	last := core.AppliedItem{Data: nil, Labels: nil, Annotations: nil}
+	if item.LastApplied != nil {
+		last = *item.LastApplied
+	}
+
+	patch, err := json.Marshal(threeWayMergePatch(last.Data, item.Data, desired))
+	if err != nil {
+		return nil, false, newProblemError(http.StatusInternalServerError, CodeInternalError, fmt.Sprintf("error on marshal merge patch: %s", err.Error()))
+	}
+
+	modified, err := patchItemJSON(item, contentTypeMergePatch, patch)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = h.checkReferences(ctx, typ, modified.Data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = h.checkOwners(ctx, item.OwnerReferences, meta.ownerReferences)
+	if err != nil {
+		return nil, false, err
+	}
+
+	original := item.DeepCopy()
+	updated := item.DeepCopy()
+	meta.set(&updated)
+	updated.Labels = threeWayMerge(last.Labels, item.Labels, meta.labels)
+	updated.Annotations = threeWayMerge(last.Annotations, item.Annotations, meta.annotations)
+	applied := &core.AppliedItem{Data: desired, Labels: meta.labels, Annotations: meta.annotations}
+
+	changed := !reflect.DeepEqual(item.Data, modified.Data) || !reflect.DeepEqual(item.Labels, updated.Labels) ||
+		!reflect.DeepEqual(item.Annotations, updated.Annotations) || !reflect.DeepEqual(item.OwnerReferences, updated.OwnerReferences) ||
+		!reflect.DeepEqual(item.Finalizers, updated.Finalizers)
+
+	if !changed && reflect.DeepEqual(item.LastApplied, applied) {
+		return item, false, nil
+	}
+
+	err = fw.write(&updated, modified.Data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	item = &updated
+	item.LastApplied = applied
+
+	// recording the last applied data alone doesn't count as a change of the item
+	if changed {
+		item.UpdatedAt = time.Now()
+	}
+
+	err = h.saveItem(ctx, original, item)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return item, false, nil
+}
+
+// threeWayMergePatch returns the merge patch that turns current into desired, but only removes the fields of last
+// that desired doesn't have. Objects are merged field by field, other values are replaced as a whole.
+func threeWayMergePatch(last, current, desired map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{})
+
+	for k := range last {
+		if _, ok := desired[k]; !ok {
+			if _, ok := current[k]; ok {
+				res[k] = nil
+			}
+		}
+	}
+
+	for k, v := range desired {
+		currentValue, ok := current[k]
+
+		desiredMap, desiredIsMap := v.(map[string]interface{})
+		currentMap, currentIsMap := currentValue.(map[string]interface{})
+
+		switch {
+		case desiredIsMap && currentIsMap:
+			lastMap, _ := last[k].(map[string]interface{})
+
+			if patch := threeWayMergePatch(lastMap, currentMap, desiredMap); len(patch) != 0 {
+				res[k] = patch
+			}
+		case !ok || !reflect.DeepEqual(currentValue, v):
+			res[k] = v
+		}
+	}
+
+	return res
+}
+
+// threeWayMerge returns current with the keys of desired, but without the keys of last that desired doesn't have.
+func threeWayMerge(last, current, desired map[string]string) map[string]string {
+	res := make(map[string]string, len(current)+len(desired))
+
+	for k, v := range current {
+		if _, ok := last[k]; ok {
+			if _, ok := desired[k]; !ok {
+				continue
+			}
+		}
+
+		res[k] = v
+	}
+
+	for k, v := range desired {
+		res[k] = v
+	}
+
+	if len(res) == 0 {
+		return nil
+	}
+
+	return res
+}
+
+// removeNulls removes null fields, as applying them removes fields.
+func removeNulls(m map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(m))
+
+	for k, v := range m {
+		switch v := v.(type) {
+		case nil:
+		case map[string]interface{}:
+			res[k] = removeNulls(v)
+		default:
+			res[k] = v
+		}
+	}
+
+	return res
+}
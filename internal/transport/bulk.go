@@ -0,0 +1,512 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/pkg/errors"
+)
+
+const contentTypeNDJSON = "application/x-ndjson"
+
+const (
+	ImportModeCreateOnly = "create-only"
+	ImportModeUpsert     = "upsert"
+	ImportModeReplaceAll = "replace-all"
+)
+
+type ImportLineError struct {
+	Line    int    `json:"line"`
+	Name    string `json:"name,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+type ImportResult struct {
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Deleted int               `json:"deleted"`
+	Failed  int               `json:"failed"`
+	Errors  []ImportLineError `json:"errors"`
+}
+
+func (res *ImportResult) add(line int, name string, created bool, lineErr *ImportLineError, seen map[string]struct{}) {
+	switch {
+	case lineErr != nil:
+		lineErr.Line = line
+		res.Failed++
+		res.Errors = append(res.Errors, *lineErr)
+	case created:
+		res.Created++
+	default:
+		res.Updated++
+	}
+
+	if name != "" {
+		seen[name] = struct{}{}
+	}
+}
+
+func (h *Handler) ExportItemsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r, contentTypeNDJSON); !ok {
+			return
+		}
+
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		sel, ok := selectorFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", contentTypeNDJSON)
+
+		_ = h.exportType(r.Context(), w, typ, sel)
+	}
+}
+
+func (h *Handler) ExportAllItemsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r, contentTypeNDJSON); !ok {
+			return
+		}
+
+		sel, ok := selectorFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		types, err := h.itemRepo.ListTypes(r.Context())
+		if err != nil {
+			writeRepositoryProblem(w, r, "error on list types from the repository", err)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", contentTypeNDJSON)
+
+		for _, typ := range types {
+			err = h.exportType(r.Context(), w, typ, sel)
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// exportType streams items of the type whose labels match the selector as NDJSON. Once streaming started, errors can
+// only be reported by cutting the response short.
+func (h *Handler) exportType(ctx context.Context, w http.ResponseWriter, typ string, sel labelSelector) error {
+	enc := json.NewEncoder(w)
+
+	err := h.eachSelected(ctx, typ, sel, func(item core.Item) error {
+		return enc.Encode(item)
+	})
+	if err != nil {
+		return errors.Wrap(err, "error on export items")
+	}
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	return nil
+}
+
+func (h *Handler) ImportItemsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
+
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		mode := r.URL.Query().Get("mode")
+		if mode == "" {
+			mode = ImportModeCreateOnly
+		}
+
+		switch mode {
+		case ImportModeCreateOnly, ImportModeUpsert, ImportModeReplaceAll:
+		default:
+			writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, "mode parameter is not valid",
+				InvalidParam{Name: "mode", Reason: fmt.Sprintf("should be one of '%s', '%s' or '%s'", ImportModeCreateOnly, ImportModeUpsert, ImportModeReplaceAll)})
+
+			return
+		}
+
+		res := ImportResult{Errors: make([]ImportLineError, 0)}
+		seen := make(map[string]struct{})
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+		switch mediaType {
+		case contentTypeCSV:
+			mapping, err := csvHeaderMapping(r)
+			if err != nil {
+				writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, "map parameter is not valid",
+					InvalidParam{Name: "map", Reason: err.Error()})
+
+				return
+			}
+
+			err = h.importCSV(r.Context(), r.Body, typ, mode, mapping, &res, seen)
+			if err != nil {
+				writeProblem(w, r, http.StatusBadRequest, CodeBodyInvalid, fmt.Sprintf("error on read request body: %s", err.Error()))
+
+				return
+			}
+		case contentTypeNDJSON, "":
+			err := h.importNDJSON(r.Context(), r.Body, typ, mode, &res, seen)
+			if err != nil {
+				writeProblem(w, r, http.StatusBadRequest, CodeBodyInvalid, fmt.Sprintf("error on read request body: %s", err.Error()))
+
+				return
+			}
+		default:
+			writeProblem(w, r, http.StatusUnsupportedMediaType, CodeMediaTypeUnsupported,
+				fmt.Sprintf("unsupported Content-Type header, it should be '%s' or '%s'", contentTypeNDJSON, contentTypeCSV))
+
+			return
+		}
+
+		// items of a failed line may be among the ones that would be deleted, so replace-all only prunes clean imports.
+		// pruned items are deleted like by the delete endpoint, so their references and owners are handled the same.
+		if mode == ImportModeReplaceAll && res.Failed == 0 {
+			items, err := h.itemRepo.ListByType(r.Context(), typ)
+			if err != nil {
+				writeRepositoryProblem(w, r, "error on list items by type from the repository", err)
+
+				return
+			}
+
+			for i := range items {
+				if _, ok := seen[items[i].Name]; ok {
+					continue
+				}
+
+				_, err = h.deleteItem(r.Context(), typ, items[i].Name, PropagationBackground)
+				if err != nil {
+					res.Failed++
+					res.Errors = append(res.Errors, *importLineError(items[i].Name, err))
+
+					continue
+				}
+
+				res.Deleted++
+			}
+		}
+
+		writeResponse(w, r, http.StatusOK, res)
+	}
+}
+
+func (h *Handler) importNDJSON(ctx context.Context, body io.Reader, typ, mode string, res *ImportResult, seen map[string]struct{}) error {
+	rd := bufio.NewReader(body)
+
+	for line := 1; ; line++ {
+		data, err := rd.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return errors.Wrap(err, "error on read line")
+		}
+
+		if len(bytes.TrimSpace(data)) != 0 {
+			name, created, lineErr := h.importLine(ctx, typ, mode, data)
+
+			res.add(line, name, created, lineErr, seen)
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+	}
+}
+
+// importLine creates or updates the item on one NDJSON line, like the item endpoints do. New items keep their uuid,
+// timestamps, status and managed fields from the line when given, so exports can be restored as they were. They keep
+// their deletion timestamp only if they have finalizers.
+func (h *Handler) importLine(ctx context.Context, typ, mode string, data []byte) (string, bool, *ImportLineError) {
+	var req core.Item
+
+	err := json.Unmarshal(data, &req)
+	if err != nil {
+		return "", false, &ImportLineError{Code: CodeBodyInvalid, Message: "error on decode line", Error: err.Error()}
+	}
+
+	if req.Type != "" && req.Type != typ {
+		return req.Name, false, &ImportLineError{Code: CodeTypeMismatch, Name: req.Name, Message: fmt.Sprintf("type field '%s' doesn't match type '%s'", req.Type, typ)}
+	}
+
+	now := time.Now()
+
+	item := core.Item{
+		UUID:              req.UUID,
+		Type:              typ,
+		Name:              req.Name,
+		Data:              req.Data,
+		CreatedAt:         req.CreatedAt,
+		UpdatedAt:         req.UpdatedAt,
+		Status:            req.Status,
+		Transitions:       req.Transitions,
+		Labels:            nil,
+		Annotations:       nil,
+		OwnerReferences:   nil,
+		Finalizers:        nil,
+		DeletionTimestamp: req.DeletionTimestamp,
+		LastApplied:       req.LastApplied,
+		ManagedFields:     req.ManagedFields,
+	}
+
+	if _, err := uuid.Parse(item.UUID); err != nil {
+		item.UUID = uuid.NewString()
+	}
+
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = now
+	}
+
+	if item.UpdatedAt.IsZero() {
+		item.UpdatedAt = now
+	}
+
+	// items only wait for deletion while finalizers hold them
+	if len(req.Finalizers) == 0 {
+		item.DeletionTimestamp = nil
+	}
+
+	// data of the line is already on the item, so writing it keeps the managed fields of the line
+	err = h.insertItem(ctx, &item, req.Data, metadataOf(req), fieldWriter{manager: "", force: false})
+	if err == nil {
+		return req.Name, true, nil
+	}
+
+	var pe *problemError
+	if mode == ImportModeCreateOnly || !errors.As(err, &pe) || pe.code != CodeItemAlreadyExists {
+		return req.Name, false, importLineError(req.Name, err)
+	}
+
+	_, err = h.replaceItem(ctx, typ, req.Name, req.Data, metadataOf(req), fieldWriter{manager: "", force: false})
+	if err != nil {
+		return req.Name, false, importLineError(req.Name, err)
+	}
+
+	return req.Name, false, nil
+}
+
+func importLineError(name string, err error) *ImportLineError {
+	var pe *problemError
+	if errors.As(err, &pe) {
+		return &ImportLineError{Code: pe.code, Name: name, Message: pe.detail}
+	}
+
+	return &ImportLineError{Code: CodeInternalError, Name: name, Message: "error on import item", Error: err.Error()}
+}
+
+// BulkResult summarizes a bulk write. Items has names of the written items, or of the matched ones on dry runs.
+type BulkResult struct {
+	DryRun    bool            `json:"dryRun"`
+	Matched   int             `json:"matched"`
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	Items     []string        `json:"items"`
+	Errors    []BulkItemError `json:"errors"`
+}
+
+type BulkItemError struct {
+	Name    string `json:"name"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PatchItemsHandler applies a json patch or a json merge patch to every item matching the filters and label selector.
+func (h *Handler) PatchItemsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
+
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		q, dryRun, ok := bulkQueryFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		fw, ok := writerFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		patchType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if patchType != contentTypeJSONPatch && patchType != contentTypeMergePatch {
+			writeProblem(w, r, http.StatusUnsupportedMediaType, CodeMediaTypeUnsupported,
+				fmt.Sprintf("unsupported Content-Type header, it should be '%s' or '%s'", contentTypeJSONPatch, contentTypeMergePatch))
+
+			return
+		}
+
+		patch, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeBodyInvalid, fmt.Sprintf("error on read request body: %s", err.Error()))
+
+			return
+		}
+
+		res, err := h.bulkWrite(r.Context(), typ, q, dryRun, func(ctx context.Context, name string) error {
+			_, err := h.patchItem(ctx, typ, name, patchType, patch, fw)
+
+			return err
+		})
+		if err != nil {
+			writeError(w, r, err)
+
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, res)
+	}
+}
+
+// DeleteItemsHandler deletes every item matching the filters and label selector. Deleting all items of the type
+// needs confirm=true, so a forgotten filter doesn't wipe the type.
+func (h *Handler) DeleteItemsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
+
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		q, dryRun, ok := bulkQueryFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		confirm, ok := boolFromRequest(w, r, "confirm")
+		if !ok {
+			return
+		}
+
+		propagation, ok := propagationFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		if len(q.selector) == 0 && len(q.filters) == 0 && !confirm && !dryRun {
+			writeProblem(w, r, http.StatusBadRequest, CodeConfirmationRequired,
+				fmt.Sprintf("deleting all items of type '%s' needs confirmation, set confirm=true or narrow it with filter or labelSelector", typ),
+				InvalidParam{Name: "confirm", Reason: "should be true when there is no filter or labelSelector"})
+
+			return
+		}
+
+		res, err := h.bulkWrite(r.Context(), typ, q, dryRun, func(ctx context.Context, name string) error {
+			_, err := h.deleteItem(ctx, typ, name, propagation)
+
+			return err
+		})
+		if err != nil {
+			writeError(w, r, err)
+
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, res)
+	}
+}
+
+// bulkQueryFromRequest reads the filter, labelSelector and dryRun parameters of bulk writes.
+func bulkQueryFromRequest(w http.ResponseWriter, r *http.Request) (itemQuery, bool, bool) {
+	q := itemQuery{selector: nil, filters: nil, sorts: nil, offset: 0, limit: -1}
+
+	sel, ok := selectorFromRequest(w, r)
+	if !ok {
+		return q, false, false
+	}
+
+	filters, ok := filtersFromRequest(w, r)
+	if !ok {
+		return q, false, false
+	}
+
+	dryRun, ok := boolFromRequest(w, r, "dryRun")
+	if !ok {
+		return q, false, false
+	}
+
+	q.selector, q.filters = sel, filters
+
+	return q, dryRun, true
+}
+
+// bulkWrite calls write for each item matching the query, and keeps going when writes fail. Dry runs only list the
+// matching items.
+func (h *Handler) bulkWrite(ctx context.Context, typ string, q itemQuery, dryRun bool, write func(ctx context.Context, name string) error) (*BulkResult, error) {
+	page, err := h.listItems(ctx, typ, q)
+	if err != nil {
+		return nil, err
+	}
+
+	res := BulkResult{
+		DryRun:    dryRun,
+		Matched:   len(page.items),
+		Succeeded: 0,
+		Failed:    0,
+		Items:     make([]string, 0, len(page.items)),
+		Errors:    make([]BulkItemError, 0),
+	}
+
+	for i := range page.items {
+		name := page.items[i].Name
+
+		if dryRun {
+			res.Items = append(res.Items, name)
+
+			continue
+		}
+
+		err = write(ctx, name)
+		if err != nil {
+			res.Failed++
+			res.Errors = append(res.Errors, bulkItemError(name, err))
+
+			continue
+		}
+
+		res.Succeeded++
+		res.Items = append(res.Items, name)
+	}
+
+	return &res, nil
+}
+
+func bulkItemError(name string, err error) BulkItemError {
+	var pe *problemError
+	if errors.As(err, &pe) {
+		return BulkItemError{Name: name, Code: pe.code, Message: pe.detail}
+	}
+
+	return BulkItemError{Name: name, Code: CodeInternalError, Message: err.Error()}
+}
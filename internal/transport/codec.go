@@ -0,0 +1,377 @@
+package transport
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/pkg/errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	contentTypeJSON    = "application/json"
+	contentTypeYAML    = "application/yaml"
+	contentTypeCBOR    = "application/cbor"
+	contentTypeMsgPack = "application/msgpack"
+)
+
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// codec encodes and decodes bodies of one media type. Non-JSON codecs go through the generic JSON form of values,
+// so custom JSON marshalling, like the flattened core.Item, applies to every format.
+type codec interface {
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, v interface{}) error {
+	return errors.Wrap(json.NewEncoder(w).Encode(v), "error on encode json")
+}
+
+func (jsonCodec) Decode(r io.Reader, v interface{}) error {
+	return errors.Wrap(json.NewDecoder(r).Decode(v), "error on decode json")
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Encode(w io.Writer, v interface{}) error {
+	g, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(w)
+
+	err = enc.Encode(g)
+	if err != nil {
+		return errors.Wrap(err, "error on encode yaml")
+	}
+
+	return errors.Wrap(enc.Close(), "error on close yaml encoder")
+}
+
+func (yamlCodec) Decode(r io.Reader, v interface{}) error {
+	var g interface{}
+
+	err := yaml.NewDecoder(r).Decode(&g)
+	if err != nil {
+		return errors.Wrap(err, "error on decode yaml")
+	}
+
+	return fromGeneric(g, v)
+}
+
+type cborCodec struct {
+	dec cbor.DecMode
+}
+
+func newCBORCodec() cborCodec {
+	dec, err := cbor.DecOptions{DefaultMapType: mapType}.DecMode()
+	if err != nil {
+		panic(errors.Wrap(err, "error on create cbor decode mode"))
+	}
+
+	return cborCodec{dec: dec}
+}
+
+func (c cborCodec) Encode(w io.Writer, v interface{}) error {
+	g, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(cbor.NewEncoder(w).Encode(g), "error on encode cbor")
+}
+
+func (c cborCodec) Decode(r io.Reader, v interface{}) error {
+	var g interface{}
+
+	err := c.dec.NewDecoder(r).Decode(&g)
+	if err != nil {
+		return errors.Wrap(err, "error on decode cbor")
+	}
+
+	return fromGeneric(g, v)
+}
+
+type msgPackCodec struct{}
+
+func (msgPackCodec) Encode(w io.Writer, v interface{}) error {
+	g, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(msgpack.NewEncoder(w).Encode(g), "error on encode msgpack")
+}
+
+func (msgPackCodec) Decode(r io.Reader, v interface{}) error {
+	var g interface{}
+
+	err := msgpack.NewDecoder(r).Decode(&g)
+	if err != nil {
+		return errors.Wrap(err, "error on decode msgpack")
+	}
+
+	return fromGeneric(g, v)
+}
+
+var (
+	mapType = reflect.TypeOf(map[string]interface{}(nil)) //nolint:gochecknoglobals
+
+	// codecs maps media types to codecs. The first one is the default.
+	codecs = []struct { //nolint:gochecknoglobals
+		contentType string
+		aliases     []string
+		codec       codec
+	}{
+		{contentType: contentTypeJSON, aliases: []string{contentTypeProblemJSON}, codec: jsonCodec{}},
+		{contentType: contentTypeYAML, aliases: []string{"application/x-yaml", "text/yaml", contentTypeProblemYAML}, codec: yamlCodec{}},
+		{contentType: contentTypeCBOR, aliases: nil, codec: newCBORCodec()},
+		{contentType: contentTypeMsgPack, aliases: []string{"application/x-msgpack", "application/vnd.msgpack"}, codec: msgPackCodec{}},
+	}
+)
+
+func codecFor(mediaType string) (string, codec, bool) {
+	for _, c := range codecs {
+		if c.contentType == mediaType {
+			return c.contentType, c.codec, true
+		}
+
+		for _, alias := range c.aliases {
+			if alias == mediaType {
+				return c.contentType, c.codec, true
+			}
+		}
+	}
+
+	return "", nil, false
+}
+
+func codecContentTypes() []string {
+	res := make([]string, len(codecs))
+
+	for i := range codecs {
+		res[i] = codecs[i].contentType
+	}
+
+	return res
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(header string) []acceptRange {
+	res := make([]acceptRange, 0)
+
+	for _, part := range strings.Split(header, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+
+		if s, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(s, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		res = append(res, acceptRange{mediaType: mediaType, q: q})
+	}
+
+	sort.SliceStable(res, func(i, j int) bool { return res[i].q > res[j].q })
+
+	return res
+}
+
+func matchesRange(mediaType, accepted string) bool {
+	if accepted == "*/*" || accepted == mediaType {
+		return true
+	}
+
+	if strings.HasSuffix(accepted, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(accepted, "*"))
+	}
+
+	return false
+}
+
+// negotiate picks the offered media type the Accept header prefers. A missing Accept header accepts the first offer.
+func negotiate(r *http.Request, offers []string) (string, bool) {
+	header := r.Header.Get("Accept")
+	if strings.TrimSpace(header) == "" {
+		return offers[0], true
+	}
+
+	for _, accepted := range parseAccept(header) {
+		if accepted.q <= 0 {
+			continue
+		}
+
+		mediaType := accepted.mediaType
+
+		if canonical, _, ok := codecFor(mediaType); ok {
+			mediaType = canonical
+		}
+
+		for _, offer := range offers {
+			if matchesRange(offer, mediaType) {
+				return offer, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// responseCodec returns the codec the request accepts, falling back to JSON so errors can always be written.
+func responseCodec(r *http.Request) (string, codec, bool) {
+	contentType, ok := negotiate(r, codecContentTypes())
+	if !ok {
+		return contentTypeJSON, jsonCodec{}, false
+	}
+
+	_, c, _ := codecFor(contentType)
+
+	return contentType, c, true
+}
+
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	contentType, c, _ := responseCodec(r)
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+
+	_ = c.Encode(w, v)
+}
+
+// acceptable writes a 406 response if the request accepts none of the offered media types.
+func acceptable(w http.ResponseWriter, r *http.Request, offers ...string) (string, bool) {
+	if len(offers) == 0 {
+		offers = codecContentTypes()
+	}
+
+	contentType, ok := negotiate(r, offers)
+	if !ok {
+		writeProblem(w, r, http.StatusNotAcceptable, CodeMediaTypeNotAcceptable,
+			fmt.Sprintf("none of the accepted media types is supported, supported media types are '%s'", strings.Join(offers, "', '")))
+
+		return "", false
+	}
+
+	return contentType, true
+}
+
+// decodeRequest decodes the body with the codec of its Content-Type, JSON if there is none.
+func decodeRequest(r *http.Request, v interface{}) error {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		return jsonCodec{}.Decode(r.Body, v)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return errors.Wrap(errUnsupportedMediaType, err.Error())
+	}
+
+	_, c, ok := codecFor(mediaType)
+	if !ok {
+		return errors.Wrapf(errUnsupportedMediaType, "media type '%s' is not supported", mediaType)
+	}
+
+	return c.Decode(r.Body, v)
+}
+
+func toGeneric(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on marshal json")
+	}
+
+	var g interface{}
+
+	err = json.Unmarshal(b, &g)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on unmarshal json")
+	}
+
+	return g, nil
+}
+
+func fromGeneric(g interface{}, v interface{}) error {
+	g, err := normalizeGeneric(g)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(g)
+	if err != nil {
+		return errors.Wrap(err, "error on marshal json")
+	}
+
+	return errors.Wrap(json.Unmarshal(b, v), "error on unmarshal json")
+}
+
+// normalizeGeneric converts decoded values JSON can't represent, like maps with non-string keys.
+func normalizeGeneric(g interface{}) (interface{}, error) {
+	switch g := g.(type) {
+	case map[string]interface{}:
+		for k, v := range g {
+			n, err := normalizeGeneric(v)
+			if err != nil {
+				return nil, err
+			}
+
+			g[k] = n
+		}
+
+		return g, nil
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(g))
+
+		for k, v := range g {
+			n, err := normalizeGeneric(v)
+			if err != nil {
+				return nil, err
+			}
+
+			res[fmt.Sprint(k)] = n
+		}
+
+		return res, nil
+	case []interface{}:
+		for i := range g {
+			n, err := normalizeGeneric(g[i])
+			if err != nil {
+				return nil, err
+			}
+
+			g[i] = n
+		}
+
+		return g, nil
+	case []byte:
+		return string(g), nil
+	default:
+		return g, nil
+	}
+}
@@ -0,0 +1,307 @@
+package transport
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/pkg/errors"
+)
+
+const contentTypeCSV = "text/csv"
+
+var (
+	metadataColumns = []string{"uuid", "type", "name", "createdAt", "updatedAt"}             //nolint:gochecknoglobals
+	csvNumberRegex  = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`) //nolint:gochecknoglobals
+)
+
+// flatten turns nested maps into dotted paths. Lists are kept whole and written as JSON.
+func flatten(prefix string, m map[string]interface{}, res map[string]interface{}) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) != 0 {
+			flatten(key, nested, res)
+
+			continue
+		}
+
+		res[key] = v
+	}
+}
+
+func flattenItem(item core.Item) map[string]interface{} {
+	res := make(map[string]interface{})
+
+	flatten("", item.Data, res)
+
+	res["uuid"] = item.UUID
+	res["type"] = item.Type
+	res["name"] = item.Name
+	res["createdAt"] = item.CreatedAt.Format(time.RFC3339)
+	res["updatedAt"] = item.UpdatedAt.Format(time.RFC3339)
+
+	return res
+}
+
+func csvColumns(rows []map[string]interface{}, fields string) []string {
+	if fields != "" {
+		res := make([]string, 0)
+
+		for _, field := range strings.Split(fields, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				res = append(res, field)
+			}
+		}
+
+		return res
+	}
+
+	dataColumns := make(map[string]struct{})
+
+	for _, row := range rows {
+		for k := range row {
+			dataColumns[k] = struct{}{}
+		}
+	}
+
+	for _, k := range metadataColumns {
+		delete(dataColumns, k)
+	}
+
+	res := make([]string, 0, len(dataColumns))
+
+	for k := range dataColumns {
+		res = append(res, k)
+	}
+
+	sort.Strings(res)
+
+	return append(append([]string{}, metadataColumns...), res...)
+}
+
+func csvCell(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", errors.Wrap(err, "error on marshal cell value")
+		}
+
+		return string(b), nil
+	}
+}
+
+func writeItemsCSV(w io.Writer, items []core.Item, fields string) error {
+	rows := make([]map[string]interface{}, len(items))
+
+	for i := range items {
+		rows[i] = flattenItem(items[i])
+	}
+
+	columns := csvColumns(rows, fields)
+
+	cw := csv.NewWriter(w)
+
+	err := cw.Write(columns)
+	if err != nil {
+		return errors.Wrap(err, "error on write csv header")
+	}
+
+	record := make([]string, len(columns))
+
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i], err = csvCell(row[column])
+			if err != nil {
+				return err
+			}
+		}
+
+		err = cw.Write(record)
+		if err != nil {
+			return errors.Wrap(err, "error on write csv record")
+		}
+	}
+
+	cw.Flush()
+
+	return errors.Wrap(cw.Error(), "error on flush csv")
+}
+
+// inferCSVValue converts cells that look like numbers, booleans or JSON lists and objects; everything else stays a string.
+func inferCSVValue(cell string) interface{} {
+	switch {
+	case csvNumberRegex.MatchString(cell):
+		f, err := strconv.ParseFloat(cell, 64)
+		if err == nil {
+			return f
+		}
+	case strings.EqualFold(cell, "true"):
+		return true
+	case strings.EqualFold(cell, "false"):
+		return false
+	case strings.HasPrefix(cell, "[") || strings.HasPrefix(cell, "{"):
+		var v interface{}
+		if json.Unmarshal([]byte(cell), &v) == nil {
+			return v
+		}
+	}
+
+	return cell
+}
+
+func setPath(m map[string]interface{}, path string, v interface{}) error {
+	keys := strings.Split(path, ".")
+
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := m[k]
+		if !ok {
+			nested := make(map[string]interface{})
+			m[k] = nested
+			m = nested
+
+			continue
+		}
+
+		nested, ok := next.(map[string]interface{})
+		if !ok {
+			return errors.Errorf("column '%s' conflicts with column '%s'", path, k)
+		}
+
+		m = nested
+	}
+
+	if _, ok := m[keys[len(keys)-1]]; ok {
+		return errors.Errorf("column '%s' conflicts with another column", path)
+	}
+
+	m[keys[len(keys)-1]] = v
+
+	return nil
+}
+
+// csvHeaderMapping parses `map=<header>:<field>` query parameters, which rename csv headers to item fields.
+func csvHeaderMapping(r *http.Request) (map[string]string, error) {
+	res := make(map[string]string)
+
+	for _, m := range r.URL.Query()["map"] {
+		parts := strings.SplitN(m, ":", 2)                       //nolint:gomnd
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" { //nolint:gomnd
+			return nil, errors.Errorf("map parameter '%s' is not valid, it should be in form of '<header>:<field>'", m)
+		}
+
+		res[parts[0]] = parts[1]
+	}
+
+	return res, nil
+}
+
+// importCSV feeds csv records to importLine, converting each record to the same json an NDJSON line would have.
+func (h *Handler) importCSV(ctx context.Context, body io.Reader, typ, mode string, mapping map[string]string, res *ImportResult, seen map[string]struct{}) error {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		return errors.Wrap(err, "error on read csv header")
+	}
+
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+
+		if field, ok := mapping[header[i]]; ok {
+			header[i] = field
+		}
+	}
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				res.Failed++
+				res.Errors = append(res.Errors, ImportLineError{Code: CodeBodyInvalid, Line: parseErr.Line, Message: "error on parse csv record", Error: err.Error()})
+
+				continue
+			}
+
+			return errors.Wrap(err, "error on read csv record")
+		}
+
+		line, _ := cr.FieldPos(0)
+
+		name, created, lineErr := h.importCSVRecord(ctx, typ, mode, header, record)
+
+		res.add(line, name, created, lineErr, seen)
+	}
+}
+
+func (h *Handler) importCSVRecord(ctx context.Context, typ, mode string, header, record []string) (string, bool, *ImportLineError) {
+	if len(record) != len(header) {
+		return "", false, &ImportLineError{Code: CodeBodyInvalid, Message: fmt.Sprintf("record has %d fields, header has %d", len(record), len(header))}
+	}
+
+	m := make(map[string]interface{})
+
+	for i, column := range header {
+		if column == "" || record[i] == "" {
+			continue
+		}
+
+		var v interface{} = record[i]
+
+		if !isMetadataColumn(column) {
+			v = inferCSVValue(record[i])
+		}
+
+		err := setPath(m, column, v)
+		if err != nil {
+			return "", false, &ImportLineError{Code: CodeBodyInvalid, Message: "error on map csv record", Error: err.Error()}
+		}
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", false, &ImportLineError{Code: CodeInternalError, Message: "error on marshal csv record", Error: err.Error()}
+	}
+
+	return h.importLine(ctx, typ, mode, data)
+}
+
+func isMetadataColumn(column string) bool {
+	for _, c := range metadataColumns {
+		if c == column {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,163 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/pkg/errors"
+)
+
+// maxExpandDepth is the number of references an expand path can follow, like 2 in "customer.company".
+const maxExpandDepth = 3
+
+// expansion is a reference field to expand, with the fields to expand in the referenced items.
+type expansion struct {
+	path     string
+	typ      string
+	children []expansion
+}
+
+// expandFromRequest reads the expand parameter, writing a problem if it's not valid.
+func (h *Handler) expandFromRequest(w http.ResponseWriter, r *http.Request, typ string) ([]expansion, bool) {
+	s := r.URL.Query().Get("expand")
+	if s == "" {
+		return nil, true
+	}
+
+	res := make([]expansion, 0)
+
+	for _, path := range strings.Split(s, ",") {
+		err := h.addExpansion(&res, typ, strings.Split(strings.TrimSpace(path), "."), 1)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, "expand parameter is not valid",
+				InvalidParam{Name: "expand", Reason: err.Error()})
+
+			return nil, false
+		}
+	}
+
+	return res, true
+}
+
+// addExpansion adds the expansion of the longest reference field of the type that the path starts with, and then
+// the rest of the path to the expansions of the referenced type.
+func (h *Handler) addExpansion(exps *[]expansion, typ string, path []string, depth int) error {
+	if depth > maxExpandDepth {
+		return errors.Errorf("expand paths should follow up to %d references", maxExpandDepth)
+	}
+
+	schema, _ := h.schemaOf(typ)
+
+	for i := len(path); i > 0; i-- {
+		field := strings.Join(path[:i], ".")
+
+		ref, ok := schema.References[field]
+		if !ok {
+			continue
+		}
+
+		var exp *expansion
+
+		for j := range *exps {
+			if (*exps)[j].path == field {
+				exp = &(*exps)[j]
+			}
+		}
+
+		if exp == nil {
+			*exps = append(*exps, expansion{path: field, typ: ref.Type, children: nil})
+			exp = &(*exps)[len(*exps)-1]
+		}
+
+		if i == len(path) {
+			return nil
+		}
+
+		return h.addExpansion(&exp.children, ref.Type, path[i:], depth+1)
+	}
+
+	return errors.Errorf("'%s' is not a reference field of type '%s'", strings.Join(path, "."), typ)
+}
+
+// expandNode is an item to expand fields of, with the uuids of the items it's embedded in, so references back to
+// them aren't expanded again.
+type expandNode struct {
+	item      *core.Item
+	ancestors []string
+}
+
+// expandItems replaces reference fields of the items with the items they reference, which are looked up once each by
+// name or uuid. References to missing items are left as they are.
+func (h *Handler) expandItems(ctx context.Context, items []core.Item, exps []expansion) error {
+	if len(exps) == 0 {
+		return nil
+	}
+
+	nodes := make([]expandNode, 0, len(items))
+
+	for i := range items {
+		nodes = append(nodes, expandNode{item: &items[i], ancestors: []string{items[i].UUID}})
+	}
+
+	return h.expand(ctx, nodes, exps, make(map[string]map[string]*core.Item))
+}
+
+// expand expands the fields of the nodes. Found has the items looked up so far by type and name or uuid, which are nil
+// for missing ones.
+func (h *Handler) expand(ctx context.Context, nodes []expandNode, exps []expansion, found map[string]map[string]*core.Item) error {
+	for _, exp := range exps {
+		refs, ok := found[exp.typ]
+		if !ok {
+			refs = make(map[string]*core.Item)
+			found[exp.typ] = refs
+		}
+
+		children := make([]expandNode, 0)
+
+		for _, node := range nodes {
+			var err error
+
+			walkReference(node.item.Data, strings.Split(exp.path, "."), func(obj map[string]interface{}, key string) {
+				s, _ := obj[key].(string)
+				if s == "" || err != nil {
+					return
+				}
+
+				ref, ok := refs[s]
+				if !ok {
+					ref, err = h.findReference(ctx, exp.typ, s)
+					if err != nil {
+						return
+					}
+
+					refs[s] = ref
+				}
+
+				if ref == nil || containsString(node.ancestors, ref.UUID) {
+					return
+				}
+
+				embedded := ref.DeepCopy()
+				obj[key] = &embedded
+
+				ancestors := make([]string, 0, len(node.ancestors)+1)
+				ancestors = append(ancestors, node.ancestors...)
+
+				children = append(children, expandNode{item: &embedded, ancestors: append(ancestors, ref.UUID)})
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		err := h.expand(ctx, children, exp.children, found)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
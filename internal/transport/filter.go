@@ -0,0 +1,318 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/pkg/errors"
+)
+
+const (
+	FilterOpEq       = "EQ"
+	FilterOpNe       = "NE"
+	FilterOpGt       = "GT"
+	FilterOpGte      = "GTE"
+	FilterOpLt       = "LT"
+	FilterOpLte      = "LTE"
+	FilterOpContains = "CONTAINS"
+	FilterOpExists   = "EXISTS"
+)
+
+// itemFilter matches items whose field compares to value with op. Fields are metadata fields or dotted paths into data.
+type itemFilter struct {
+	field string
+	op    string
+	value interface{}
+}
+
+type itemSort struct {
+	field string
+	desc  bool
+}
+
+// itemField returns the value of a metadata field or a dotted path into data.
+func itemField(item core.Item, path string) (interface{}, bool) {
+	switch path {
+	case "uuid":
+		return item.UUID, true
+	case "type":
+		return item.Type, true
+	case "name":
+		return item.Name, true
+	case "createdAt":
+		return item.CreatedAt.Format(time.RFC3339), true
+	case "updatedAt":
+		return item.UpdatedAt.Format(time.RFC3339), true
+	}
+
+	var v interface{} = item.Data
+
+	for _, k := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		v, ok = m[k]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// rank orders values of different kinds, so any two values compare.
+func rank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64:
+		return 2 //nolint:gomnd
+	case string:
+		return 3 //nolint:gomnd
+	default:
+		return 4 //nolint:gomnd
+	}
+}
+
+func compareValues(a, b interface{}) int {
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+
+	switch a := a.(type) {
+	case bool:
+		b, _ := b.(bool)
+
+		switch {
+		case a == b:
+			return 0
+		case !a:
+			return -1
+		default:
+			return 1
+		}
+	case float64:
+		b, _ := b.(float64)
+
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		default:
+			return 0
+		}
+	case string:
+		b, _ := b.(string)
+
+		return strings.Compare(a, b)
+	case nil:
+		return 0
+	default:
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+}
+
+// normalizeNumber makes numbers of any go type comparable with decoded json numbers.
+func normalizeNumber(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	default:
+		return v
+	}
+}
+
+func (f itemFilter) check() error {
+	switch f.op {
+	case FilterOpEq, FilterOpNe, FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte, FilterOpContains:
+	case FilterOpExists:
+		if _, ok := f.value.(bool); !ok && f.value != nil {
+			return errors.Errorf("value of filter on '%s' should be a boolean for operator '%s'", f.field, f.op)
+		}
+	default:
+		return errors.Errorf("filter operator '%s' is not valid", f.op)
+	}
+
+	if f.field == "" {
+		return errors.New("filter field is required")
+	}
+
+	return nil
+}
+
+func (f itemFilter) match(item core.Item) bool {
+	v, ok := itemField(item, f.field)
+	value := normalizeNumber(f.value)
+
+	switch f.op {
+	case FilterOpExists:
+		want, isBool := value.(bool)
+
+		return ok == (want || !isBool)
+	case FilterOpNe:
+		return !ok || !reflect.DeepEqual(v, value)
+	case FilterOpContains:
+		return ok && contains(v, value)
+	}
+
+	if !ok {
+		return false
+	}
+
+	switch f.op {
+	case FilterOpEq:
+		return reflect.DeepEqual(v, value)
+	case FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte:
+		if rank(v) != rank(value) {
+			return false
+		}
+
+		c := compareValues(v, value)
+
+		switch f.op {
+		case FilterOpGt:
+			return c > 0
+		case FilterOpGte:
+			return c >= 0
+		case FilterOpLt:
+			return c < 0
+		default:
+			return c <= 0
+		}
+	}
+
+	return false
+}
+
+// contains reports whether a string contains a substring or a list contains an element.
+func contains(v, value interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		s, ok := value.(string)
+
+		return ok && strings.Contains(v, s)
+	case []interface{}:
+		for i := range v {
+			if reflect.DeepEqual(v[i], value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func filterItems(items []core.Item, filters []itemFilter) []core.Item {
+	if len(filters) == 0 {
+		return items
+	}
+
+	res := make([]core.Item, 0, len(items))
+
+	for i := range items {
+		matched := true
+
+		for _, f := range filters {
+			if !f.match(items[i]) {
+				matched = false
+
+				break
+			}
+		}
+
+		if matched {
+			res = append(res, items[i])
+		}
+	}
+
+	return res
+}
+
+// sortItems sorts items by the fields in order. Items missing a field come first, ties keep repository order.
+func sortItems(items []core.Item, sorts []itemSort) {
+	if len(sorts) == 0 {
+		return
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, s := range sorts {
+			a, _ := itemField(items[i], s.field)
+			b, _ := itemField(items[j], s.field)
+
+			c := compareValues(a, b)
+			if c == 0 {
+				continue
+			}
+
+			if s.desc {
+				return c > 0
+			}
+
+			return c < 0
+		}
+
+		return false
+	})
+}
+
+// filtersFromRequest reads the filter parameters, like "price:GT:3" or "origin:EXISTS", writing a problem if they're
+// not valid. Values are read as JSON, or as strings if they aren't JSON.
+func filtersFromRequest(w http.ResponseWriter, r *http.Request) ([]itemFilter, bool) {
+	params := r.URL.Query()["filter"]
+	res := make([]itemFilter, 0, len(params))
+
+	for _, param := range params {
+		f, err := parseFilter(param)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, "filter parameter is not valid",
+				InvalidParam{Name: "filter", Reason: err.Error()})
+
+			return nil, false
+		}
+
+		res = append(res, *f)
+	}
+
+	return res, true
+}
+
+func parseFilter(s string) (*itemFilter, error) {
+	field, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, errors.Errorf("filter '%s' should be in the form field:OP:value", s)
+	}
+
+	op, value, hasValue := strings.Cut(rest, ":")
+
+	f := itemFilter{field: field, op: strings.ToUpper(op), value: nil}
+
+	if hasValue {
+		err := json.Unmarshal([]byte(value), &f.value)
+		if err != nil {
+			f.value = value
+		}
+	}
+
+	err := f.check()
+	if err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
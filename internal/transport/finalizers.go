@@ -0,0 +1,127 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nasermirzaei89/core/internal/core"
+)
+
+// markDeleting sets the deletion timestamp of the item, which is removed when its last finalizer is. It's the delete
+// of the item, so callers admit it as one.
+func (h *Handler) markDeleting(ctx context.Context, item *core.Item) error {
+	if item.DeletionTimestamp != nil {
+		return nil
+	}
+
+	now := time.Now()
+	item.DeletionTimestamp = &now
+
+	err := h.itemRepo.Replace(ctx, item.UUID, *item)
+	if err != nil {
+		return repositoryError("error on replace item in the repository", err)
+	}
+
+	return nil
+}
+
+// saveItem replaces the original item with the updated one, once webhooks admit it. Items pending deletion only accept
+// removing finalizers, and are deleted when none is left, so the afterWrite hook doesn't run for them.
+func (h *Handler) saveItem(ctx context.Context, original core.Item, item *core.Item) error {
+	if original.DeletionTimestamp == nil {
+		err := h.updateItem(ctx, original, item)
+		if err != nil {
+			return err
+		}
+
+		h.runAfterWrite(ctx, item)
+
+		return nil
+	}
+
+	err := h.admit(ctx, core.OperationUpdate, &original, item)
+	if err != nil {
+		return err
+	}
+
+	err = checkFinalizing(original, *item)
+	if err != nil {
+		return err
+	}
+
+	// removing the last finalizer deletes the item, which is admitted again now that it's written
+	if len(item.Finalizers) == 0 {
+		err = h.admit(ctx, core.OperationDelete, item, nil)
+		if err != nil {
+			return err
+		}
+
+		return h.deleteWithReferences(ctx, *item)
+	}
+
+	err = h.itemRepo.Replace(ctx, item.UUID, *item)
+	if err != nil {
+		return repositoryError("error on replace item in the repository", err)
+	}
+
+	return nil
+}
+
+// updateItem replaces the original item with the updated one, once webhooks admit it.
+func (h *Handler) updateItem(ctx context.Context, original core.Item, item *core.Item) error {
+	err := h.admit(ctx, core.OperationUpdate, &original, item)
+	if err != nil {
+		return err
+	}
+
+	err = h.itemRepo.Replace(ctx, item.UUID, *item)
+	if err != nil {
+		return repositoryError("error on replace item in the repository", err)
+	}
+
+	return nil
+}
+
+// checkFinalizing rejects changes to an item pending deletion, other than removing finalizers.
+func checkFinalizing(original, item core.Item) error {
+	changed := !bytes.Equal(finalizingView(original), finalizingView(item))
+
+	for _, finalizer := range item.Finalizers {
+		if !containsString(original.Finalizers, finalizer) {
+			changed = true
+		}
+	}
+
+	if changed {
+		return newProblemError(http.StatusConflict, CodeItemDeleting,
+			fmt.Sprintf("%s '%s' is pending deletion, so only its finalizers can be removed", original.Type, original.Name))
+	}
+
+	return nil
+}
+
+// finalizingView returns the json form of the fields of the item that can't change while it's pending deletion. Empty
+// and missing fields are the same in it.
+func finalizingView(item core.Item) []byte {
+	view := struct {
+		Data            map[string]interface{} `json:"data,omitempty"`
+		Labels          map[string]string      `json:"labels,omitempty"`
+		Annotations     map[string]string      `json:"annotations,omitempty"`
+		OwnerReferences []core.OwnerReference  `json:"ownerReferences,omitempty"`
+		LastApplied     *core.AppliedItem      `json:"lastApplied,omitempty"`
+	}{
+		Data:            item.Data,
+		Labels:          item.Labels,
+		Annotations:     item.Annotations,
+		OwnerReferences: item.OwnerReferences,
+		LastApplied:     item.LastApplied,
+	}
+
+	// values of items always marshal
+	res, _ := json.Marshal(view)
+
+	return res
+}
@@ -0,0 +1,811 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gertd/go-pluralize"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/nasermirzaei89/core/internal/repository"
+	"github.com/pkg/errors"
+)
+
+const contentTypeEventStream = "text/event-stream"
+
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Extensions reports the problem code of errors returned by resolvers.
+func (err *problemError) Extensions() map[string]interface{} {
+	res := map[string]interface{}{"code": err.code}
+
+	if len(err.invalidParams) != 0 {
+		res["invalidParams"] = err.invalidParams
+	}
+
+	return res
+}
+
+func (h *Handler) GraphQLHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := graphQLRequestFrom(w, r)
+		if !ok {
+			return
+		}
+
+		operation := operationType(req)
+
+		if operation == ast.OperationTypeMutation && r.Method == http.MethodGet {
+			writeProblem(w, r, http.StatusMethodNotAllowed, CodeOperationNotAllowed, "mutations are not allowed over GET requests")
+
+			return
+		}
+
+		offers := codecContentTypes()
+		if operation == ast.OperationTypeSubscription {
+			offers = []string{contentTypeEventStream}
+		}
+
+		if _, ok := acceptable(w, r, offers...); !ok {
+			return
+		}
+
+		schema, err := h.graphQLSchema(r.Context())
+		if err != nil {
+			writeError(w, r, err)
+
+			return
+		}
+
+		params := graphql.Params{
+			Schema:         schema,
+			RequestString:  req.Query,
+			RootObject:     nil,
+			VariableValues: req.Variables,
+			OperationName:  req.OperationName,
+			Context:        r.Context(),
+		}
+
+		if operation == ast.OperationTypeSubscription {
+			serveGraphQLSubscription(w, params)
+
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, graphql.Do(params))
+	}
+}
+
+func graphQLRequestFrom(w http.ResponseWriter, r *http.Request) (GraphQLRequest, bool) {
+	var req GraphQLRequest
+
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+
+		if variables := q.Get("variables"); variables != "" {
+			err := json.Unmarshal([]byte(variables), &req.Variables)
+			if err != nil {
+				writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, "variables parameter is not valid",
+					InvalidParam{Name: "variables", Reason: err.Error()})
+
+				return req, false
+			}
+		}
+	} else {
+		err := decodeRequest(r, &req)
+		if err != nil {
+			writeDecodeProblem(w, r, err)
+
+			return req, false
+		}
+	}
+
+	if req.Query == "" {
+		writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, "query is required",
+			InvalidParam{Name: "query", Reason: "is required"})
+
+		return req, false
+	}
+
+	return req, true
+}
+
+// operationType returns the type of the operation the request executes, or an empty string if the query doesn't parse,
+// leaving the error to the executor.
+func operationType(req GraphQLRequest) string {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		return ""
+	}
+
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		if req.OperationName == "" || (op.Name != nil && op.Name.Value == req.OperationName) {
+			return op.Operation
+		}
+	}
+
+	return ""
+}
+
+// serveGraphQLSubscription streams results as server-sent events, in the distinct connections mode of the GraphQL over SSE protocol.
+func serveGraphQLSubscription(w http.ResponseWriter, params graphql.Params) {
+	flusher, _ := w.(http.Flusher)
+
+	w.Header().Set("Content-Type", contentTypeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	// results must be drained until the channel is closed, or the executor blocks
+	for res := range graphql.Subscribe(params) {
+		b, err := json.Marshal(res)
+		if err != nil {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(w, "event: next\ndata: %s\n\n", b)
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	_, _ = fmt.Fprint(w, "event: complete\ndata:\n\n")
+}
+
+// graphQLSchema returns the schema of the types in the repository and the types with schemas, built again only when
+// the types change.
+func (h *Handler) graphQLSchema(ctx context.Context) (graphql.Schema, error) {
+	types, err := h.itemRepo.ListTypes(ctx)
+	if err != nil {
+		return graphql.Schema{}, repositoryError("error on list types from the repository", err)
+	}
+
+	for _, schema := range h.listSchemas() {
+		if !containsString(types, schema.Type) {
+			types = append(types, schema.Type)
+		}
+	}
+
+	sort.Strings(types)
+
+	key := strings.Join(types, ",")
+
+	h.graphQLMu.Lock()
+	defer h.graphQLMu.Unlock()
+
+	if h.graphQLSchemaCache != nil && h.graphQLSchemaKey == key {
+		return *h.graphQLSchemaCache, nil
+	}
+
+	schema, err := h.newGraphQLSchema(types)
+	if err != nil {
+		return graphql.Schema{}, newProblemError(http.StatusInternalServerError, CodeInternalError, fmt.Sprintf("error on build graphql schema: %s", err.Error()))
+	}
+
+	h.graphQLSchemaCache = &schema
+	h.graphQLSchemaKey = key
+
+	return schema, nil
+}
+
+// graphQLTypes holds the shared types of one schema.
+type graphQLTypes struct {
+	json       *graphql.Scalar
+	item       *graphql.Object
+	connection *graphql.Object
+	event      *graphql.Object
+	filter     *graphql.InputObject
+	sort       *graphql.InputObject
+	owner      *graphql.InputObject
+}
+
+func newGraphQLTypes() graphQLTypes {
+	jsonScalar := graphql.NewScalar(graphql.ScalarConfig{
+		Name:         "JSON",
+		Description:  "Any JSON value.",
+		Serialize:    func(v interface{}) interface{} { return v },
+		ParseValue:   func(v interface{}) interface{} { return v },
+		ParseLiteral: parseJSONLiteral,
+	})
+
+	item := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Item",
+		Fields: graphql.Fields{
+			"uuid":            &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"type":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"name":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"data":            &graphql.Field{Type: jsonScalar},
+			"createdAt":       &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"updatedAt":       &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"status":          &graphql.Field{Type: jsonScalar},
+			"transitions":     &graphql.Field{Type: jsonScalar},
+			"labels":          &graphql.Field{Type: jsonScalar},
+			"annotations":     &graphql.Field{Type: jsonScalar},
+			"ownerReferences": &graphql.Field{Type: jsonScalar},
+			"finalizers":      &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+			"deletionTimestamp": &graphql.Field{
+				Type:        graphql.DateTime,
+				Description: "Set once the item is deleted, while its finalizers hold it.",
+			},
+			"managedFields": &graphql.Field{
+				Type:        jsonScalar,
+				Description: "Field managers by dotted paths of the data fields they wrote.",
+			},
+		},
+	})
+
+	pageInfo := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"endCursor":   &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	connection := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ItemConnection",
+		Fields: graphql.Fields{
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"items":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(item)))},
+			"pageInfo":   &graphql.Field{Type: graphql.NewNonNull(pageInfo)},
+		},
+	})
+
+	event := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ItemEvent",
+		Fields: graphql.Fields{
+			"type": &graphql.Field{Type: graphql.NewNonNull(graphql.NewEnum(graphql.EnumConfig{
+				Name: "EventType",
+				Values: graphql.EnumValueConfigMap{
+					string(repository.EventAdded):    &graphql.EnumValueConfig{Value: string(repository.EventAdded)},
+					string(repository.EventModified): &graphql.EnumValueConfig{Value: string(repository.EventModified)},
+					string(repository.EventDeleted):  &graphql.EnumValueConfig{Value: string(repository.EventDeleted)},
+				},
+			}))},
+			"item": &graphql.Field{Type: graphql.NewNonNull(item)},
+		},
+	})
+
+	filterOps := graphql.EnumValueConfigMap{}
+	for _, op := range []string{FilterOpEq, FilterOpNe, FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte, FilterOpContains, FilterOpExists} {
+		filterOps[op] = &graphql.EnumValueConfig{Value: op}
+	}
+
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "ItemFilter",
+		Description: "Matches items whose field compares to value. Field is a metadata field or a dotted path into data.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"op":    &graphql.InputObjectFieldConfig{Type: graphql.NewEnum(graphql.EnumConfig{Name: "FilterOperator", Values: filterOps}), DefaultValue: FilterOpEq},
+			"value": &graphql.InputObjectFieldConfig{Type: jsonScalar},
+		},
+	})
+
+	sort := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ItemSort",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"direction": &graphql.InputObjectFieldConfig{Type: graphql.NewEnum(graphql.EnumConfig{
+				Name: "SortDirection",
+				Values: graphql.EnumValueConfigMap{
+					"ASC":  &graphql.EnumValueConfig{Value: "ASC"},
+					"DESC": &graphql.EnumValueConfig{Value: "DESC"},
+				},
+			}), DefaultValue: "ASC"},
+		},
+	})
+
+	owner := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "OwnerReferenceInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"type": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"name": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"uuid": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.ID)},
+		},
+	})
+
+	return graphQLTypes{
+		json:       jsonScalar,
+		item:       item,
+		connection: connection,
+		event:      event,
+		filter:     filter,
+		sort:       sort,
+		owner:      owner,
+	}
+}
+
+// newGraphQLSchema builds a schema with generic fields taking the type as an argument, and fields of each known type.
+// Fields of types whose names clash with other fields are left out; the generic fields still serve them.
+func (h *Handler) newGraphQLSchema(types []string) (graphql.Schema, error) {
+	t := newGraphQLTypes()
+
+	query := graphql.Fields{}
+	mutation := graphql.Fields{}
+	subscription := graphql.Fields{}
+
+	h.addGraphQLFields(t, query, mutation, subscription, "item", "items", "Item", "")
+
+	pc := pluralize.NewClient()
+
+	for _, typ := range types {
+		single := graphQLName(typ)
+
+		plural := graphQLName(pc.Plural(typ))
+		if plural == single {
+			plural = single + "List"
+		}
+
+		h.addGraphQLFields(t, query, mutation, subscription, single, plural, strings.ToUpper(single[:1])+single[1:], typ)
+	}
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:        graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: query}),
+		Mutation:     graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: mutation}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{Name: "Subscription", Fields: subscription}),
+	})
+	if err != nil {
+		return graphql.Schema{}, errors.Wrap(err, "error on create schema")
+	}
+
+	return schema, nil
+}
+
+// addGraphQLFields adds the fields of a type. An empty type adds the generic fields, which take the type as an argument.
+func (h *Handler) addGraphQLFields(t graphQLTypes, query, mutation, subscription graphql.Fields, single, plural, title, typ string) {
+	typeArgs := func(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		if typ == "" {
+			args["type"] = &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}
+		}
+
+		return args
+	}
+
+	typeOf := func(p graphql.ResolveParams) (string, error) {
+		if typ != "" {
+			return typ, nil
+		}
+
+		s, _ := p.Args["type"].(string)
+
+		return s, checkType(s)
+	}
+
+	nameArg := func() *graphql.ArgumentConfig {
+		return &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}
+	}
+
+	writeArgs := func(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		args["fieldManager"] = &graphql.ArgumentConfig{Type: graphql.String}
+		args["force"] = &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false}
+
+		return typeArgs(args)
+	}
+
+	metadataArgs := func(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		args["labels"] = &graphql.ArgumentConfig{Type: t.json}
+		args["annotations"] = &graphql.ArgumentConfig{Type: t.json}
+		args["ownerReferences"] = &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(t.owner))}
+		args["finalizers"] = &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))}
+
+		return writeArgs(args)
+	}
+
+	fields := []struct {
+		root  graphql.Fields
+		name  string
+		field *graphql.Field
+	}{
+		{root: query, name: single, field: &graphql.Field{
+			Type: t.item,
+			Args: typeArgs(graphql.FieldConfigArgument{"name": nameArg()}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				typ, err := typeOf(p)
+				if err != nil {
+					return nil, err
+				}
+
+				item, err := h.findItem(p.Context, typ, p.Args["name"].(string))
+				if err != nil {
+					var pe *problemError
+					if errors.As(err, &pe) && pe.code == CodeItemNotFound {
+						return nil, nil
+					}
+
+					return nil, err
+				}
+
+				return item, nil
+			},
+		}},
+		{root: query, name: plural, field: &graphql.Field{
+			Type: graphql.NewNonNull(t.connection),
+			Args: typeArgs(graphql.FieldConfigArgument{
+				"filter": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(t.filter))},
+				"sort":   &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(t.sort))},
+				"first":  &graphql.ArgumentConfig{Type: graphql.Int},
+				"after":  &graphql.ArgumentConfig{Type: graphql.String},
+			}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				typ, err := typeOf(p)
+				if err != nil {
+					return nil, err
+				}
+
+				return h.resolveItemConnection(p, typ)
+			},
+		}},
+		{root: mutation, name: "create" + title, field: &graphql.Field{
+			Type: graphql.NewNonNull(t.item),
+			Args: metadataArgs(graphql.FieldConfigArgument{"name": nameArg(), "data": &graphql.ArgumentConfig{Type: t.json}}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				typ, err := typeOf(p)
+				if err != nil {
+					return nil, err
+				}
+
+				data, err := dataArg(p.Args["data"])
+				if err != nil {
+					return nil, err
+				}
+
+				meta, err := metadataArg(p.Args)
+				if err != nil {
+					return nil, err
+				}
+
+				return h.createItem(p.Context, typ, p.Args["name"].(string), data, meta, writerOf(p))
+			},
+		}},
+		{root: mutation, name: "replace" + title, field: &graphql.Field{
+			Type: graphql.NewNonNull(t.item),
+			Args: metadataArgs(graphql.FieldConfigArgument{"name": nameArg(), "data": &graphql.ArgumentConfig{Type: t.json}}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				typ, err := typeOf(p)
+				if err != nil {
+					return nil, err
+				}
+
+				data, err := dataArg(p.Args["data"])
+				if err != nil {
+					return nil, err
+				}
+
+				meta, err := metadataArg(p.Args)
+				if err != nil {
+					return nil, err
+				}
+
+				return h.replaceItem(p.Context, typ, p.Args["name"].(string), data, meta, writerOf(p))
+			},
+		}},
+		{root: mutation, name: "patch" + title, field: &graphql.Field{
+			Type:        graphql.NewNonNull(t.item),
+			Description: "Applies a JSON merge patch if patch is an object, or a JSON patch if it is a list.",
+			Args:        writeArgs(graphql.FieldConfigArgument{"name": nameArg(), "patch": &graphql.ArgumentConfig{Type: graphql.NewNonNull(t.json)}}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				typ, err := typeOf(p)
+				if err != nil {
+					return nil, err
+				}
+
+				patchType := contentTypeMergePatch
+				if _, ok := p.Args["patch"].([]interface{}); ok {
+					patchType = contentTypeJSONPatch
+				}
+
+				patch, err := json.Marshal(p.Args["patch"])
+				if err != nil {
+					return nil, newProblemError(http.StatusBadRequest, CodePatchInvalid, fmt.Sprintf("error on marshal patch: %s", err.Error()))
+				}
+
+				return h.patchItem(p.Context, typ, p.Args["name"].(string), patchType, patch, writerOf(p))
+			},
+		}},
+		{root: mutation, name: "delete" + title, field: &graphql.Field{
+			Type: graphql.NewNonNull(t.item),
+			Args: typeArgs(graphql.FieldConfigArgument{"name": nameArg()}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				typ, err := typeOf(p)
+				if err != nil {
+					return nil, err
+				}
+
+				return h.deleteItem(p.Context, typ, p.Args["name"].(string), PropagationBackground)
+			},
+		}},
+		{root: subscription, name: single + "Events", field: &graphql.Field{
+			Type: graphql.NewNonNull(t.event),
+			Args: func() graphql.FieldConfigArgument {
+				if typ == "" {
+					return graphql.FieldConfigArgument{"type": &graphql.ArgumentConfig{Type: graphql.String, Description: "Watches all types if not set."}}
+				}
+
+				return graphql.FieldConfigArgument{}
+			}(),
+			Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+				watchType := typ
+				if typ == "" {
+					watchType, _ = p.Args["type"].(string)
+				}
+
+				return h.subscribeItemEvents(p.Context, watchType)
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source, nil
+			},
+		}},
+	}
+
+	for _, f := range fields {
+		if _, ok := f.root[f.name]; ok {
+			continue
+		}
+
+		f.root[f.name] = f.field
+	}
+}
+
+func (h *Handler) resolveItemConnection(p graphql.ResolveParams, typ string) (interface{}, error) {
+	filters, sorts, err := listArgs(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	q := itemQuery{selector: nil, filters: filters, sorts: sorts, offset: 0, limit: -1}
+
+	if after, ok := p.Args["after"].(string); ok {
+		q.offset, err = decodeCursor(after)
+		if err != nil {
+			return nil, newProblemError(http.StatusBadRequest, CodeParamInvalid, "after argument is not valid",
+				InvalidParam{Name: "after", Reason: err.Error()})
+		}
+	}
+
+	if first, ok := p.Args["first"].(int); ok {
+		if first < 0 {
+			return nil, newProblemError(http.StatusBadRequest, CodeParamInvalid, "first argument is not valid",
+				InvalidParam{Name: "first", Reason: "should not be negative"})
+		}
+
+		q.limit = first
+	}
+
+	page, err := h.listItems(p.Context, typ, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var endCursor interface{}
+	if page.endCursor != "" {
+		endCursor = page.endCursor
+	}
+
+	return map[string]interface{}{
+		"totalCount": page.total,
+		"items":      page.items,
+		"pageInfo": map[string]interface{}{
+			"hasNextPage": page.hasNext,
+			"endCursor":   endCursor,
+		},
+	}, nil
+}
+
+func listArgs(args map[string]interface{}) ([]itemFilter, []itemSort, error) {
+	filterArgs, _ := args["filter"].([]interface{})
+	filters := make([]itemFilter, 0, len(filterArgs))
+
+	for _, arg := range filterArgs {
+		m, _ := arg.(map[string]interface{})
+
+		f := itemFilter{field: fmt.Sprint(m["field"]), op: fmt.Sprint(m["op"]), value: m["value"]}
+
+		err := f.check()
+		if err != nil {
+			return nil, nil, newProblemError(http.StatusBadRequest, CodeParamInvalid, "filter argument is not valid",
+				InvalidParam{Name: "filter", Reason: err.Error()})
+		}
+
+		filters = append(filters, f)
+	}
+
+	sortArgs, _ := args["sort"].([]interface{})
+	sorts := make([]itemSort, 0, len(sortArgs))
+
+	for _, arg := range sortArgs {
+		m, _ := arg.(map[string]interface{})
+
+		sorts = append(sorts, itemSort{field: fmt.Sprint(m["field"]), desc: m["direction"] == "DESC"})
+	}
+
+	return filters, sorts, nil
+}
+
+func (h *Handler) subscribeItemEvents(ctx context.Context, typ string) (interface{}, error) {
+	events, err := h.watchItems(ctx, typ)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(chan interface{})
+
+	go func() {
+		defer close(res)
+
+		for event := range events {
+			select {
+			case res <- map[string]interface{}{"type": string(event.Type), "item": event.Item}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return res, nil
+}
+
+// writerOf returns the field writer of the fieldManager and force arguments of a mutation.
+func writerOf(p graphql.ResolveParams) fieldWriter {
+	manager, _ := p.Args["fieldManager"].(string)
+	force, _ := p.Args["force"].(bool)
+
+	return fieldWriter{manager: manager, force: force}
+}
+
+// dataArg checks the data argument is an object.
+func dataArg(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return make(map[string]interface{}), nil
+	}
+
+	data, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, newProblemError(http.StatusBadRequest, CodeBodyInvalid, "data argument should be an object",
+			InvalidParam{Name: "data", Reason: "should be an object"})
+	}
+
+	return data, checkData(data)
+}
+
+// metadataArg reads the labels, annotations, ownerReferences and finalizers arguments of a mutation. Missing arguments
+// keep the metadata items have.
+func metadataArg(args map[string]interface{}) (itemMetadata, error) {
+	res := itemMetadata{labels: nil, annotations: nil, ownerReferences: nil, finalizers: nil}
+
+	var err error
+
+	res.labels, err = stringsArg(args, "labels")
+	if err != nil {
+		return res, err
+	}
+
+	res.annotations, err = stringsArg(args, "annotations")
+	if err != nil {
+		return res, err
+	}
+
+	if owners, ok := args["ownerReferences"].([]interface{}); ok {
+		res.ownerReferences = make([]core.OwnerReference, 0, len(owners))
+
+		for _, owner := range owners {
+			m, _ := owner.(map[string]interface{})
+			typ, _ := m["type"].(string)
+			name, _ := m["name"].(string)
+			ownerUUID, _ := m["uuid"].(string)
+
+			res.ownerReferences = append(res.ownerReferences, core.OwnerReference{Type: typ, Name: name, UUID: ownerUUID})
+		}
+	}
+
+	if finalizers, ok := args["finalizers"].([]interface{}); ok {
+		res.finalizers = make([]string, 0, len(finalizers))
+
+		for _, finalizer := range finalizers {
+			s, _ := finalizer.(string)
+
+			res.finalizers = append(res.finalizers, s)
+		}
+	}
+
+	return res, nil
+}
+
+// stringsArg reads an argument that is an object of strings, which is nil if it's missing.
+func stringsArg(args map[string]interface{}, name string) (map[string]string, error) {
+	if args[name] == nil {
+		return nil, nil
+	}
+
+	obj, ok := args[name].(map[string]interface{})
+	if !ok {
+		return nil, newProblemError(http.StatusBadRequest, CodeBodyInvalid, fmt.Sprintf("%s argument should be an object", name),
+			InvalidParam{Name: name, Reason: "should be an object"})
+	}
+
+	res := make(map[string]string, len(obj))
+
+	for k, v := range obj {
+		s, ok := v.(string)
+		if !ok {
+			return nil, newProblemError(http.StatusBadRequest, CodeBodyInvalid, fmt.Sprintf("value of '%s' in %s argument should be a string", k, name),
+				InvalidParam{Name: name + "." + k, Reason: "should be a string"})
+		}
+
+		res[k] = s
+	}
+
+	return res, nil
+}
+
+func parseJSONLiteral(value ast.Value) interface{} {
+	switch value := value.(type) {
+	case *ast.StringValue:
+		return value.Value
+	case *ast.BooleanValue:
+		return value.Value
+	case *ast.EnumValue:
+		return value.Value
+	case *ast.IntValue:
+		f, _ := strconv.ParseFloat(value.Value, 64)
+
+		return f
+	case *ast.FloatValue:
+		f, _ := strconv.ParseFloat(value.Value, 64)
+
+		return f
+	case *ast.ListValue:
+		res := make([]interface{}, len(value.Values))
+
+		for i := range value.Values {
+			res[i] = parseJSONLiteral(value.Values[i])
+		}
+
+		return res
+	case *ast.ObjectValue:
+		res := make(map[string]interface{}, len(value.Fields))
+
+		for _, field := range value.Fields {
+			res[field.Name.Value] = parseJSONLiteral(field.Value)
+		}
+
+		return res
+	default:
+		return nil
+	}
+}
+
+// graphQLName turns a kebab-case type into a camelCase name.
+func graphQLName(typ string) string {
+	parts := strings.Split(typ, "-")
+
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+
+	return strings.Join(parts, "")
+}
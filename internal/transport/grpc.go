@@ -0,0 +1,399 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+
+	corev1 "github.com/nasermirzaei89/core/api/core/v1"
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/nasermirzaei89/core/internal/repository"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const grpcErrorDomain = "core"
+
+// grpcServer serves the item service with the same operations as the REST handlers.
+type grpcServer struct {
+	corev1.UnimplementedItemServiceServer
+
+	h *Handler
+}
+
+// RegisterGRPC registers the item service of the handler on a gRPC server.
+func (h *Handler) RegisterGRPC(s grpc.ServiceRegistrar) {
+	corev1.RegisterItemServiceServer(s, &grpcServer{UnimplementedItemServiceServer: corev1.UnimplementedItemServiceServer{}, h: h})
+}
+
+func (s *grpcServer) CreateItem(ctx context.Context, req *corev1.CreateItemRequest) (*corev1.Item, error) {
+	err := checkType(req.GetType())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	data := req.GetData().AsMap()
+
+	err = checkData(data)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	meta := metadataFromProto(req.GetLabels(), req.GetAnnotations(), req.GetOwnerReferences(), req.GetFinalizers())
+
+	item, err := s.h.createItem(ctx, req.GetType(), req.GetName(), data, meta, fieldWriter{manager: "", force: false})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return itemToProto(item)
+}
+
+func (s *grpcServer) GetItem(ctx context.Context, req *corev1.GetItemRequest) (*corev1.Item, error) {
+	err := checkTypeAndName(req.GetType(), req.GetName())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	item, err := s.h.findItem(ctx, req.GetType(), req.GetName())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return itemToProto(item)
+}
+
+func (s *grpcServer) ListItems(ctx context.Context, req *corev1.ListItemsRequest) (*corev1.ListItemsResponse, error) {
+	err := checkType(req.GetType())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	sel, err := parseLabelSelector(req.GetLabelSelector())
+	if err != nil {
+		return nil, grpcError(newProblemError(http.StatusBadRequest, CodeParamInvalid, "label_selector field is not valid",
+			InvalidParam{Name: "label_selector", Reason: err.Error()}))
+	}
+
+	q := itemQuery{
+		selector: sel,
+		filters:  make([]itemFilter, 0, len(req.GetFilters())),
+		sorts:    make([]itemSort, 0, len(req.GetSort())),
+		offset:   0,
+		limit:    int(req.GetPageSize()),
+	}
+
+	for _, f := range req.GetFilters() {
+		filter := itemFilter{field: f.GetField(), op: f.GetOp(), value: nil}
+
+		if filter.op == "" {
+			filter.op = FilterOpEq
+		}
+
+		if f.GetValue() != nil {
+			filter.value = f.GetValue().AsInterface()
+		}
+
+		err = filter.check()
+		if err != nil {
+			return nil, grpcError(newProblemError(http.StatusBadRequest, CodeParamInvalid, "filters field is not valid",
+				InvalidParam{Name: "filters", Reason: err.Error()}))
+		}
+
+		q.filters = append(q.filters, filter)
+	}
+
+	for _, sort := range req.GetSort() {
+		q.sorts = append(q.sorts, itemSort{field: sort.GetField(), desc: sort.GetDesc()})
+	}
+
+	switch {
+	case q.limit < 0:
+		return nil, grpcError(newProblemError(http.StatusBadRequest, CodeParamInvalid, "page_size field is not valid",
+			InvalidParam{Name: "page_size", Reason: "should not be negative"}))
+	case q.limit == 0:
+		q.limit = -1
+	}
+
+	if req.GetPageToken() != "" {
+		q.offset, err = decodeCursor(req.GetPageToken())
+		if err != nil {
+			return nil, grpcError(newProblemError(http.StatusBadRequest, CodeParamInvalid, "page_token field is not valid",
+				InvalidParam{Name: "page_token", Reason: err.Error()}))
+		}
+	}
+
+	page, err := s.h.listItems(ctx, req.GetType(), q)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	res := corev1.ListItemsResponse{
+		Items:         make([]*corev1.Item, 0, len(page.items)),
+		TotalCount:    int32(page.total),
+		NextPageToken: "",
+	}
+
+	if page.hasNext {
+		res.NextPageToken = page.endCursor
+	}
+
+	for i := range page.items {
+		item, err := itemToProto(&page.items[i])
+		if err != nil {
+			return nil, err
+		}
+
+		res.Items = append(res.Items, item)
+	}
+
+	return &res, nil
+}
+
+func (s *grpcServer) ReplaceItem(ctx context.Context, req *corev1.ReplaceItemRequest) (*corev1.Item, error) {
+	err := checkTypeAndName(req.GetType(), req.GetName())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	data := req.GetData().AsMap()
+
+	err = checkData(data)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	meta := metadataFromProto(req.GetLabels(), req.GetAnnotations(), req.GetOwnerReferences(), req.GetFinalizers())
+
+	item, err := s.h.replaceItem(ctx, req.GetType(), req.GetName(), data, meta, fieldWriter{manager: "", force: false})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return itemToProto(item)
+}
+
+func (s *grpcServer) PatchItem(ctx context.Context, req *corev1.PatchItemRequest) (*corev1.Item, error) {
+	err := checkTypeAndName(req.GetType(), req.GetName())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	var (
+		patchType string
+		patch     []byte
+	)
+
+	switch p := req.GetPatch().(type) {
+	case *corev1.PatchItemRequest_MergePatch:
+		patchType = contentTypeMergePatch
+
+		patch, err = p.MergePatch.MarshalJSON()
+		if err != nil {
+			return nil, grpcError(newProblemError(http.StatusBadRequest, CodePatchInvalid, "error on marshal merge patch: "+err.Error()))
+		}
+	case *corev1.PatchItemRequest_JsonPatch:
+		patchType = contentTypeJSONPatch
+		patch = []byte(p.JsonPatch)
+	default:
+		return nil, grpcError(newProblemError(http.StatusBadRequest, CodePatchInvalid, "merge_patch or json_patch field is required",
+			InvalidParam{Name: "patch", Reason: "is required"}))
+	}
+
+	item, err := s.h.patchItem(ctx, req.GetType(), req.GetName(), patchType, patch, fieldWriter{manager: "", force: false})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return itemToProto(item)
+}
+
+func (s *grpcServer) DeleteItem(ctx context.Context, req *corev1.DeleteItemRequest) (*corev1.Item, error) {
+	err := checkTypeAndName(req.GetType(), req.GetName())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	item, err := s.h.deleteItem(ctx, req.GetType(), req.GetName(), PropagationBackground)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return itemToProto(item)
+}
+
+func (s *grpcServer) WatchItems(req *corev1.WatchItemsRequest, stream corev1.ItemService_WatchItemsServer) error {
+	ctx := stream.Context()
+
+	events, err := s.h.watchItems(ctx, req.GetType())
+	if err != nil {
+		return grpcError(err)
+	}
+
+	for event := range events {
+		item, err := itemToProto(&event.Item)
+		if err != nil {
+			return err
+		}
+
+		err = stream.Send(&corev1.Event{Type: eventTypeToProto(event.Type), Item: item})
+		if err != nil {
+			return errors.Wrap(err, "error on send event")
+		}
+	}
+
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+
+	return status.Error(codes.Aborted, "watch closed because it fell behind the changes")
+}
+
+func checkTypeAndName(typ, name string) error {
+	err := checkType(typ)
+	if err != nil {
+		return err
+	}
+
+	return checkName(name)
+}
+
+// metadataFromProto returns metadata of request fields. Empty fields are nil, so they keep the metadata items have.
+func metadataFromProto(labels, annotations map[string]string, owners []*corev1.OwnerReference, finalizers []string) itemMetadata {
+	res := itemMetadata{labels: nil, annotations: nil, ownerReferences: nil, finalizers: nil}
+
+	if len(labels) != 0 {
+		res.labels = labels
+	}
+
+	if len(annotations) != 0 {
+		res.annotations = annotations
+	}
+
+	for _, owner := range owners {
+		res.ownerReferences = append(res.ownerReferences, core.OwnerReference{Type: owner.GetType(), Name: owner.GetName(), UUID: owner.GetUuid()})
+	}
+
+	if len(finalizers) != 0 {
+		res.finalizers = finalizers
+	}
+
+	return res
+}
+
+func itemToProto(item *core.Item) (*corev1.Item, error) {
+	data, err := structpb.NewStruct(item.Data)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error on convert data of item: %s", err.Error())
+	}
+
+	res := corev1.Item{
+		Uuid:              item.UUID,
+		Type:              item.Type,
+		Name:              item.Name,
+		Data:              data,
+		CreatedAt:         timestamppb.New(item.CreatedAt),
+		UpdatedAt:         timestamppb.New(item.UpdatedAt),
+		Labels:            item.Labels,
+		Annotations:       item.Annotations,
+		OwnerReferences:   make([]*corev1.OwnerReference, 0, len(item.OwnerReferences)),
+		Finalizers:        item.Finalizers,
+		DeletionTimestamp: nil,
+		Status:            nil,
+	}
+
+	for _, owner := range item.OwnerReferences {
+		res.OwnerReferences = append(res.OwnerReferences, &corev1.OwnerReference{Type: owner.Type, Name: owner.Name, Uuid: owner.UUID})
+	}
+
+	if item.DeletionTimestamp != nil {
+		res.DeletionTimestamp = timestamppb.New(*item.DeletionTimestamp)
+	}
+
+	if item.Status != nil {
+		res.Status, err = structpb.NewStruct(item.Status)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "error on convert status of item: %s", err.Error())
+		}
+	}
+
+	return &res, nil
+}
+
+func eventTypeToProto(typ repository.EventType) corev1.Event_Type {
+	switch typ {
+	case repository.EventAdded:
+		return corev1.Event_ADDED
+	case repository.EventModified:
+		return corev1.Event_MODIFIED
+	case repository.EventDeleted:
+		return corev1.Event_DELETED
+	default:
+		return corev1.Event_TYPE_UNSPECIFIED
+	}
+}
+
+// grpcError converts errors of item operations to statuses, with the problem code as an ErrorInfo reason
+// and invalid params as BadRequest field violations.
+func grpcError(err error) error {
+	var pe *problemError
+	if !errors.As(err, &pe) {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	st := status.New(grpcCode(pe), pe.detail)
+
+	info := &errdetails.ErrorInfo{Reason: pe.code, Domain: grpcErrorDomain, Metadata: nil}
+
+	withDetails, detailsErr := st.WithDetails(info)
+
+	if len(pe.invalidParams) != 0 {
+		badRequest := &errdetails.BadRequest{FieldViolations: make([]*errdetails.BadRequest_FieldViolation, 0, len(pe.invalidParams))}
+
+		for _, param := range pe.invalidParams {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: param.Name, Description: param.Reason})
+		}
+
+		withDetails, detailsErr = st.WithDetails(info, badRequest)
+	}
+
+	if detailsErr != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
+
+// grpcCode returns the code of the problem. Conflicts are told apart by their problem codes, as only some of them mean
+// the item exists.
+func grpcCode(pe *problemError) codes.Code {
+	switch pe.status {
+	case http.StatusBadRequest, http.StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		switch pe.code {
+		case CodeItemAlreadyExists:
+			return codes.AlreadyExists
+		case CodeFieldConflict:
+			return codes.Aborted
+		default:
+			return codes.FailedPrecondition
+		}
+	case http.StatusUnprocessableEntity:
+		return codes.FailedPrecondition
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusBadGateway:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
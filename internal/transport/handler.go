@@ -2,18 +2,38 @@ package transport
 
 import (
 	"net/http"
+	"sync"
 
 	"github.com/gorilla/mux"
+	"github.com/graphql-go/graphql"
+	"github.com/nasermirzaei89/core/internal/core"
 	"github.com/nasermirzaei89/core/internal/repository"
 )
 
 type Handler struct {
 	router   *mux.Router
 	itemRepo repository.ItemRepository
+
+	graphQLMu          sync.Mutex
+	graphQLSchemaKey   string
+	graphQLSchemaCache *graphql.Schema
+
+	schemasMu sync.RWMutex
+	schemas   map[string]core.Schema
+	scripts   map[string]*compiledScript
+	rules     map[string][]compiledRule
+	workflows map[string]*compiledWorkflow
+
+	gc *garbageCollector
+
+	webhookClient *http.Client
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	h.router.ServeHTTP(w, r)
+	ctx := withActor(r.Context(), actorFromRequest(r))
+	ctx = withRoles(ctx, rolesFromRequest(r))
+
+	h.router.ServeHTTP(w, r.WithContext(ctx))
 }
 
 func New(itemRepo repository.ItemRepository) *Handler {
@@ -21,6 +41,12 @@ func New(itemRepo repository.ItemRepository) *Handler {
 
 	h.itemRepo = itemRepo
 	h.router = mux.NewRouter()
+	h.schemas = make(map[string]core.Schema)
+	h.scripts = make(map[string]*compiledScript)
+	h.rules = make(map[string][]compiledRule)
+	h.workflows = make(map[string]*compiledWorkflow)
+	h.gc = newGarbageCollector(h.collectDependents)
+	h.webhookClient = newWebhookClient()
 
 	h.registerRoutes()
 
@@ -3,431 +3,389 @@ package transport
 import (
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
-	"time"
+	"strconv"
 
-	jsonpatch "github.com/evanphx/json-patch"
-	"github.com/gertd/go-pluralize"
-	"github.com/google/uuid"
-	"github.com/gorilla/mux"
 	"github.com/nasermirzaei89/core/internal/core"
-	"github.com/nasermirzaei89/core/internal/repository"
 	"github.com/pkg/errors"
 )
 
-type HTTPError struct {
-	Message string `json:"message"`
-	Error   string `json:"error,omitempty"`
-}
-
 func (h *Handler) CreateItemHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		pc := pluralize.NewClient()
-
-		typePlural := mux.Vars(r)["typePlural"]
-
-		if !pc.IsPlural(typePlural) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "you should set plural form of the type"})
-
+		if _, ok := acceptable(w, r); !ok {
 			return
 		}
 
-		typ := pc.Singular(typePlural)
-
-		if !isValidType(typ) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("type field is not valid, it should an string that matches the regex '%s'", core.TypeRegex)})
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
 
+		fw, ok := writerFromRequest(w, r)
+		if !ok {
 			return
 		}
 
 		var req core.Item
 
-		err := json.NewDecoder(r.Body).Decode(&req)
+		err := decodeRequest(r, &req)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on decode request body", Error: err.Error()})
+			writeDecodeProblem(w, r, err)
 
 			return
 		}
 
-		if req.Name == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "name field is required"})
+		item, err := h.createItem(r.Context(), typ, req.Name, req.Data, metadataOf(req), fw)
+		if err != nil {
+			writeError(w, r, err)
 
 			return
 		}
 
-		if !isValidName(req.Name) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("name field is not valid, it should an string that matches the regex '%s'", core.NameRegex)})
-
-			return
-		}
+		writeResponse(w, r, http.StatusCreated, item)
+	}
+}
 
-		_, err = h.itemRepo.GetByTypeAndName(r.Context(), typ, req.Name)
+func (h *Handler) ListItemsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		watch, err := boolParam(r, "watch")
 		if err != nil {
-			if !errors.Is(err, repository.ErrItemNotFound) {
-				w.WriteHeader(http.StatusInternalServerError)
-				_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on find item by type and name from the repository", Error: err.Error()})
-
-				return
-			}
-		} else {
-			w.WriteHeader(http.StatusConflict)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("%s with name '%s' already exists", typ, req.Name)})
+			writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, "watch parameter is not valid",
+				InvalidParam{Name: "watch", Reason: "should be a boolean"})
 
 			return
 		}
 
-		now := time.Now()
+		offers := append(codecContentTypes(), contentTypeCSV)
+		if watch {
+			offers = []string{contentTypeNDJSON}
+		}
 
-		item := core.Item{
-			UUID:      uuid.NewString(),
-			Type:      typ,
-			Name:      req.Name,
-			Data:      req.Data,
-			CreatedAt: now,
-			UpdatedAt: now,
+		contentType, ok := acceptable(w, r, offers...)
+		if !ok {
+			return
 		}
 
-		err = h.itemRepo.Insert(r.Context(), item)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on insert item to the repository", Error: err.Error()})
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
 
+		sel, ok := selectorFromRequest(w, r)
+		if !ok {
 			return
 		}
 
-		w.WriteHeader(http.StatusCreated)
-		_ = json.NewEncoder(w).Encode(item)
-	}
-}
+		if watch {
+			h.streamEvents(w, r, typ, sel)
 
-func (h *Handler) ListItemsHandler() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		pc := pluralize.NewClient()
+			return
+		}
 
-		typePlural := mux.Vars(r)["typePlural"]
+		q, ok := listQueryFromRequest(w, r)
+		if !ok {
+			return
+		}
 
-		if !pc.IsPlural(typePlural) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "you should set plural form of the type"})
+		q.selector = sel
 
+		q.filters, ok = filtersFromRequest(w, r)
+		if !ok {
 			return
 		}
 
-		typ := pc.Singular(typePlural)
+		exps, ok := h.expandFromRequest(w, r, typ)
+		if !ok {
+			return
+		}
 
-		if !isValidType(typ) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("type field is not valid, it should an string that matches the regex '%s'", core.TypeRegex)})
+		page, err := h.listItems(r.Context(), typ, q)
+		if err != nil {
+			writeError(w, r, err)
 
 			return
 		}
 
-		items, err := h.itemRepo.ListByType(r.Context(), typ)
+		err = h.expandItems(r.Context(), page.items, exps)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on list items by type from the repository", Error: err.Error()})
+			writeError(w, r, err)
+
+			return
+		}
+
+		if contentType == contentTypeCSV {
+			w.Header().Set("Content-Type", contentTypeCSV)
+			_ = writeItemsCSV(w, page.items, r.URL.Query().Get("fields"))
 
 			return
 		}
 
-		rsp := core.ItemList{Items: items}
+		rsp := core.ItemList{Items: page.items}
+
+		if page.hasNext {
+			rsp.Next = page.endCursor
+		}
 
-		_ = json.NewEncoder(w).Encode(rsp)
+		writeResponse(w, r, http.StatusOK, rsp)
 	}
 }
 
-func (h *Handler) ReadItemHandler() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		pc := pluralize.NewClient()
+// listQueryFromRequest reads the limit and cursor parameters, writing a problem if they're not valid.
+func listQueryFromRequest(w http.ResponseWriter, r *http.Request) (itemQuery, bool) {
+	q := itemQuery{selector: nil, filters: nil, sorts: nil, offset: 0, limit: -1}
 
-		typePlural := mux.Vars(r)["typePlural"]
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 {
+			writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, "limit parameter is not valid",
+				InvalidParam{Name: "limit", Reason: "should be a positive integer"})
 
-		if !pc.IsPlural(typePlural) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "you should set plural form of the type"})
-
-			return
+			return q, false
 		}
 
-		typ := pc.Singular(typePlural)
+		q.limit = limit
+	}
 
-		if !isValidType(typ) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("type parameter is not valid, it should an string that matches the regex '%s'", core.TypeRegex)})
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		offset, err := decodeCursor(s)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, "cursor parameter is not valid",
+				InvalidParam{Name: "cursor", Reason: err.Error()})
 
-			return
+			return q, false
 		}
 
-		name := mux.Vars(r)["name"]
+		q.offset = offset
+	}
+
+	return q, true
+}
 
-		if !isValidName(name) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("name parameter is not valid, it should an string that matches the regex '%s'", core.NameRegex)})
+// streamEvents streams changes to items of the type whose labels match the selector as NDJSON events until the client
+// goes away.
+func (h *Handler) streamEvents(w http.ResponseWriter, r *http.Request, typ string, sel labelSelector) {
+	events, err := h.watchItems(r.Context(), typ)
+	if err != nil {
+		writeError(w, r, err)
 
-			return
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+
+	w.Header().Set("Content-Type", contentTypeNDJSON)
+	w.WriteHeader(http.StatusOK)
+
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	enc := json.NewEncoder(w)
+
+	for event := range events {
+		if !sel.matches(event.Item.Labels) {
+			continue
 		}
 
-		item, err := h.itemRepo.GetByTypeAndName(r.Context(), typ, name)
+		err = enc.Encode(event)
 		if err != nil {
-			switch {
-			case errors.Is(err, repository.ErrItemNotFound):
-				w.WriteHeader(http.StatusNotFound)
-				_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("%s with name '%s' not found", typ, name)})
-			default:
-				w.WriteHeader(http.StatusInternalServerError)
-				_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on find item by type and name from the repository", Error: err.Error()})
-			}
-
 			return
 		}
 
-		_ = json.NewEncoder(w).Encode(item)
+		if flusher != nil {
+			flusher.Flush()
+		}
 	}
 }
 
-func (h *Handler) ReplaceItemHandler() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		pc := pluralize.NewClient()
+// boolFromRequest reads the boolean parameter, writing a problem if it's not valid.
+func boolFromRequest(w http.ResponseWriter, r *http.Request, name string) (bool, bool) {
+	res, err := boolParam(r, name)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, fmt.Sprintf("%s parameter is not valid", name),
+			InvalidParam{Name: name, Reason: "should be a boolean"})
 
-		typePlural := mux.Vars(r)["typePlural"]
+		return false, false
+	}
 
-		if !pc.IsPlural(typePlural) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "you should set plural form of the type"})
+	return res, true
+}
 
-			return
-		}
+func boolParam(r *http.Request, name string) (bool, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return false, nil
+	}
 
-		typ := pc.Singular(typePlural)
+	res, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, errors.Wrapf(err, "error on parse %s parameter", name)
+	}
 
-		if !isValidType(typ) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("type parameter is not valid, it should an string that matches the regex '%s'", core.TypeRegex)})
+	return res, nil
+}
 
+func (h *Handler) ReadItemHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
 			return
 		}
 
-		name := mux.Vars(r)["name"]
-
-		if !isValidName(name) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("name parameter is not valid, it should an string that matches the regex '%s'", core.NameRegex)})
-
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
 			return
 		}
 
-		var req core.Item
-
-		err := json.NewDecoder(r.Body).Decode(&req)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on decode request body", Error: err.Error()})
+		name, ok := nameFromRequest(w, r)
+		if !ok {
+			return
+		}
 
+		exps, ok := h.expandFromRequest(w, r, typ)
+		if !ok {
 			return
 		}
 
-		item, err := h.itemRepo.GetByTypeAndName(r.Context(), typ, name)
+		item, err := h.findItem(r.Context(), typ, name)
 		if err != nil {
-			if errors.Is(err, repository.ErrItemNotFound) {
-				w.WriteHeader(http.StatusNotFound)
-				_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("%s with name '%s' not found", typ, name)})
-			} else {
-				w.WriteHeader(http.StatusInternalServerError)
-				_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on find item by type and name from the repository", Error: err.Error()})
-			}
+			writeError(w, r, err)
 
 			return
 		}
 
-		item.Data = req.Data
-		item.UpdatedAt = time.Now()
+		items := []core.Item{*item}
 
-		err = h.itemRepo.Replace(r.Context(), item.UUID, *item)
+		err = h.expandItems(r.Context(), items, exps)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on replace item in the repository", Error: err.Error()})
+			writeError(w, r, err)
 
 			return
 		}
 
-		_ = json.NewEncoder(w).Encode(*item)
+		writeResponse(w, r, http.StatusOK, items[0])
 	}
 }
 
-func (h *Handler) PatchItemHandler() http.HandlerFunc {
+func (h *Handler) ReplaceItemHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		pc := pluralize.NewClient()
-
-		typePlural := mux.Vars(r)["typePlural"]
-
-		if !pc.IsPlural(typePlural) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "you should set plural form of the type"})
-
+		if _, ok := acceptable(w, r); !ok {
 			return
 		}
 
-		typ := pc.Singular(typePlural)
-
-		if !isValidType(typ) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("type parameter is not valid, it should an string that matches the regex '%s'", core.TypeRegex)})
-
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
 			return
 		}
 
-		name := mux.Vars(r)["name"]
-
-		if !isValidName(name) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("name parameter is not valid, it should an string that matches the regex '%s'", core.NameRegex)})
-
+		name, ok := nameFromRequest(w, r)
+		if !ok {
 			return
 		}
 
-		item, err := h.itemRepo.GetByTypeAndName(r.Context(), typ, name)
-		if err != nil {
-			if errors.Is(err, repository.ErrItemNotFound) {
-				w.WriteHeader(http.StatusNotFound)
-				_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("%s with name '%s' not found", typ, name)})
-			} else {
-				w.WriteHeader(http.StatusInternalServerError)
-				_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on find item by type and name from the repository", Error: err.Error()})
-			}
-
+		fw, ok := writerFromRequest(w, r)
+		if !ok {
 			return
 		}
 
-		originalBytes, err := json.Marshal(item)
+		var req core.Item
+
+		err := decodeRequest(r, &req)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on marshal original item", Error: err.Error()})
+			writeDecodeProblem(w, r, err)
 
 			return
 		}
 
-		requestBody, err := io.ReadAll(r.Body)
+		item, err := h.replaceItem(r.Context(), typ, name, req.Data, metadataOf(req), fw)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on read request body", Error: err.Error()})
+			writeError(w, r, err)
 
 			return
 		}
 
-		ctype := r.Header.Get("Content-Type")
-
-		var modifiedBytes []byte
+		writeResponse(w, r, http.StatusOK, item)
+	}
+}
 
-		switch ctype {
-		case "application/json-patch+json":
-			patch, err := jsonpatch.DecodePatch(requestBody)
-			if err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on decode json patch", Error: err.Error()})
+func (h *Handler) PatchItemHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
 
-				return
-			}
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
 
-			modifiedBytes, err = patch.Apply(originalBytes)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on apply json patch", Error: err.Error()})
+		name, ok := nameFromRequest(w, r)
+		if !ok {
+			return
+		}
 
-				return
-			}
-		case "application/merge-patch+json":
-			modifiedBytes, err = jsonpatch.MergePatch(originalBytes, requestBody)
-			if err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on apply merge patch", Error: err.Error()})
+		fw, ok := writerFromRequest(w, r)
+		if !ok {
+			return
+		}
 
-				return
-			}
-		default:
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "unsupported Content-Type header", Error: err.Error()})
+		requestBody, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeBodyInvalid, fmt.Sprintf("error on read request body: %s", err.Error()))
 
 			return
 		}
 
-		var modified core.Item
-
-		err = json.Unmarshal(modifiedBytes, &modified)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on unmarshal modified bytes", Error: err.Error()})
+		if isApplyPatch(r) {
+			h.applyPatchHandler(w, r, typ, name, requestBody, fw)
 
 			return
 		}
 
-		item.Data = modified.Data
-		item.UpdatedAt = time.Now()
+		patchType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
 
-		err = h.itemRepo.Replace(r.Context(), item.UUID, *item)
+		item, err := h.patchItem(r.Context(), typ, name, patchType, requestBody, fw)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on replace item in the repository", Error: err.Error()})
+			writeError(w, r, err)
 
 			return
 		}
 
-		_ = json.NewEncoder(w).Encode(*item)
+		writeResponse(w, r, http.StatusOK, item)
 	}
 }
 
 func (h *Handler) DeleteItemHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		pc := pluralize.NewClient()
-
-		typePlural := mux.Vars(r)["typePlural"]
-
-		if !pc.IsPlural(typePlural) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "you should set plural form of the type"})
-
+		if _, ok := acceptable(w, r); !ok {
 			return
 		}
 
-		typ := pc.Singular(typePlural)
-
-		if !isValidType(typ) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("type parameter is not valid, it should an string that matches the regex '%s'", core.TypeRegex)})
-
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
 			return
 		}
 
-		name := mux.Vars(r)["name"]
-
-		if !isValidName(name) {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("name parameter is not valid, it should an string that matches the regex '%s'", core.NameRegex)})
+		name, ok := nameFromRequest(w, r)
+		if !ok {
+			return
+		}
 
+		propagation, ok := propagationFromRequest(w, r)
+		if !ok {
 			return
 		}
 
-		item, err := h.itemRepo.GetByTypeAndName(r.Context(), typ, name)
+		item, err := h.deleteItem(r.Context(), typ, name, propagation)
 		if err != nil {
-			if errors.Is(err, repository.ErrItemNotFound) {
-				w.WriteHeader(http.StatusNotFound)
-				_ = json.NewEncoder(w).Encode(HTTPError{Message: fmt.Sprintf("%s with name '%s' not found", typ, name)})
-			} else {
-				w.WriteHeader(http.StatusInternalServerError)
-				_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on find item by type and name from the repository", Error: err.Error()})
-			}
+			writeError(w, r, err)
 
 			return
 		}
 
-		err = h.itemRepo.Delete(r.Context(), item.UUID)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			_ = json.NewEncoder(w).Encode(HTTPError{Message: "error on delete item from the repository", Error: err.Error()})
+		// items with finalizers are still there until their finalizers are removed
+		if item.DeletionTimestamp != nil {
+			writeResponse(w, r, http.StatusAccepted, item)
 
 			return
 		}
@@ -435,3 +393,13 @@ func (h *Handler) DeleteItemHandler() http.HandlerFunc {
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+func writeDecodeProblem(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errUnsupportedMediaType) {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, CodeMediaTypeUnsupported, err.Error())
+
+		return
+	}
+
+	writeProblem(w, r, http.StatusBadRequest, CodeBodyInvalid, fmt.Sprintf("error on decode request body: %s", err.Error()))
+}
@@ -0,0 +1,463 @@
+package transport
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/nasermirzaei89/core/internal/repository"
+	"github.com/pkg/errors"
+)
+
+const (
+	contentTypeJSONPatch  = "application/json-patch+json"
+	contentTypeMergePatch = "application/merge-patch+json"
+
+	cursorPrefix = "offset:"
+)
+
+// problemError is returned by item operations shared between the APIs, so each API can report it with the same code.
+type problemError struct {
+	status        int
+	code          string
+	detail        string
+	invalidParams []InvalidParam
+}
+
+func (err *problemError) Error() string {
+	return err.detail
+}
+
+func newProblemError(status int, code, detail string, invalidParams ...InvalidParam) *problemError {
+	return &problemError{status: status, code: code, detail: detail, invalidParams: invalidParams}
+}
+
+func repositoryError(detail string, err error) *problemError {
+	return newProblemError(http.StatusInternalServerError, CodeRepositoryError, fmt.Sprintf("%s: %s", detail, err.Error()))
+}
+
+func notFoundError(typ, name string) *problemError {
+	return newProblemError(http.StatusNotFound, CodeItemNotFound, fmt.Sprintf("%s with name '%s' not found", typ, name))
+}
+
+// writeError writes err as a problem, using its details if it is a problemError.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var pe *problemError
+	if errors.As(err, &pe) {
+		writeProblem(w, r, pe.status, pe.code, pe.detail, pe.invalidParams...)
+
+		return
+	}
+
+	writeProblem(w, r, http.StatusInternalServerError, CodeInternalError, err.Error())
+}
+
+func checkType(typ string) error {
+	if !isValidType(typ) {
+		return newProblemError(http.StatusBadRequest, CodeTypeInvalid, "type is not valid",
+			InvalidParam{Name: "type", Reason: fmt.Sprintf("should be an string that matches the regex '%s'", core.TypeRegex)})
+	}
+
+	return nil
+}
+
+func checkName(name string) error {
+	if name == "" {
+		return newProblemError(http.StatusBadRequest, CodeNameRequired, "name field is required",
+			InvalidParam{Name: "name", Reason: "is required"})
+	}
+
+	if !isValidName(name) {
+		return newProblemError(http.StatusBadRequest, CodeNameInvalid, "name field is not valid",
+			InvalidParam{Name: "name", Reason: fmt.Sprintf("should be an string that matches the regex '%s'", core.NameRegex)})
+	}
+
+	return nil
+}
+
+// checkData rejects data with metadata fields, as APIs that keep data apart from metadata can't represent them.
+func checkData(data map[string]interface{}) error {
+	for _, k := range core.ReservedFields() {
+		if _, ok := data[k]; ok {
+			return newProblemError(http.StatusBadRequest, CodeBodyInvalid, fmt.Sprintf("data should not have field '%s'", k),
+				InvalidParam{Name: "data", Reason: fmt.Sprintf("field '%s' is reserved", k)})
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) findItem(ctx context.Context, typ, name string) (*core.Item, error) {
+	item, err := h.itemRepo.GetByTypeAndName(ctx, typ, name)
+	if err != nil {
+		if errors.Is(err, repository.ErrItemNotFound) {
+			return nil, notFoundError(typ, name)
+		}
+
+		return nil, repositoryError("error on find item by type and name from the repository", err)
+	}
+
+	return item, nil
+}
+
+// itemQuery selects a page of the items matching filters. A negative limit selects all items after offset.
+type itemQuery struct {
+	selector labelSelector
+	filters  []itemFilter
+	sorts    []itemSort
+	offset   int
+	limit    int
+}
+
+type itemPage struct {
+	items     []core.Item
+	total     int
+	hasNext   bool
+	endCursor string
+}
+
+// listItems reads the items the selector selects to select a page, as data fields aren't indexed, so it takes time in
+// proportion to them.
+func (h *Handler) listItems(ctx context.Context, typ string, q itemQuery) (*itemPage, error) {
+	items := make([]core.Item, 0)
+
+	err := h.eachSelected(ctx, typ, q.selector, func(item core.Item) error {
+		items = append(items, item)
+
+		return nil
+	})
+	if err != nil {
+		return nil, repositoryError("error on list items by type from the repository", err)
+	}
+
+	items = filterItems(items, q.filters)
+	sortItems(items, q.sorts)
+
+	start := q.offset
+	if start > len(items) {
+		start = len(items)
+	}
+
+	end := len(items)
+	if q.limit >= 0 && start+q.limit < end {
+		end = start + q.limit
+	}
+
+	page := itemPage{
+		items:     items[start:end],
+		total:     len(items),
+		hasNext:   end < len(items),
+		endCursor: "",
+	}
+
+	if end > start {
+		page.endCursor = encodeCursor(end)
+	}
+
+	return &page, nil
+}
+
+func (h *Handler) createItem(ctx context.Context, typ, name string, data map[string]interface{}, meta itemMetadata, fw fieldWriter) (*core.Item, error) {
+	item := newItem(typ, name)
+
+	err := h.insertItem(ctx, &item, data, meta, fw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// insertItem checks the new item and inserts it, with data written by fw and metadata fields that meta has.
+func (h *Handler) insertItem(ctx context.Context, item *core.Item, data map[string]interface{}, meta itemMetadata, fw fieldWriter) error {
+	err := checkName(item.Name)
+	if err != nil {
+		return err
+	}
+
+	err = meta.check()
+	if err != nil {
+		return err
+	}
+
+	_, err = h.itemRepo.GetByTypeAndName(ctx, item.Type, item.Name)
+	if err != nil {
+		if !errors.Is(err, repository.ErrItemNotFound) {
+			return repositoryError("error on find item by type and name from the repository", err)
+		}
+	} else {
+		return newProblemError(http.StatusConflict, CodeItemAlreadyExists, fmt.Sprintf("%s with name '%s' already exists", item.Type, item.Name))
+	}
+
+	err = h.checkReferences(ctx, item.Type, data)
+	if err != nil {
+		return err
+	}
+
+	err = h.checkOwners(ctx, nil, meta.ownerReferences)
+	if err != nil {
+		return err
+	}
+
+	meta.set(item)
+
+	// there are no other managers yet, so this doesn't conflict
+	_ = fw.write(item, data)
+
+	err = h.admit(ctx, core.OperationCreate, nil, item)
+	if err != nil {
+		return err
+	}
+
+	err = h.itemRepo.Insert(ctx, *item)
+	if err != nil {
+		return repositoryError("error on insert item to the repository", err)
+	}
+
+	h.runAfterWrite(ctx, item)
+
+	return nil
+}
+
+func newItem(typ, name string) core.Item {
+	now := time.Now()
+
+	return core.Item{
+		UUID:              uuid.NewString(),
+		Type:              typ,
+		Name:              name,
+		Data:              nil,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		Status:            nil,
+		Transitions:       nil,
+		Labels:            nil,
+		Annotations:       nil,
+		OwnerReferences:   nil,
+		Finalizers:        nil,
+		DeletionTimestamp: nil,
+		LastApplied:       nil,
+		ManagedFields:     nil,
+	}
+}
+
+// replaceItem replaces data of the item, and its metadata fields that meta has.
+func (h *Handler) replaceItem(ctx context.Context, typ, name string, data map[string]interface{}, meta itemMetadata, fw fieldWriter) (*core.Item, error) {
+	err := meta.check()
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := h.findItem(ctx, typ, name)
+	if err != nil {
+		return nil, err
+	}
+
+	original := item.DeepCopy()
+
+	err = h.checkReferences(ctx, typ, data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.checkOwners(ctx, item.OwnerReferences, meta.ownerReferences)
+	if err != nil {
+		return nil, err
+	}
+
+	meta.set(item)
+
+	err = fw.write(item, data)
+	if err != nil {
+		return nil, err
+	}
+
+	item.UpdatedAt = time.Now()
+
+	err = h.saveItem(ctx, original, item)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// patchItem applies a json patch or a json merge patch to the json form of the item. Only changes to data and metadata
+// are kept.
+func (h *Handler) patchItem(ctx context.Context, typ, name, patchType string, patch []byte, fw fieldWriter) (*core.Item, error) {
+	item, err := h.findItem(ctx, typ, name)
+	if err != nil {
+		return nil, err
+	}
+
+	original := item.DeepCopy()
+
+	modified, err := patchItemJSON(item, patchType, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	err = metadataOf(*modified).check()
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.checkReferences(ctx, typ, modified.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.checkOwners(ctx, item.OwnerReferences, modified.OwnerReferences)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fw.write(item, modified.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Labels = modified.Labels
+	item.Annotations = modified.Annotations
+	item.OwnerReferences = modified.OwnerReferences
+	item.Finalizers = modified.Finalizers
+
+	item.UpdatedAt = time.Now()
+
+	err = h.saveItem(ctx, original, item)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// patchItemJSON returns the item after applying the patch to its json form.
+func patchItemJSON(item *core.Item, patchType string, patch []byte) (*core.Item, error) {
+	originalBytes, err := json.Marshal(item)
+	if err != nil {
+		return nil, newProblemError(http.StatusInternalServerError, CodeInternalError, fmt.Sprintf("error on marshal original item: %s", err.Error()))
+	}
+
+	var modifiedBytes []byte
+
+	switch patchType {
+	case contentTypeJSONPatch:
+		decoded, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, newProblemError(http.StatusBadRequest, CodePatchInvalid, fmt.Sprintf("error on decode json patch: %s", err.Error()))
+		}
+
+		modifiedBytes, err = decoded.Apply(originalBytes)
+		if err != nil {
+			return nil, newProblemError(http.StatusUnprocessableEntity, CodePatchFailed, fmt.Sprintf("error on apply json patch: %s", err.Error()))
+		}
+	case contentTypeMergePatch:
+		modifiedBytes, err = jsonpatch.MergePatch(originalBytes, patch)
+		if err != nil {
+			return nil, newProblemError(http.StatusBadRequest, CodePatchInvalid, fmt.Sprintf("error on apply merge patch: %s", err.Error()))
+		}
+	default:
+		return nil, newProblemError(http.StatusUnsupportedMediaType, CodeMediaTypeUnsupported,
+			fmt.Sprintf("unsupported Content-Type header, it should be '%s', '%s' or '%s'", contentTypeJSONPatch, contentTypeMergePatch, contentTypeApplyPatch))
+	}
+
+	var modified core.Item
+
+	err = json.Unmarshal(modifiedBytes, &modified)
+	if err != nil {
+		return nil, newProblemError(http.StatusUnprocessableEntity, CodePatchFailed, fmt.Sprintf("error on unmarshal modified item: %s", err.Error()))
+	}
+
+	return &modified, nil
+}
+
+// deleteItem deletes the item, and then its dependents in the background, or first with foreground propagation.
+// Foreground propagation deletes the dependents of items with finalizers too, before they're marked.
+func (h *Handler) deleteItem(ctx context.Context, typ, name, propagation string) (*core.Item, error) {
+	item, err := h.findItem(ctx, typ, name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.admit(ctx, core.OperationDelete, item, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if propagation == PropagationForeground {
+		err = h.deleteDependents(ctx, *item, make(map[string]struct{}))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// items with finalizers are only marked, and are deleted when the last finalizer is removed
+	if len(item.Finalizers) != 0 {
+		err = h.markDeleting(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+
+		return item, nil
+	}
+
+	err = h.deleteWithReferences(ctx, *item)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// encodeCursor returns an opaque cursor pointing after the first offset items of a list.
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	b, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.Wrap(err, "error on decode cursor")
+	}
+
+	if !strings.HasPrefix(string(b), cursorPrefix) {
+		return 0, errors.New("cursor is not valid")
+	}
+
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(b), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, errors.New("cursor is not valid")
+	}
+
+	return offset, nil
+}
+
+// watchItems watches items of the type, or of all types if it's empty.
+func (h *Handler) watchItems(ctx context.Context, typ string) (<-chan repository.Event, error) {
+	if typ != "" {
+		err := checkType(typ)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	watcher, ok := h.itemRepo.(repository.ItemWatcher)
+	if !ok {
+		return nil, newProblemError(http.StatusNotImplemented, CodeWatchUnsupported, "the repository doesn't support watching items")
+	}
+
+	events, err := watcher.Watch(ctx, typ)
+	if err != nil {
+		return nil, repositoryError("error on watch items in the repository", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,305 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/nasermirzaei89/core/internal/repository"
+	"github.com/pkg/errors"
+)
+
+// itemMetadata is metadata writers set next to data of items. Nil fields keep the metadata items have.
+type itemMetadata struct {
+	labels          map[string]string
+	annotations     map[string]string
+	ownerReferences []core.OwnerReference
+	finalizers      []string
+}
+
+func metadataOf(item core.Item) itemMetadata {
+	return itemMetadata{labels: item.Labels, annotations: item.Annotations, ownerReferences: item.OwnerReferences, finalizers: item.Finalizers}
+}
+
+func (m itemMetadata) check() error {
+	keys := make([]string, 0, len(m.labels))
+
+	for k := range m.labels {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if !isValidLabelKey(k) {
+			return newProblemError(http.StatusBadRequest, CodeLabelInvalid, fmt.Sprintf("label key '%s' is not valid", k),
+				InvalidParam{Name: "metadata.labels." + k, Reason: fmt.Sprintf("key should be a name of up to %d characters with an optional DNS subdomain prefix, that matches the regex '%s'", maxLabelNameLength, core.LabelKeyRegex)})
+		}
+
+		if !isValidLabelValue(m.labels[k]) {
+			return newProblemError(http.StatusBadRequest, CodeLabelInvalid, fmt.Sprintf("value of label '%s' is not valid", k),
+				InvalidParam{Name: "metadata.labels." + k, Reason: fmt.Sprintf("value should be up to %d characters that match the regex '%s'", maxLabelNameLength, core.LabelValueRegex)})
+		}
+	}
+
+	for i, owner := range m.ownerReferences {
+		param := fmt.Sprintf("metadata.ownerReferences.%d", i)
+
+		if !isValidType(owner.Type) {
+			return newProblemError(http.StatusBadRequest, CodeOwnerInvalid, fmt.Sprintf("type of owner reference %d is not valid", i),
+				InvalidParam{Name: param + ".type", Reason: fmt.Sprintf("should be an string that matches the regex '%s'", core.TypeRegex)})
+		}
+
+		if !isValidName(owner.Name) {
+			return newProblemError(http.StatusBadRequest, CodeOwnerInvalid, fmt.Sprintf("name of owner reference %d is not valid", i),
+				InvalidParam{Name: param + ".name", Reason: fmt.Sprintf("should be an string that matches the regex '%s'", core.NameRegex)})
+		}
+
+		if _, err := uuid.Parse(owner.UUID); err != nil {
+			return newProblemError(http.StatusBadRequest, CodeOwnerInvalid, fmt.Sprintf("uuid of owner reference %d is not valid", i),
+				InvalidParam{Name: param + ".uuid", Reason: "should be an uuid"})
+		}
+	}
+
+	for i, finalizer := range m.finalizers {
+		if !isValidLabelKey(finalizer) {
+			return newProblemError(http.StatusBadRequest, CodeFinalizerInvalid, fmt.Sprintf("finalizer '%s' is not valid", finalizer),
+				InvalidParam{Name: fmt.Sprintf("metadata.finalizers.%d", i), Reason: fmt.Sprintf("should be a name of up to %d characters with an optional DNS subdomain prefix, that matches the regex '%s'", maxLabelNameLength, core.LabelKeyRegex)})
+		}
+	}
+
+	return nil
+}
+
+func (m itemMetadata) set(item *core.Item) {
+	if m.labels != nil {
+		item.Labels = m.labels
+	}
+
+	if m.annotations != nil {
+		item.Annotations = m.annotations
+	}
+
+	if m.ownerReferences != nil {
+		item.OwnerReferences = m.ownerReferences
+	}
+
+	if m.finalizers != nil {
+		item.Finalizers = m.finalizers
+	}
+}
+
+type selectorOperator string
+
+const (
+	selectorEquals       selectorOperator = "="
+	selectorNotEquals    selectorOperator = "!="
+	selectorIn           selectorOperator = "in"
+	selectorNotIn        selectorOperator = "notin"
+	selectorExists       selectorOperator = "exists"
+	selectorDoesNotExist selectorOperator = "!"
+)
+
+var selectorSetRegex = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\(([^()]*)\)$`) //nolint:gochecknoglobals
+
+type labelRequirement struct {
+	key    string
+	op     selectorOperator
+	values []string
+}
+
+// labelSelector selects items whose labels match all of its requirements, like "env=prod,tier in (web,api),!deprecated".
+// An empty selector selects all items.
+type labelSelector []labelRequirement
+
+func selectorFromRequest(w http.ResponseWriter, r *http.Request) (labelSelector, bool) {
+	sel, err := parseLabelSelector(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, "labelSelector parameter is not valid",
+			InvalidParam{Name: "labelSelector", Reason: err.Error()})
+
+		return nil, false
+	}
+
+	return sel, true
+}
+
+func parseLabelSelector(s string) (labelSelector, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	parts, err := splitRequirements(s)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(labelSelector, 0, len(parts))
+
+	for _, part := range parts {
+		req, err := parseRequirement(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, *req)
+	}
+
+	return res, nil
+}
+
+// splitRequirements splits the selector at commas that aren't in the value sets of in and notin requirements.
+func splitRequirements(s string) ([]string, error) {
+	res := make([]string, 0)
+	depth, start := 0, 0
+
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, errors.New("parentheses should not be nested")
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, errors.New("parentheses are not balanced")
+			}
+		case ',':
+			if depth == 0 {
+				res = append(res, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, errors.New("parentheses are not balanced")
+	}
+
+	return append(res, s[start:]), nil
+}
+
+func parseRequirement(s string) (*labelRequirement, error) {
+	req := labelRequirement{key: "", op: "", values: nil}
+
+	switch {
+	case s == "":
+		return nil, errors.New("requirement should not be empty")
+	case strings.HasPrefix(s, "!"):
+		req.key, req.op = strings.TrimSpace(s[1:]), selectorDoesNotExist
+	case selectorSetRegex.MatchString(s):
+		m := selectorSetRegex.FindStringSubmatch(s)
+
+		req.key, req.op = m[1], selectorOperator(m[2])
+
+		for _, v := range strings.Split(m[3], ",") {
+			req.values = append(req.values, strings.TrimSpace(v))
+		}
+	case strings.Contains(s, "!="):
+		i := strings.Index(s, "!=")
+		req.key, req.op, req.values = strings.TrimSpace(s[:i]), selectorNotEquals, []string{strings.TrimSpace(s[i+2:])}
+	case strings.Contains(s, "="):
+		i := strings.Index(s, "=")
+		req.key, req.op, req.values = strings.TrimSpace(s[:i]), selectorEquals, []string{strings.TrimSpace(strings.TrimPrefix(s[i+1:], "="))}
+	default:
+		req.key, req.op = s, selectorExists
+	}
+
+	if !isValidLabelKey(req.key) {
+		return nil, errors.Errorf("label key '%s' is not valid", req.key)
+	}
+
+	for _, v := range req.values {
+		if !isValidLabelValue(v) {
+			return nil, errors.Errorf("label value '%s' is not valid", v)
+		}
+	}
+
+	return &req, nil
+}
+
+// eachSelected calls fn for the items of the type that the selector selects, in name order. Items are listed by the
+// first requirement that needs a label if the repository has a label index, and every item of the type is read
+// otherwise.
+func (h *Handler) eachSelected(ctx context.Context, typ string, sel labelSelector, fn func(item core.Item) error) error {
+	if index, ok := h.itemRepo.(repository.ItemLabelIndex); ok {
+		for _, req := range sel {
+			if req.op != selectorEquals && req.op != selectorIn && req.op != selectorExists {
+				continue
+			}
+
+			items, err := index.ListByLabel(ctx, typ, req.key, req.values)
+			if err != nil {
+				return errors.Wrap(err, "error on list items by label")
+			}
+
+			for i := range items {
+				if !sel.matches(items[i].Labels) {
+					continue
+				}
+
+				err = fn(items[i])
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+	}
+
+	return h.itemRepo.EachByType(ctx, typ, func(item core.Item) error {
+		if !sel.matches(item.Labels) {
+			return nil
+		}
+
+		return fn(item)
+	})
+}
+
+func (sel labelSelector) matches(labels map[string]string) bool {
+	for _, req := range sel {
+		if !req.matches(labels) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (req labelRequirement) matches(labels map[string]string) bool {
+	v, ok := labels[req.key]
+
+	switch req.op {
+	case selectorEquals:
+		return ok && v == req.values[0]
+	case selectorNotEquals:
+		return !ok || v != req.values[0]
+	case selectorIn:
+		return ok && containsString(req.values, v)
+	case selectorNotIn:
+		return !ok || !containsString(req.values, v)
+	case selectorExists:
+		return ok
+	case selectorDoesNotExist:
+		return !ok
+	default:
+		return false
+	}
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,269 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/nasermirzaei89/core/internal/repository"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PropagationBackground deletes the owner right away, and leaves its dependents to the garbage collector.
+	PropagationBackground = "Background"
+	// PropagationForeground deletes the dependents of the owner before the owner.
+	PropagationForeground = "Foreground"
+)
+
+func propagationFromRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	propagation := r.URL.Query().Get("propagationPolicy")
+
+	switch propagation {
+	case "":
+		return PropagationBackground, true
+	case PropagationBackground, PropagationForeground:
+		return propagation, true
+	default:
+		writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, "propagationPolicy parameter is not valid",
+			InvalidParam{Name: "propagationPolicy", Reason: fmt.Sprintf("should be '%s' or '%s'", PropagationBackground, PropagationForeground)})
+
+		return "", false
+	}
+}
+
+// checkOwners rejects owner references that were added, unless they point to existing items. References the item
+// already had may point to owners that are gone, as their dependents are collected in the background.
+func (h *Handler) checkOwners(ctx context.Context, current, owners []core.OwnerReference) error {
+	for i, owner := range owners {
+		if containsOwner(current, owner.UUID) {
+			continue
+		}
+
+		alive, err := h.ownerExists(ctx, owner)
+		if err != nil {
+			return err
+		}
+
+		if !alive {
+			return newProblemError(http.StatusUnprocessableEntity, CodeOwnerInvalid, fmt.Sprintf("owner %s '%s' doesn't exist", owner.Type, owner.Name),
+				InvalidParam{Name: fmt.Sprintf("metadata.ownerReferences.%d", i), Reason: fmt.Sprintf("%s with name '%s' and uuid '%s' doesn't exist", owner.Type, owner.Name, owner.UUID)})
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) ownerExists(ctx context.Context, owner core.OwnerReference) (bool, error) {
+	item, err := h.itemRepo.GetByTypeAndName(ctx, owner.Type, owner.Name)
+	if err != nil {
+		if errors.Is(err, repository.ErrItemNotFound) {
+			return false, nil
+		}
+
+		return false, repositoryError("error on find item by type and name from the repository", err)
+	}
+
+	return item.UUID == owner.UUID, nil
+}
+
+func containsOwner(owners []core.OwnerReference, ownerUUID string) bool {
+	for i := range owners {
+		if owners[i].UUID == ownerUUID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dependentsOf returns the items that have the owner in their owner references. Repositories without an owner index
+// are scanned, type by type.
+func (h *Handler) dependentsOf(ctx context.Context, ownerUUID string) ([]core.Item, error) {
+	if index, ok := h.itemRepo.(repository.ItemOwnerIndex); ok {
+		res, err := index.ListByOwner(ctx, ownerUUID)
+		if err != nil {
+			return nil, repositoryError("error on list items by owner from the repository", err)
+		}
+
+		return res, nil
+	}
+
+	types, err := h.itemRepo.ListTypes(ctx)
+	if err != nil {
+		return nil, repositoryError("error on list types from the repository", err)
+	}
+
+	res := make([]core.Item, 0)
+
+	for _, typ := range types {
+		err = h.itemRepo.EachByType(ctx, typ, func(item core.Item) error {
+			if containsOwner(item.OwnerReferences, ownerUUID) {
+				res = append(res, item)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, repositoryError("error on list items by type from the repository", err)
+		}
+	}
+
+	return res, nil
+}
+
+// deleteDependents deletes the dependents of the owner that have no other owners left, dependents of them first.
+// Dependents with other owners only lose their reference to the owner. Both are admitted like other writes.
+// Dependents that fail don't stop the others, and the first failure is returned.
+func (h *Handler) deleteDependents(ctx context.Context, owner core.Item, visited map[string]struct{}) error {
+	if _, ok := visited[owner.UUID]; ok {
+		return nil
+	}
+
+	visited[owner.UUID] = struct{}{}
+
+	dependents, err := h.dependentsOf(ctx, owner.UUID)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+
+	for i := range dependents {
+		dependent := dependents[i]
+
+		owners := make([]core.OwnerReference, 0, len(dependent.OwnerReferences))
+		orphaned := true
+
+		for _, ref := range dependent.OwnerReferences {
+			if ref.UUID == owner.UUID {
+				continue
+			}
+
+			owners = append(owners, ref)
+
+			alive, err := h.ownerExists(ctx, ref)
+			if err != nil {
+				return err
+			}
+
+			if alive {
+				orphaned = false
+			}
+		}
+
+		if !orphaned {
+			original := dependent.DeepCopy()
+
+			dependent.OwnerReferences = owners
+			dependent.UpdatedAt = time.Now()
+
+			err = h.updateItem(ctx, original, &dependent)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+
+			continue
+		}
+
+		err = h.admit(ctx, core.OperationDelete, &dependent, nil)
+		if err == nil {
+			err = h.deleteDependents(ctx, dependent, visited)
+		}
+
+		if err == nil {
+			err = h.deleteWithReferences(ctx, dependent)
+		}
+
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// garbageCollector deletes dependents of deleted owners in the background, one owner at a time. Its worker runs only
+// while there are queued owners, so idle handlers have no goroutine to leak.
+type garbageCollector struct {
+	mu      sync.Mutex
+	owners  []core.Item
+	running bool
+	stopped bool
+	collect func(owner core.Item)
+}
+
+func newGarbageCollector(collect func(owner core.Item)) *garbageCollector {
+	return &garbageCollector{
+		mu:      sync.Mutex{},
+		owners:  nil,
+		running: false,
+		stopped: false,
+		collect: collect,
+	}
+}
+
+// queue queues the deleted owner, so its dependents are deleted, and starts the worker if it isn't running.
+func (gc *garbageCollector) queue(owner core.Item) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if gc.stopped {
+		return
+	}
+
+	gc.owners = append(gc.owners, owner)
+
+	if !gc.running {
+		gc.running = true
+
+		go gc.run()
+	}
+}
+
+func (gc *garbageCollector) run() {
+	for owner, ok := gc.next(); ok; owner, ok = gc.next() {
+		gc.collect(owner)
+	}
+}
+
+// next returns the next queued owner. The worker exits when there is none, or when the collector stopped.
+func (gc *garbageCollector) next() (core.Item, bool) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if gc.stopped || len(gc.owners) == 0 {
+		gc.running = false
+
+		return core.Item{}, false
+	}
+
+	owner := gc.owners[0]
+	gc.owners = gc.owners[1:]
+
+	return owner, true
+}
+
+func (gc *garbageCollector) stop() {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	gc.stopped = true
+	gc.owners = nil
+}
+
+// collectDependents deletes dependents of the deleted owner. Dependents that can't be deleted, like ones with
+// restricting references to them, are left as they are.
+func (h *Handler) collectDependents(owner core.Item) {
+	_ = h.deleteDependents(context.Background(), owner, make(map[string]struct{}))
+}
+
+// Close stops the garbage collector. Dependents of owners that are queued or deleted later are left as they are.
+func (h *Handler) Close() error {
+	h.gc.stop()
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/nasermirzaei89/core/internal/core"
+)
+
+// fieldWriter is who writes data of an item. Fields a manager writes are owned by it, and other managers can't change
+// them unless they force it. Writes without a manager, like ones without a fieldManager parameter and imports, aren't
+// checked and leave the fields they change unowned.
+type fieldWriter struct {
+	manager string
+	force   bool
+}
+
+func writerFromRequest(w http.ResponseWriter, r *http.Request) (fieldWriter, bool) {
+	force, err := boolParam(r, "force")
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, "force parameter is not valid",
+			InvalidParam{Name: "force", Reason: "should be a boolean"})
+
+		return fieldWriter{}, false
+	}
+
+	return fieldWriter{manager: r.URL.Query().Get("fieldManager"), force: force}, true
+}
+
+// write sets data of the item, and the managers of the fields that changed.
+func (fw fieldWriter) write(item *core.Item, data map[string]interface{}) error {
+	changed := changedFields("", item.Data, data)
+
+	if fw.manager != "" && !fw.force {
+		conflicts := make([]InvalidParam, 0)
+
+		for _, path := range changed {
+			if manager, ok := item.ManagedFields[path]; ok && manager != fw.manager {
+				conflicts = append(conflicts, InvalidParam{Name: path, Reason: fmt.Sprintf("is managed by '%s'", manager)})
+			}
+		}
+
+		if len(conflicts) != 0 {
+			names := make([]string, 0, len(conflicts))
+
+			for i := range conflicts {
+				names = append(names, conflicts[i].Name)
+			}
+
+			return newProblemError(http.StatusConflict, CodeFieldConflict,
+				fmt.Sprintf("fields %s are managed by other field managers, set force to take them over", strings.Join(names, ", ")), conflicts...)
+		}
+	}
+
+	leaves := make(map[string]struct{})
+
+	for k, v := range data {
+		leafFields(k, v, leaves)
+	}
+
+	for _, path := range changed {
+		if _, ok := leaves[path]; ok && fw.manager != "" {
+			if item.ManagedFields == nil {
+				item.ManagedFields = make(map[string]string)
+			}
+
+			item.ManagedFields[path] = fw.manager
+
+			continue
+		}
+
+		delete(item.ManagedFields, path)
+	}
+
+	if len(item.ManagedFields) == 0 {
+		item.ManagedFields = nil
+	}
+
+	item.Data = data
+
+	return nil
+}
+
+// changedFields returns dotted paths of the leaf fields that were added, removed or changed, sorted.
+func changedFields(prefix string, from, to map[string]interface{}) []string {
+	res := make(map[string]struct{})
+
+	for k, v := range from {
+		if _, ok := to[k]; !ok {
+			leafFields(joinPath(prefix, k), v, res)
+		}
+	}
+
+	for k, v := range to {
+		path := joinPath(prefix, k)
+
+		old, ok := from[k]
+		if !ok {
+			leafFields(path, v, res)
+
+			continue
+		}
+
+		oldMap, oldIsMap := old.(map[string]interface{})
+		newMap, newIsMap := v.(map[string]interface{})
+
+		switch {
+		case oldIsMap && newIsMap:
+			for _, p := range changedFields(path, oldMap, newMap) {
+				res[p] = struct{}{}
+			}
+		case !reflect.DeepEqual(old, v):
+			leafFields(path, old, res)
+			leafFields(path, v, res)
+		}
+	}
+
+	paths := make([]string, 0, len(res))
+
+	for p := range res {
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// leafFields adds the paths of the values under path that aren't objects, or are empty objects.
+func leafFields(path string, v interface{}, res map[string]struct{}) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		res[path] = struct{}{}
+
+		return
+	}
+
+	for k, v := range m {
+		leafFields(joinPath(path, k), v, res)
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
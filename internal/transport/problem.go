@@ -0,0 +1,140 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gertd/go-pluralize"
+	"github.com/gorilla/mux"
+	"github.com/nasermirzaei89/core/internal/core"
+)
+
+const (
+	contentTypeProblemJSON = "application/problem+json"
+	contentTypeProblemYAML = "application/problem+yaml"
+
+	problemTypePrefix = "urn:core:problem:"
+)
+
+const (
+	CodeTypeNotPlural          = "type.not_plural"
+	CodeTypeInvalid            = "type.invalid"
+	CodeTypeMismatch           = "type.mismatch"
+	CodeNameRequired           = "name.required"
+	CodeNameInvalid            = "name.invalid"
+	CodeParamInvalid           = "param.invalid"
+	CodeBodyInvalid            = "body.invalid"
+	CodeMediaTypeUnsupported   = "media_type.unsupported"
+	CodeMediaTypeNotAcceptable = "media_type.not_acceptable"
+	CodeItemNotFound           = "item.not_found"
+	CodeItemAlreadyExists      = "item.already_exists"
+	CodePatchInvalid           = "patch.invalid"
+	CodePatchFailed            = "patch.failed"
+	CodeFieldConflict          = "field.conflict"
+	CodeLabelInvalid           = "label.invalid"
+	CodeSchemaNotFound         = "schema.not_found"
+	CodeSchemaInvalid          = "schema.invalid"
+	CodeReferenceInvalid       = "reference.invalid"
+	CodeItemReferenced         = "item.referenced"
+	CodeOwnerInvalid           = "owner.invalid"
+	CodeFinalizerInvalid       = "finalizer.invalid"
+	CodeItemDeleting           = "item.deleting"
+	CodeAdmissionDenied        = "admission.denied"
+	CodeAdmissionFailed        = "admission.failed"
+	CodeScriptRejected         = "script.rejected"
+	CodeScriptFailed           = "script.failed"
+	CodeRuleFailed             = "rule.failed"
+	CodeWorkflowNotFound       = "workflow.not_found"
+	CodeTransitionInvalid      = "transition.invalid"
+	CodeTransitionForbidden    = "transition.forbidden"
+	CodeOperationNotAllowed    = "operation.not_allowed"
+	CodeConfirmationRequired   = "confirmation.required"
+	CodeWatchUnsupported       = "watch.unsupported"
+	CodeRepositoryError        = "repository.error"
+	CodeInternalError          = "internal.error"
+)
+
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Problem is an RFC 7807 problem details object. Code is a stable, machine-readable error code,
+// also used as the last segment of Type.
+type Problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Code          string         `json:"code"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	InvalidParams []InvalidParam `json:"invalidParams,omitempty"`
+}
+
+// writeProblem writes an error response. Every handler reports its errors through it.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string, invalidParams ...InvalidParam) {
+	contentType, c, _ := responseCodec(r)
+
+	switch contentType {
+	case contentTypeJSON:
+		contentType = contentTypeProblemJSON
+	case contentTypeYAML:
+		contentType = contentTypeProblemYAML
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+
+	_ = c.Encode(w, Problem{
+		Type:          problemTypePrefix + code,
+		Title:         http.StatusText(status),
+		Status:        status,
+		Code:          code,
+		Detail:        detail,
+		Instance:      r.URL.RequestURI(),
+		InvalidParams: invalidParams,
+	})
+}
+
+func writeRepositoryProblem(w http.ResponseWriter, r *http.Request, detail string, err error) {
+	writeProblem(w, r, http.StatusInternalServerError, CodeRepositoryError, fmt.Sprintf("%s: %s", detail, err.Error()))
+}
+
+// typeFromRequest returns the singular type of the typePlural path parameter, writing a problem if it's not valid.
+func typeFromRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	pc := pluralize.NewClient()
+
+	typePlural := mux.Vars(r)["typePlural"]
+
+	if !pc.IsPlural(typePlural) {
+		writeProblem(w, r, http.StatusBadRequest, CodeTypeNotPlural, "you should set plural form of the type",
+			InvalidParam{Name: "typePlural", Reason: "should be plural form of the type"})
+
+		return "", false
+	}
+
+	typ := pc.Singular(typePlural)
+
+	if !isValidType(typ) {
+		writeProblem(w, r, http.StatusBadRequest, CodeTypeInvalid, "type parameter is not valid",
+			InvalidParam{Name: "typePlural", Reason: fmt.Sprintf("singular form should be an string that matches the regex '%s'", core.TypeRegex)})
+
+		return "", false
+	}
+
+	return typ, true
+}
+
+// nameFromRequest returns the name path parameter, writing a problem if it's not valid.
+func nameFromRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := mux.Vars(r)["name"]
+
+	if !isValidName(name) {
+		writeProblem(w, r, http.StatusBadRequest, CodeNameInvalid, "name parameter is not valid",
+			InvalidParam{Name: "name", Reason: fmt.Sprintf("should be an string that matches the regex '%s'", core.NameRegex)})
+
+		return "", false
+	}
+
+	return name, true
+}
@@ -0,0 +1,381 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/nasermirzaei89/core/internal/repository"
+	"github.com/pkg/errors"
+)
+
+// checkReferences rejects data whose reference fields don't hold names or uuids of existing items of the referenced
+// types. Missing and null reference fields are allowed.
+func (h *Handler) checkReferences(ctx context.Context, typ string, data map[string]interface{}) error {
+	schema, ok := h.schemaOf(typ)
+	if !ok {
+		return nil
+	}
+
+	paths := make([]string, 0, len(schema.References))
+
+	for path := range schema.References {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		ref := schema.References[path]
+		values := make([]interface{}, 0)
+
+		walkReference(data, strings.Split(path, "."), func(obj map[string]interface{}, key string) {
+			values = append(values, obj[key])
+		})
+
+		for _, v := range values {
+			if v == nil {
+				continue
+			}
+
+			s, ok := v.(string)
+			if !ok {
+				return newProblemError(http.StatusUnprocessableEntity, CodeReferenceInvalid, fmt.Sprintf("field '%s' should reference a %s", path, ref.Type),
+					InvalidParam{Name: path, Reason: fmt.Sprintf("should be the name or uuid of a %s", ref.Type)})
+			}
+
+			found, err := h.referenceExists(ctx, ref.Type, s)
+			if err != nil {
+				return err
+			}
+
+			if !found {
+				return newProblemError(http.StatusUnprocessableEntity, CodeReferenceInvalid, fmt.Sprintf("field '%s' references missing %s '%s'", path, ref.Type, s),
+					InvalidParam{Name: path, Reason: fmt.Sprintf("%s with name or uuid '%s' doesn't exist", ref.Type, s)})
+			}
+		}
+	}
+
+	return nil
+}
+
+// referenceExists reports whether an item of the type has the name, or the uuid if it's one.
+func (h *Handler) referenceExists(ctx context.Context, typ, s string) (bool, error) {
+	item, err := h.findReference(ctx, typ, s)
+	if err != nil {
+		return false, err
+	}
+
+	return item != nil, nil
+}
+
+// findReference returns the item of the type with the name, or the uuid if it's one, or nil if there's none. Uuids are
+// looked up with the uuid index of the repository if it has one, or by reading items of the type otherwise.
+func (h *Handler) findReference(ctx context.Context, typ, s string) (*core.Item, error) {
+	item, err := h.itemRepo.GetByTypeAndName(ctx, typ, s)
+	if err == nil {
+		return item, nil
+	}
+
+	if !errors.Is(err, repository.ErrItemNotFound) {
+		return nil, repositoryError("error on find item by type and name from the repository", err)
+	}
+
+	if _, err := uuid.Parse(s); err != nil {
+		return nil, nil
+	}
+
+	if index, ok := h.itemRepo.(repository.ItemUUIDIndex); ok {
+		item, err = index.GetByUUID(ctx, s)
+		if errors.Is(err, repository.ErrItemNotFound) {
+			return nil, nil
+		}
+
+		if err != nil {
+			return nil, repositoryError("error on find item by uuid from the repository", err)
+		}
+
+		if item.Type != typ {
+			return nil, nil
+		}
+
+		return item, nil
+	}
+
+	items, err := h.itemRepo.ListByType(ctx, typ)
+	if err != nil {
+		return nil, repositoryError("error on list items by type from the repository", err)
+	}
+
+	for i := range items {
+		if items[i].UUID == s {
+			return &items[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// walkReference calls fn with the objects that have the last field of the path, going through lists on the way.
+func walkReference(v interface{}, path []string, fn func(obj map[string]interface{}, key string)) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			if _, ok := v[path[0]]; ok {
+				fn(v, path[0])
+			}
+
+			return
+		}
+
+		walkReference(v[path[0]], path[1:], fn)
+	case []interface{}:
+		for i := range v {
+			walkReference(v[i], path, fn)
+		}
+	}
+}
+
+// referrer is a reference field that may hold items of a type.
+type referrer struct {
+	typ    string
+	path   string
+	policy core.DeletePolicy
+}
+
+func (h *Handler) referrersOf(typ string) []referrer {
+	res := make([]referrer, 0)
+
+	for _, schema := range h.listSchemas() {
+		paths := make([]string, 0, len(schema.References))
+
+		for path, ref := range schema.References {
+			if ref.Type == typ {
+				paths = append(paths, path)
+			}
+		}
+
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			policy := schema.References[path].OnDelete
+			if policy == "" {
+				policy = core.DeleteRestrict
+			}
+
+			res = append(res, referrer{typ: schema.Type, path: path, policy: policy})
+		}
+	}
+
+	return res
+}
+
+// deletePlan is what deleting an item takes, given the delete policies of the references to it and to the items it
+// cascades to. Originals are the items of nulls before their references were set to null. Items are the items of each
+// type read for repositories without a reference index.
+type deletePlan struct {
+	deletes   []core.Item
+	deleted   map[string]struct{}
+	nulls     map[string]*core.Item
+	originals map[string]core.Item
+	blocking  []blockingReference
+	items     map[string][]core.Item
+}
+
+// blockingReference is a restricting reference of an item to one that is deleted.
+type blockingReference struct {
+	itemUUID string
+	param    InvalidParam
+}
+
+func (h *Handler) planDelete(ctx context.Context, item core.Item, plan *deletePlan) error {
+	if _, ok := plan.deleted[item.UUID]; ok {
+		return nil
+	}
+
+	plan.deletes = append(plan.deletes, item)
+	plan.deleted[item.UUID] = struct{}{}
+
+	for _, ref := range h.referrersOf(item.Type) {
+		items, err := h.referringItems(ctx, ref, item, plan)
+		if err != nil {
+			return err
+		}
+
+		for i := range items {
+			referring := &items[i]
+
+			// references set to null already are kept null
+			if nulled, ok := plan.nulls[referring.UUID]; ok {
+				referring = nulled
+			}
+
+			if !references(referring.Data, ref.path, item) {
+				continue
+			}
+
+			switch ref.policy {
+			case core.DeleteCascade:
+				err := h.planDelete(ctx, *referring, plan)
+				if err != nil {
+					return err
+				}
+			case core.DeleteSetNull:
+				if _, ok := plan.nulls[referring.UUID]; !ok {
+					plan.originals[referring.UUID] = referring.DeepCopy()
+				}
+
+				plan.nulls[referring.UUID] = referring
+
+				walkReference(referring.Data, strings.Split(ref.path, "."), func(obj map[string]interface{}, key string) {
+					if obj[key] == item.Name || obj[key] == item.UUID {
+						obj[key] = nil
+					}
+				})
+			default:
+				plan.blocking = append(plan.blocking, blockingReference{
+					itemUUID: referring.UUID,
+					param: InvalidParam{
+						Name:   fmt.Sprintf("%s/%s", referring.Type, referring.Name),
+						Reason: fmt.Sprintf("field '%s' references %s '%s'", ref.path, item.Type, item.Name),
+					},
+				})
+			}
+		}
+	}
+
+	return nil
+}
+
+// referringItems returns items that may reference the item in the field of the referrer. They're listed with the
+// reference index of the repository if it has one, or read once per type and plan otherwise.
+func (h *Handler) referringItems(ctx context.Context, ref referrer, item core.Item, plan *deletePlan) ([]core.Item, error) {
+	if index, ok := h.itemRepo.(repository.ItemReferenceIndex); ok {
+		items, err := index.ListByReference(ctx, ref.typ, ref.path, []string{item.Name, item.UUID})
+		if err != nil {
+			return nil, repositoryError("error on list items by reference from the repository", err)
+		}
+
+		return items, nil
+	}
+
+	items, ok := plan.items[ref.typ]
+	if !ok {
+		var err error
+
+		items, err = h.itemRepo.ListByType(ctx, ref.typ)
+		if err != nil {
+			return nil, repositoryError("error on list items by type from the repository", err)
+		}
+
+		plan.items[ref.typ] = items
+	}
+
+	return items, nil
+}
+
+// references reports whether the field of data holds the name or uuid of the item.
+func references(data map[string]interface{}, path string, item core.Item) bool {
+	res := false
+
+	walkReference(data, strings.Split(path, "."), func(obj map[string]interface{}, key string) {
+		if obj[key] == item.Name || obj[key] == item.UUID {
+			res = true
+		}
+	})
+
+	return res
+}
+
+// deleteWithReferences deletes the item after applying the delete policies of the references to it. The item should be
+// admitted already, and the items it cascades to are admitted here, as deletes and as updates that set references to
+// null. Nothing is written if a restricting reference or an admission blocks the deletion.
+func (h *Handler) deleteWithReferences(ctx context.Context, item core.Item) error {
+	plan := deletePlan{
+		deletes:   nil,
+		deleted:   make(map[string]struct{}),
+		nulls:     make(map[string]*core.Item),
+		originals: make(map[string]core.Item),
+		blocking:  nil,
+		items:     make(map[string][]core.Item),
+	}
+
+	err := h.planDelete(ctx, item, &plan)
+	if err != nil {
+		return err
+	}
+
+	// items that are deleted too don't block the deletion
+	blocking := make([]InvalidParam, 0, len(plan.blocking))
+
+	for _, ref := range plan.blocking {
+		if _, ok := plan.deleted[ref.itemUUID]; ok {
+			continue
+		}
+
+		blocking = append(blocking, ref.param)
+	}
+
+	if len(blocking) != 0 {
+		return newProblemError(http.StatusConflict, CodeItemReferenced, fmt.Sprintf("%s '%s' is referenced by other items", item.Type, item.Name), blocking...)
+	}
+
+	nulls := make([]string, 0, len(plan.nulls))
+
+	for itemUUID, nulled := range plan.nulls {
+		if _, ok := plan.deleted[itemUUID]; ok {
+			continue
+		}
+
+		nulled.UpdatedAt = time.Now()
+		original := plan.originals[itemUUID]
+
+		err = h.admit(ctx, core.OperationUpdate, &original, nulled)
+		if err != nil {
+			return err
+		}
+
+		nulls = append(nulls, itemUUID)
+	}
+
+	for i := 1; i < len(plan.deletes); i++ {
+		err = h.admit(ctx, core.OperationDelete, &plan.deletes[i], nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, itemUUID := range nulls {
+		err = h.itemRepo.Replace(ctx, itemUUID, *plan.nulls[itemUUID])
+		if err != nil {
+			return repositoryError("error on replace item in the repository", err)
+		}
+	}
+
+	// dependents go first, so a failure leaves the item to delete again
+	for i := len(plan.deletes) - 1; i >= 0; i-- {
+		// items with finalizers are only marked, and are deleted when their last finalizer is removed
+		if len(plan.deletes[i].Finalizers) != 0 {
+			err = h.markDeleting(ctx, &plan.deletes[i])
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		err = h.itemRepo.Delete(ctx, plan.deletes[i].UUID)
+		if err != nil {
+			return repositoryError("error on delete item from the repository", err)
+		}
+
+		h.gc.queue(plan.deletes[i])
+	}
+
+	return nil
+}
@@ -3,10 +3,26 @@ package transport
 import "net/http"
 
 func (h *Handler) registerRoutes() {
+	h.router.Methods(http.MethodGet, http.MethodPost).Path("/graphql").HandlerFunc(h.GraphQLHandler())
+	h.router.Methods(http.MethodPost).Path("/_apply").HandlerFunc(h.ApplyItemHandler())
+	h.router.Methods(http.MethodGet).Path("/_schemas").HandlerFunc(h.ListSchemasHandler())
+	h.router.Methods(http.MethodGet).Path("/_schemas/{typePlural}").HandlerFunc(h.ReadSchemaHandler())
+	h.router.Methods(http.MethodPut).Path("/_schemas/{typePlural}").HandlerFunc(h.PutSchemaHandler())
+	h.router.Methods(http.MethodDelete).Path("/_schemas/{typePlural}").HandlerFunc(h.DeleteSchemaHandler())
+	h.router.Methods(http.MethodGet).Path("/_export").HandlerFunc(h.ExportAllItemsHandler())
+	h.router.Methods(http.MethodGet).Path("/{typePlural}/_export").HandlerFunc(h.ExportItemsHandler())
+	h.router.Methods(http.MethodPost).Path("/{typePlural}/_import").HandlerFunc(h.ImportItemsHandler())
 	h.router.Methods(http.MethodPost).Path("/{typePlural}").HandlerFunc(h.CreateItemHandler())
 	h.router.Methods(http.MethodGet).Path("/{typePlural}").HandlerFunc(h.ListItemsHandler())
+	h.router.Methods(http.MethodPatch).Path("/{typePlural}").HandlerFunc(h.PatchItemsHandler())
+	h.router.Methods(http.MethodDelete).Path("/{typePlural}").HandlerFunc(h.DeleteItemsHandler())
 	h.router.Methods(http.MethodGet).Path("/{typePlural}/{name}").HandlerFunc(h.ReadItemHandler())
 	h.router.Methods(http.MethodPut).Path("/{typePlural}/{name}").HandlerFunc(h.ReplaceItemHandler())
 	h.router.Methods(http.MethodPatch).Path("/{typePlural}/{name}").HandlerFunc(h.PatchItemHandler())
 	h.router.Methods(http.MethodDelete).Path("/{typePlural}/{name}").HandlerFunc(h.DeleteItemHandler())
+	h.router.Methods(http.MethodPost).Path("/{typePlural}/{name}:transition").HandlerFunc(h.TransitionItemHandler())
+	h.router.Methods(http.MethodGet).Path("/{typePlural}/{name}/transitions").HandlerFunc(h.ListTransitionsHandler())
+	h.router.Methods(http.MethodGet).Path("/{typePlural}/{name}/status").HandlerFunc(h.ReadStatusHandler())
+	h.router.Methods(http.MethodPut).Path("/{typePlural}/{name}/status").HandlerFunc(h.ReplaceStatusHandler())
+	h.router.Methods(http.MethodPatch).Path("/{typePlural}/{name}/status").HandlerFunc(h.PatchStatusHandler())
 }
@@ -0,0 +1,128 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/cel-go/cel"
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/pkg/errors"
+)
+
+// maxRuleCost bounds the computation of a rule evaluation, like of comprehensions over long lists.
+const maxRuleCost = 1000000
+
+// compiledRule is a rule ready to evaluate. Rules that use oldSelf are only evaluated on updates.
+type compiledRule struct {
+	rule     core.Rule
+	program  cel.Program
+	onCreate bool
+}
+
+// ruleEnvs returns the environments of rules on creates, with self, and on updates, with oldSelf too. Both are
+// dynamic values.
+func ruleEnvs() (*cel.Env, *cel.Env, error) {
+	createEnv, err := cel.NewEnv(cel.Variable("self", cel.DynType))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error on create cel environment")
+	}
+
+	updateEnv, err := createEnv.Extend(cel.Variable("oldSelf", cel.DynType))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error on extend cel environment")
+	}
+
+	return createEnv, updateEnv, nil
+}
+
+// compileRule type checks the expression, and returns its program. Problems are reported on param.
+func compileRule(env *cel.Env, name, expr, param string) (cel.Program, error) {
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("rule '%s' is not valid", name),
+			InvalidParam{Name: param, Reason: issues.Err().Error()})
+	}
+
+	if !ast.OutputType().IsExactType(cel.BoolType) && !ast.OutputType().IsExactType(cel.DynType) {
+		return nil, newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("rule '%s' is not valid", name),
+			InvalidParam{Name: param, Reason: fmt.Sprintf("should evaluate to bool, not %s", ast.OutputType())})
+	}
+
+	program, err := env.Program(ast, cel.CostLimit(maxRuleCost))
+	if err != nil {
+		return nil, newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("rule '%s' is not valid", name),
+			InvalidParam{Name: param, Reason: err.Error()})
+	}
+
+	return program, nil
+}
+
+// compileRules type checks the rules, and returns their programs.
+func compileRules(rules []core.Rule) ([]compiledRule, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	createEnv, updateEnv, err := ruleEnvs()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]compiledRule, 0, len(rules))
+
+	for i, rule := range rules {
+		program, err := compileRule(updateEnv, rule.Name, rule.Rule, fmt.Sprintf("rules.%d.rule", i))
+		if err != nil {
+			return nil, err
+		}
+
+		// rules that don't compile without oldSelf use it
+		_, issues := createEnv.Compile(rule.Rule)
+
+		res = append(res, compiledRule{rule: rule, program: program, onCreate: issues == nil || issues.Err() == nil})
+	}
+
+	return res, nil
+}
+
+func (h *Handler) rulesOf(typ string) []compiledRule {
+	h.schemasMu.RLock()
+	defer h.schemasMu.RUnlock()
+
+	return h.rules[typ]
+}
+
+// checkRules evaluates the rules of the type of the item against its data as self, and the data of old as oldSelf.
+// Old is nil on creates. Rules that evaluate to false or fail reject the item.
+func (h *Handler) checkRules(ctx context.Context, old, item *core.Item) error {
+	for _, cr := range h.rulesOf(item.Type) {
+		if old == nil && !cr.onCreate {
+			continue
+		}
+
+		vars := map[string]interface{}{"self": item.Data}
+
+		if old != nil {
+			vars["oldSelf"] = old.Data
+		}
+
+		out, _, err := cr.program.ContextEval(ctx, vars)
+		if err != nil {
+			return newProblemError(http.StatusUnprocessableEntity, CodeRuleFailed, fmt.Sprintf("rule '%s' failed: %s", cr.rule.Name, err.Error()),
+				InvalidParam{Name: cr.rule.Name, Reason: err.Error()})
+		}
+
+		if ok, _ := out.Value().(bool); !ok {
+			message := cr.rule.Message
+			if message == "" {
+				message = fmt.Sprintf("should satisfy '%s'", cr.rule.Rule)
+			}
+
+			return newProblemError(http.StatusUnprocessableEntity, CodeRuleFailed, fmt.Sprintf("rule '%s' failed: %s", cr.rule.Name, message),
+				InvalidParam{Name: cr.rule.Name, Reason: message})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/pkg/errors"
+)
+
+// Scripts run in a sandbox, which is a child process of the same executable started with scriptSandboxEnv set. Starlark
+// can't bound the memory a script allocates, so the sandbox limits the memory of its process, and a script that goes
+// over the limit only ends the sandbox.
+const (
+	scriptSandboxEnv = "CORE_SCRIPT_SANDBOX"
+	// maxScriptMemory is the memory of the sandbox, which has the runtime and the script.
+	maxScriptMemory = 256 << 20
+	// sandboxTimeout is the time a sandbox has to start and run a script, which times out on its own first.
+	sandboxTimeout = scriptTimeout + 5*time.Second
+)
+
+//nolint:gochecknoinits
+func init() {
+	if os.Getenv(scriptSandboxEnv) == "" {
+		return
+	}
+
+	os.Exit(serveSandbox(os.Stdin, os.Stdout))
+}
+
+// scriptRun is what the sandbox is asked to run. The script is only run to define its hooks if hook is empty. Old is
+// only set for hooks that take the stored item.
+type scriptRun struct {
+	Type   string     `json:"type"`
+	Script string     `json:"script"`
+	Hook   string     `json:"hook,omitempty"`
+	Item   *core.Item `json:"item,omitempty"`
+	Old    *core.Item `json:"old,omitempty"`
+}
+
+// scriptResult is what the sandbox answers runs with. Hooks are the hooks the script defines, and data is the data the
+// hook left to the item. Rejected is the message of fail if the script called it, and failed is the error of the run
+// otherwise.
+type scriptResult struct {
+	Hooks    []string               `json:"hooks,omitempty"`
+	Data     map[string]interface{} `json:"data"`
+	Rejected string                 `json:"rejected,omitempty"`
+	Failed   string                 `json:"failed,omitempty"`
+}
+
+// serveSandbox runs the script run read from r within the memory limit, and writes its result to w. It returns the
+// exit code of the sandbox.
+func serveSandbox(r io.Reader, w io.Writer) int {
+	var res scriptResult
+
+	err := limitMemory(maxScriptMemory)
+	if err != nil {
+		res.Failed = errors.Wrap(err, "error on limit memory of sandbox").Error()
+	} else {
+		var run scriptRun
+
+		err = json.NewDecoder(r).Decode(&run)
+		if err != nil {
+			res.Failed = errors.Wrap(err, "error on decode script run").Error()
+		} else {
+			res = execScript(run)
+		}
+	}
+
+	err = json.NewEncoder(w).Encode(res)
+	if err != nil {
+		return 1
+	}
+
+	return 0
+}
+
+// runSandboxed runs the script run in a new sandbox.
+func runSandboxed(ctx context.Context, run scriptRun) (*scriptResult, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, errors.Wrap(err, "error on find executable of sandbox")
+	}
+
+	body, err := json.Marshal(run)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on marshal script run")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sandboxTimeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, exe)
+	cmd.Env = append(os.Environ(), scriptSandboxEnv+"=1")
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = &stdout
+
+	err = cmd.Run()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.Errorf("sandbox timed out after %s", sandboxTimeout)
+		}
+
+		// the runtime crashes in different ways when it runs out of memory, so the exit isn't told apart
+		return nil, errors.Wrapf(err, "error on run sandbox, which ends if the script uses more than %d bytes of memory", maxScriptMemory)
+	}
+
+	var res scriptResult
+
+	err = json.Unmarshal(stdout.Bytes(), &res)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on decode script result")
+	}
+
+	return &res, nil
+}
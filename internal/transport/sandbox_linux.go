@@ -0,0 +1,18 @@
+package transport
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// limitMemory limits the writable memory the process maps to the bytes. The address space isn't limited instead, as the
+// Go runtime reserves more of it than it uses.
+func limitMemory(bytes uint64) error {
+	err := syscall.Setrlimit(syscall.RLIMIT_DATA, &syscall.Rlimit{Cur: bytes, Max: bytes})
+	if err != nil {
+		return errors.Wrap(err, "error on set data limit")
+	}
+
+	return nil
+}
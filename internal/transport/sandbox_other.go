@@ -0,0 +1,10 @@
+//go:build !linux
+
+package transport
+
+import "github.com/pkg/errors"
+
+// limitMemory fails, as memory of processes can only be limited on Linux, so scripts don't run elsewhere.
+func limitMemory(uint64) error {
+	return errors.New("memory of scripts can only be limited on linux")
+}
@@ -0,0 +1,349 @@
+package transport
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/pkg/errors"
+)
+
+// SchemaList is the response of listing schemas.
+type SchemaList struct {
+	Items []core.Schema `json:"items"`
+}
+
+func (h *Handler) schemaOf(typ string) (core.Schema, bool) {
+	h.schemasMu.RLock()
+	defer h.schemasMu.RUnlock()
+
+	schema, ok := h.schemas[typ]
+
+	return schema, ok
+}
+
+func (h *Handler) listSchemas() []core.Schema {
+	h.schemasMu.RLock()
+	defer h.schemasMu.RUnlock()
+
+	res := make([]core.Schema, 0, len(h.schemas))
+
+	for _, schema := range h.schemas {
+		res = append(res, schema)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Type < res[j].Type })
+
+	return res
+}
+
+// putSchema sets the schema of its type, and reports whether the type had no schema before. Existing items are not
+// checked against the new schema.
+func (h *Handler) putSchema(schema core.Schema) (bool, error) {
+	err := checkSchema(schema)
+	if err != nil {
+		return false, err
+	}
+
+	var script *compiledScript
+
+	if schema.Script != "" {
+		script, err = compileScript(schema.Type, schema.Script)
+		if err != nil {
+			return false, newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("script of type '%s' is not valid", schema.Type),
+				InvalidParam{Name: "script", Reason: err.Error()})
+		}
+	}
+
+	rules, err := compileRules(schema.Rules)
+	if err != nil {
+		return false, err
+	}
+
+	workflow, err := compileWorkflow(schema.Workflow)
+	if err != nil {
+		return false, err
+	}
+
+	h.schemasMu.Lock()
+	defer h.schemasMu.Unlock()
+
+	_, ok := h.schemas[schema.Type]
+	h.schemas[schema.Type] = schema
+	h.scripts[schema.Type] = script
+	h.rules[schema.Type] = rules
+	h.workflows[schema.Type] = workflow
+
+	return !ok, nil
+}
+
+func (h *Handler) deleteSchema(typ string) bool {
+	h.schemasMu.Lock()
+	defer h.schemasMu.Unlock()
+
+	_, ok := h.schemas[typ]
+	delete(h.schemas, typ)
+	delete(h.scripts, typ)
+	delete(h.rules, typ)
+	delete(h.workflows, typ)
+
+	return ok
+}
+
+// LoadSchemas sets the schemas of a YAML or JSON list, like the ones of a schemas file read on start.
+func (h *Handler) LoadSchemas(r io.Reader) error {
+	var schemas []core.Schema
+
+	err := yamlCodec{}.Decode(r, &schemas)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return errors.Wrap(err, "error on decode schemas")
+	}
+
+	for i := range schemas {
+		_, err = h.putSchema(schemas[i])
+		if err != nil {
+			return errors.Wrapf(err, "error on put schema of type '%s'", schemas[i].Type)
+		}
+	}
+
+	return nil
+}
+
+func checkSchema(schema core.Schema) error {
+	err := checkType(schema.Type)
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(schema.References))
+
+	for path := range schema.References {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		ref := schema.References[path]
+
+		for _, segment := range strings.Split(path, ".") {
+			if segment == "" {
+				return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("reference path '%s' is not valid", path),
+					InvalidParam{Name: "references." + path, Reason: "path should be dotted field names"})
+			}
+		}
+
+		if !isValidType(ref.Type) {
+			return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("type of reference '%s' is not valid", path),
+				InvalidParam{Name: "references." + path + ".type", Reason: fmt.Sprintf("should be an string that matches the regex '%s'", core.TypeRegex)})
+		}
+
+		switch ref.OnDelete {
+		case "", core.DeleteRestrict, core.DeleteCascade, core.DeleteSetNull:
+		default:
+			return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("delete policy of reference '%s' is not valid", path),
+				InvalidParam{Name: "references." + path + ".onDelete", Reason: fmt.Sprintf("should be one of '%s', '%s' or '%s'", core.DeleteRestrict, core.DeleteCascade, core.DeleteSetNull)})
+		}
+	}
+
+	err = checkWebhooks(schema.Webhooks)
+	if err != nil {
+		return err
+	}
+
+	err = checkRuleNames(schema.Rules)
+	if err != nil {
+		return err
+	}
+
+	if schema.Workflow != nil {
+		return checkWorkflow(schema.Workflow)
+	}
+
+	return nil
+}
+
+func checkRuleNames(rules []core.Rule) error {
+	names := make(map[string]struct{}, len(rules))
+
+	for i, rule := range rules {
+		param := fmt.Sprintf("rules.%d", i)
+
+		if rule.Name == "" {
+			return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, "rule name is required",
+				InvalidParam{Name: param + ".name", Reason: "is required"})
+		}
+
+		if _, ok := names[rule.Name]; ok {
+			return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("rule name '%s' is duplicate", rule.Name),
+				InvalidParam{Name: param + ".name", Reason: "should be unique"})
+		}
+
+		names[rule.Name] = struct{}{}
+	}
+
+	return nil
+}
+
+func checkWebhooks(webhooks []core.Webhook) error {
+	names := make(map[string]struct{}, len(webhooks))
+
+	for i, webhook := range webhooks {
+		param := fmt.Sprintf("webhooks.%d", i)
+
+		if webhook.Name == "" {
+			return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, "webhook name is required",
+				InvalidParam{Name: param + ".name", Reason: "is required"})
+		}
+
+		if _, ok := names[webhook.Name]; ok {
+			return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("webhook name '%s' is duplicate", webhook.Name),
+				InvalidParam{Name: param + ".name", Reason: "should be unique"})
+		}
+
+		names[webhook.Name] = struct{}{}
+
+		switch webhook.Kind {
+		case core.WebhookValidating, core.WebhookMutating:
+		default:
+			return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("kind of webhook '%s' is not valid", webhook.Name),
+				InvalidParam{Name: param + ".kind", Reason: fmt.Sprintf("should be '%s' or '%s'", core.WebhookValidating, core.WebhookMutating)})
+		}
+
+		u, err := url.Parse(webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("url of webhook '%s' is not valid", webhook.Name),
+				InvalidParam{Name: param + ".url", Reason: "should be an absolute http or https url"})
+		}
+
+		for j, op := range webhook.Operations {
+			switch op {
+			case core.OperationCreate, core.OperationUpdate, core.OperationDelete:
+			default:
+				return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("operation of webhook '%s' is not valid", webhook.Name),
+					InvalidParam{Name: fmt.Sprintf("%s.operations.%d", param, j), Reason: fmt.Sprintf("should be one of '%s', '%s' or '%s'", core.OperationCreate, core.OperationUpdate, core.OperationDelete)})
+			}
+		}
+
+		if webhook.TimeoutSeconds < 0 || webhook.TimeoutSeconds > maxWebhookTimeoutSeconds {
+			return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("timeout of webhook '%s' is not valid", webhook.Name),
+				InvalidParam{Name: param + ".timeoutSeconds", Reason: fmt.Sprintf("should be between 0 and %d", maxWebhookTimeoutSeconds)})
+		}
+
+		switch webhook.FailurePolicy {
+		case "", core.FailurePolicyFail, core.FailurePolicyIgnore:
+		default:
+			return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("failure policy of webhook '%s' is not valid", webhook.Name),
+				InvalidParam{Name: param + ".failurePolicy", Reason: fmt.Sprintf("should be '%s' or '%s'", core.FailurePolicyFail, core.FailurePolicyIgnore)})
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) ListSchemasHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, SchemaList{Items: h.listSchemas()})
+	}
+}
+
+func (h *Handler) ReadSchemaHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
+
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		schema, ok := h.schemaOf(typ)
+		if !ok {
+			writeProblem(w, r, http.StatusNotFound, CodeSchemaNotFound, fmt.Sprintf("type '%s' has no schema", typ))
+
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, schema)
+	}
+}
+
+// PutSchemaHandler sets the schema of the type. Schemas are kept in memory, so ones that should outlive the
+// process belong in the schemas file.
+func (h *Handler) PutSchemaHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
+
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		var req core.Schema
+
+		err := decodeRequest(r, &req)
+		if err != nil {
+			writeDecodeProblem(w, r, err)
+
+			return
+		}
+
+		if req.Type == "" {
+			req.Type = typ
+		}
+
+		if req.Type != typ {
+			writeProblem(w, r, http.StatusBadRequest, CodeTypeMismatch, fmt.Sprintf("type field '%s' doesn't match type '%s'", req.Type, typ),
+				InvalidParam{Name: "type", Reason: fmt.Sprintf("should be '%s'", typ)})
+
+			return
+		}
+
+		created, err := h.putSchema(req)
+		if err != nil {
+			writeError(w, r, err)
+
+			return
+		}
+
+		if created {
+			writeResponse(w, r, http.StatusCreated, req)
+
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, req)
+	}
+}
+
+func (h *Handler) DeleteSchemaHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
+
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		if !h.deleteSchema(typ) {
+			writeProblem(w, r, http.StatusNotFound, CodeSchemaNotFound, fmt.Sprintf("type '%s' has no schema", typ))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,401 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nasermirzaei89/core/internal/core"
+	"github.com/pkg/errors"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Script hooks are functions of the script of a type that take items as dicts with uuid, type, name, data, labels,
+// annotations, createdAt and updatedAt keys. They reject writes by calling fail, and change data by changing the data
+// dict of the item.
+const (
+	// hookBeforeCreate is called with the item before it's created.
+	hookBeforeCreate = "beforeCreate"
+	// hookBeforeUpdate is called with the item and the stored item before the item is updated.
+	hookBeforeUpdate = "beforeUpdate"
+	// hookBeforeDelete is called with the stored item before it's deleted. It can't change it.
+	hookBeforeDelete = "beforeDelete"
+	// hookAfterWrite is called with the item once it's created or updated, for computing derived fields of its stored
+	// data. The fields it computes are saved with a follow-up write. It can't reject the write, which stays and is
+	// responded with if it fails.
+	hookAfterWrite = "afterWrite"
+)
+
+const (
+	maxScriptSize = 64 << 10
+	// maxScriptSteps bounds the computation of a script run. Its memory is bounded by the sandbox it runs in.
+	maxScriptSteps = 1000000
+	scriptTimeout  = time.Second
+	// maxScriptDataSize is the size of the json form of data that a script can leave to an item.
+	maxScriptDataSize = 1 << 20
+)
+
+// scriptHooks are the hooks a script can define.
+var scriptHooks = []string{hookBeforeCreate, hookBeforeUpdate, hookBeforeDelete, hookAfterWrite} //nolint:gochecknoglobals
+
+// newScriptThread returns a thread that runs scripts within the limits, which can't load other files or print. The
+// returned function should be called once the thread is done.
+func newScriptThread(name string) (*starlark.Thread, func()) {
+	thread := &starlark.Thread{
+		Name:  name,
+		Print: func(*starlark.Thread, string) {},
+		Load:  nil,
+	}
+
+	thread.SetMaxExecutionSteps(maxScriptSteps)
+
+	timer := time.AfterFunc(scriptTimeout, func() { thread.Cancel(fmt.Sprintf("timed out after %s", scriptTimeout)) })
+
+	return thread, func() { timer.Stop() }
+}
+
+// compiledScript is a script that ran once in a sandbox to define its hooks.
+type compiledScript struct {
+	source string
+	hooks  []string
+}
+
+func (s *compiledScript) has(hook string) bool {
+	return s != nil && containsString(s.hooks, hook)
+}
+
+// compileScript runs the script once in a sandbox to find its hooks.
+func compileScript(typ, src string) (*compiledScript, error) {
+	if len(src) > maxScriptSize {
+		return nil, errors.Errorf("script should be up to %d bytes", maxScriptSize)
+	}
+
+	res, err := runSandboxed(context.Background(), scriptRun{Type: typ, Script: src, Hook: "", Item: nil, Old: nil})
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Failed != "" {
+		return nil, errors.New(res.Failed)
+	}
+
+	return &compiledScript{source: src, hooks: res.Hooks}, nil
+}
+
+// execScript runs the script in the sandbox to define its hooks, and then calls the hook of the run, if it has one.
+func execScript(run scriptRun) scriptResult {
+	res := scriptResult{Hooks: nil, Data: nil, Rejected: "", Failed: ""}
+
+	thread, done := newScriptThread(run.Hook)
+	defer done()
+
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, run.Type+".star", run.Script, nil)
+	if err != nil {
+		res.Failed = errors.Wrap(err, "error on run script").Error()
+
+		return res
+	}
+
+	for _, hook := range scriptHooks {
+		v, ok := globals[hook]
+		if !ok {
+			continue
+		}
+
+		if _, ok := v.(*starlark.Function); !ok {
+			res.Failed = fmt.Sprintf("%s should be a function, not %s", hook, v.Type())
+
+			return res
+		}
+
+		res.Hooks = append(res.Hooks, hook)
+	}
+
+	fn, ok := globals[run.Hook]
+	if !ok || run.Item == nil {
+		return res
+	}
+
+	arg := itemValue(*run.Item)
+	args := starlark.Tuple{arg}
+
+	if run.Old != nil {
+		args = append(args, itemValue(*run.Old))
+	}
+
+	_, err = starlark.Call(thread, fn, args, nil)
+	if err != nil {
+		var evalErr *starlark.EvalError
+
+		if errors.As(err, &evalErr) && evalErr.Unwrap() != nil && strings.HasPrefix(evalErr.Unwrap().Error(), "fail: ") {
+			res.Rejected = strings.TrimPrefix(evalErr.Unwrap().Error(), "fail: ")
+		} else {
+			res.Failed = err.Error()
+		}
+
+		return res
+	}
+
+	res.Data, err = dataOf(arg)
+	if err != nil {
+		res.Failed = err.Error()
+	}
+
+	return res
+}
+
+func (h *Handler) scriptOf(typ string) *compiledScript {
+	h.schemasMu.RLock()
+	defer h.schemasMu.RUnlock()
+
+	return h.scripts[typ]
+}
+
+func (h *Handler) runBeforeHook(ctx context.Context, op core.Operation, old, item *core.Item) error {
+	switch op {
+	case core.OperationCreate:
+		return h.runHook(ctx, hookBeforeCreate, item, nil)
+	case core.OperationUpdate:
+		return h.runHook(ctx, hookBeforeUpdate, item, old)
+	case core.OperationDelete:
+		return h.runHook(ctx, hookBeforeDelete, old, nil)
+	default:
+		return nil
+	}
+}
+
+// runAfterWrite runs the afterWrite hook on the stored item, and saves the data it leaves if it changed, once it's
+// admitted like any update. The write is already stored, so failures are logged rather than returned, and item stays
+// as stored then.
+func (h *Handler) runAfterWrite(ctx context.Context, item *core.Item) {
+	err := h.deriveItem(ctx, item)
+	if err != nil {
+		log.Printf("error on run afterWrite hook of %s '%s': %s", item.Type, item.Name, err.Error())
+	}
+}
+
+func (h *Handler) deriveItem(ctx context.Context, item *core.Item) error {
+	if !h.scriptOf(item.Type).has(hookAfterWrite) {
+		return nil
+	}
+
+	derived := item.DeepCopy()
+
+	err := h.runHook(ctx, hookAfterWrite, &derived, nil)
+	if err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(item.Data, derived.Data) {
+		return nil
+	}
+
+	// derived data is checked like data of any update, but doesn't run the hook again
+	err = h.updateItem(ctx, *item, &derived)
+	if err != nil {
+		return err
+	}
+
+	*item = derived
+
+	return nil
+}
+
+// runHook calls the hook of the script of the type of the item in a sandbox, if it has one, and keeps the data it
+// leaves to the item, unless it's a delete hook.
+func (h *Handler) runHook(ctx context.Context, hook string, item, old *core.Item) error {
+	script := h.scriptOf(item.Type)
+	if !script.has(hook) {
+		return nil
+	}
+
+	res, err := runSandboxed(ctx, scriptRun{Type: item.Type, Script: script.source, Hook: hook, Item: item, Old: old})
+	if err != nil {
+		return scriptFailed(item.Type, hook, err.Error())
+	}
+
+	if res.Rejected != "" {
+		return newProblemError(http.StatusUnprocessableEntity, CodeScriptRejected,
+			fmt.Sprintf("script of type '%s' rejected the write: %s", item.Type, res.Rejected))
+	}
+
+	if res.Failed != "" {
+		return scriptFailed(item.Type, hook, res.Failed)
+	}
+
+	if hook == hookBeforeDelete {
+		return nil
+	}
+
+	item.Data = res.Data
+
+	return h.checkMutated(ctx, *item)
+}
+
+func scriptFailed(typ, hook, reason string) error {
+	return newProblemError(http.StatusInternalServerError, CodeScriptFailed, fmt.Sprintf("error on run %s hook of type '%s': %s", hook, typ, reason))
+}
+
+func itemValue(item core.Item) *starlark.Dict {
+	res := starlark.NewDict(8) //nolint:gomnd
+
+	_ = res.SetKey(starlark.String("uuid"), starlark.String(item.UUID))
+	_ = res.SetKey(starlark.String("type"), starlark.String(item.Type))
+	_ = res.SetKey(starlark.String("name"), starlark.String(item.Name))
+	_ = res.SetKey(starlark.String("data"), toStarlark(item.Data))
+	_ = res.SetKey(starlark.String("labels"), toStarlark(item.Labels))
+	_ = res.SetKey(starlark.String("annotations"), toStarlark(item.Annotations))
+	_ = res.SetKey(starlark.String("createdAt"), starlark.String(item.CreatedAt.Format(time.RFC3339)))
+	_ = res.SetKey(starlark.String("updatedAt"), starlark.String(item.UpdatedAt.Format(time.RFC3339)))
+
+	return res
+}
+
+// dataOf returns the data the script left to the item dict.
+func dataOf(item *starlark.Dict) (map[string]interface{}, error) {
+	v, _, _ := item.Get(starlark.String("data"))
+
+	data, err := fromStarlark(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "data is not valid")
+	}
+
+	res, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("data should be a dict")
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on marshal data")
+	}
+
+	if len(b) > maxScriptDataSize {
+		return nil, errors.Errorf("data should be up to %d bytes", maxScriptDataSize)
+	}
+
+	return res, nil
+}
+
+// toStarlark converts json values to starlark values. Whole numbers become ints.
+func toStarlark(v interface{}) starlark.Value {
+	switch v := v.(type) {
+	case nil:
+		return starlark.None
+	case bool:
+		return starlark.Bool(v)
+	case string:
+		return starlark.String(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return starlark.MakeInt64(int64(v))
+		}
+
+		return starlark.Float(v)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+
+		for k := range v {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		res := starlark.NewDict(len(v))
+
+		for _, k := range keys {
+			_ = res.SetKey(starlark.String(k), toStarlark(v[k]))
+		}
+
+		return res
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+
+		for k := range v {
+			m[k] = v[k]
+		}
+
+		return toStarlark(m)
+	case []interface{}:
+		res := make([]starlark.Value, 0, len(v))
+
+		for i := range v {
+			res = append(res, toStarlark(v[i]))
+		}
+
+		return starlark.NewList(res)
+	default:
+		return starlark.String(fmt.Sprint(v))
+	}
+}
+
+// fromStarlark converts starlark values to json values. Numbers become float64, like they're decoded from json.
+func fromStarlark(v starlark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Int:
+		return float64(v.Float()), nil
+	case starlark.Float:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return nil, errors.Errorf("number %s has no json form", v.String())
+		}
+
+		return float64(v), nil
+	case *starlark.Dict:
+		res := make(map[string]interface{}, v.Len())
+
+		for _, kv := range v.Items() {
+			k, ok := kv[0].(starlark.String)
+			if !ok {
+				return nil, errors.Errorf("dict key %s is not string", kv[0].String())
+			}
+
+			value, err := fromStarlark(kv[1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "value of '%s' is not valid", string(k))
+			}
+
+			res[string(k)] = value
+		}
+
+		return res, nil
+	case *starlark.List:
+		return fromStarlarkIterable(v, v.Len())
+	case starlark.Tuple:
+		return fromStarlarkIterable(v, v.Len())
+	default:
+		return nil, errors.Errorf("value of type %s has no json form", v.Type())
+	}
+}
+
+func fromStarlarkIterable(v starlark.Iterable, n int) ([]interface{}, error) {
+	res := make([]interface{}, 0, n)
+
+	iter := v.Iterate()
+	defer iter.Done()
+
+	var x starlark.Value
+
+	for iter.Next(&x) {
+		value, err := fromStarlark(x)
+		if err != nil {
+			return nil, errors.Wrapf(err, "element %d is not valid", len(res))
+		}
+
+		res = append(res, value)
+	}
+
+	return res, nil
+}
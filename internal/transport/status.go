@@ -0,0 +1,164 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/nasermirzaei89/core/internal/core"
+)
+
+// replaceStatus replaces status of the item, leaving the rest of it as it is.
+func (h *Handler) replaceStatus(ctx context.Context, typ, name string, status map[string]interface{}) (*core.Item, error) {
+	item, err := h.findItem(ctx, typ, name)
+	if err != nil {
+		return nil, err
+	}
+
+	original := item.DeepCopy()
+
+	item.Status = status
+	item.UpdatedAt = time.Now()
+
+	err = h.saveItem(ctx, original, item)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// patchStatus applies a json patch or a json merge patch to the json form of the item. Only changes to status are
+// kept.
+func (h *Handler) patchStatus(ctx context.Context, typ, name, patchType string, patch []byte) (*core.Item, error) {
+	if patchType != contentTypeJSONPatch && patchType != contentTypeMergePatch {
+		return nil, newProblemError(http.StatusUnsupportedMediaType, CodeMediaTypeUnsupported,
+			fmt.Sprintf("unsupported Content-Type header, it should be '%s' or '%s'", contentTypeJSONPatch, contentTypeMergePatch))
+	}
+
+	item, err := h.findItem(ctx, typ, name)
+	if err != nil {
+		return nil, err
+	}
+
+	original := item.DeepCopy()
+
+	modified, err := patchItemJSON(item, patchType, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Status = modified.Status
+	item.UpdatedAt = time.Now()
+
+	err = h.saveItem(ctx, original, item)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (h *Handler) ReadStatusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
+
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		name, ok := nameFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		item, err := h.findItem(r.Context(), typ, name)
+		if err != nil {
+			writeError(w, r, err)
+
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, item)
+	}
+}
+
+// ReplaceStatusHandler replaces status of the item with status of the request body. Other fields of the body are
+// ignored, like status is by the item endpoints.
+func (h *Handler) ReplaceStatusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
+
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		name, ok := nameFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		var req core.Item
+
+		err := decodeRequest(r, &req)
+		if err != nil {
+			writeDecodeProblem(w, r, err)
+
+			return
+		}
+
+		item, err := h.replaceStatus(r.Context(), typ, name, req.Status)
+		if err != nil {
+			writeError(w, r, err)
+
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, item)
+	}
+}
+
+func (h *Handler) PatchStatusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
+
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		name, ok := nameFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		requestBody, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeBodyInvalid, fmt.Sprintf("error on read request body: %s", err.Error()))
+
+			return
+		}
+
+		patchType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+		item, err := h.patchStatus(r.Context(), typ, name, patchType, requestBody)
+		if err != nil {
+			writeError(w, r, err)
+
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, item)
+	}
+}
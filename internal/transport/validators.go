@@ -2,10 +2,16 @@ package transport
 
 import (
 	"regexp"
+	"strings"
 
 	"github.com/nasermirzaei89/core/internal/core"
 )
 
+const (
+	maxLabelNameLength   = 63
+	maxLabelPrefixLength = 253
+)
+
 func isValidName(name string) bool {
 	return regexp.MustCompile(core.NameRegex).MatchString(name)
 }
@@ -13,3 +19,21 @@ func isValidName(name string) bool {
 func isValidType(typ string) bool {
 	return regexp.MustCompile(core.TypeRegex).MatchString(typ)
 }
+
+func isValidLabelKey(key string) bool {
+	name := key
+
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		if i > maxLabelPrefixLength {
+			return false
+		}
+
+		name = key[i+1:]
+	}
+
+	return len(name) <= maxLabelNameLength && regexp.MustCompile(core.LabelKeyRegex).MatchString(key)
+}
+
+func isValidLabelValue(value string) bool {
+	return len(value) <= maxLabelNameLength && regexp.MustCompile(core.LabelValueRegex).MatchString(value)
+}
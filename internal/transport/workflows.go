@@ -0,0 +1,376 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/nasermirzaei89/core/internal/core"
+)
+
+// maxTransitionHistory is the number of transitions kept on an item. Older ones are dropped.
+const maxTransitionHistory = 100
+
+// TransitionList is the response of listing transitions of an item.
+type TransitionList struct {
+	Items []core.TransitionRecord `json:"items"`
+}
+
+// compiledWorkflow is a workflow with programs of its guards, by index of their transitions.
+type compiledWorkflow struct {
+	workflow core.Workflow
+	guards   []cel.Program
+}
+
+type rolesKey struct{}
+
+func withRoles(ctx context.Context, roles []string) context.Context {
+	return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+func rolesOf(ctx context.Context) []string {
+	roles, _ := ctx.Value(rolesKey{}).([]string)
+
+	return roles
+}
+
+// rolesFromRequest returns the roles of the comma separated X-Roles headers of the request. They're trusted as they
+// are, so a proxy that authenticates requests should set them.
+func rolesFromRequest(r *http.Request) []string {
+	res := make([]string, 0)
+
+	for _, value := range r.Header.Values("X-Roles") {
+		for _, role := range strings.Split(value, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				res = append(res, role)
+			}
+		}
+	}
+
+	return res
+}
+
+func checkWorkflow(workflow *core.Workflow) error {
+	if workflow.Field == "" {
+		return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, "workflow field is required",
+			InvalidParam{Name: "workflow.field", Reason: "is required"})
+	}
+
+	if containsString(core.ReservedFields(), workflow.Field) {
+		return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("workflow field '%s' is reserved", workflow.Field),
+			InvalidParam{Name: "workflow.field", Reason: "should be a data field"})
+	}
+
+	if len(workflow.States) == 0 {
+		return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, "workflow states are required",
+			InvalidParam{Name: "workflow.states", Reason: "is required"})
+	}
+
+	states := make(map[string]struct{}, len(workflow.States))
+
+	for i, state := range workflow.States {
+		if _, ok := states[state]; ok || state == "" {
+			return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("workflow state '%s' is not valid", state),
+				InvalidParam{Name: fmt.Sprintf("workflow.states.%d", i), Reason: "should be a unique non-empty string"})
+		}
+
+		states[state] = struct{}{}
+	}
+
+	if _, ok := states[workflow.Initial]; !ok {
+		return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("workflow initial state '%s' is not a state", workflow.Initial),
+			InvalidParam{Name: "workflow.initial", Reason: "should be one of the states"})
+	}
+
+	for i, transition := range workflow.Transitions {
+		for j, from := range transition.From {
+			if _, ok := states[from]; !ok {
+				return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("workflow transition from '%s' is not from a state", from),
+					InvalidParam{Name: fmt.Sprintf("workflow.transitions.%d.from.%d", i, j), Reason: "should be one of the states"})
+			}
+		}
+
+		if _, ok := states[transition.To]; !ok {
+			return newProblemError(http.StatusUnprocessableEntity, CodeSchemaInvalid, fmt.Sprintf("workflow transition to '%s' is not to a state", transition.To),
+				InvalidParam{Name: fmt.Sprintf("workflow.transitions.%d.to", i), Reason: "should be one of the states"})
+		}
+	}
+
+	return nil
+}
+
+// compileWorkflow type checks the guards of the workflow, like rules.
+func compileWorkflow(workflow *core.Workflow) (*compiledWorkflow, error) {
+	if workflow == nil {
+		return nil, nil
+	}
+
+	_, env, err := ruleEnvs()
+	if err != nil {
+		return nil, err
+	}
+
+	res := compiledWorkflow{
+		workflow: *workflow,
+		guards:   make([]cel.Program, len(workflow.Transitions)),
+	}
+
+	for i, transition := range workflow.Transitions {
+		if transition.Guard == "" {
+			continue
+		}
+
+		name := fmt.Sprintf("guard of transition to '%s'", transition.To)
+
+		res.guards[i], err = compileRule(env, name, transition.Guard, fmt.Sprintf("workflow.transitions.%d.guard", i))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &res, nil
+}
+
+func (h *Handler) workflowOf(typ string) *compiledWorkflow {
+	h.schemasMu.RLock()
+	defer h.schemasMu.RUnlock()
+
+	return h.workflows[typ]
+}
+
+// defaultState sets the workflow field of new items that have none to the initial state, so hooks and webhooks see it.
+func (h *Handler) defaultState(item *core.Item) {
+	cw := h.workflowOf(item.Type)
+	if cw == nil {
+		return
+	}
+
+	if _, ok := item.Data[cw.workflow.Field]; ok {
+		return
+	}
+
+	if item.Data == nil {
+		item.Data = make(map[string]interface{})
+	}
+
+	item.Data[cw.workflow.Field] = cw.workflow.Initial
+}
+
+// checkTransition checks the change of the workflow state of the item, and adds it to the transitions of the item.
+// New items should be in the initial state, and entering it is their first transition. Old is nil on creates.
+func (h *Handler) checkTransition(ctx context.Context, old, item *core.Item) error {
+	cw := h.workflowOf(item.Type)
+	if cw == nil {
+		return nil
+	}
+
+	field := cw.workflow.Field
+	to := item.Data[field]
+
+	if old == nil {
+		if to != cw.workflow.Initial {
+			return newProblemError(http.StatusConflict, CodeTransitionInvalid,
+				fmt.Sprintf("%s items start in state '%s', not '%v'", item.Type, cw.workflow.Initial, to),
+				InvalidParam{Name: field, Reason: fmt.Sprintf("should be '%s'", cw.workflow.Initial)})
+		}
+
+		// imported items keep the transitions they had
+		if len(item.Transitions) == 0 {
+			recordTransition(ctx, item, "", cw.workflow.Initial)
+		}
+
+		return nil
+	}
+
+	from, hasState := old.Data[field]
+	if hasState && from == to {
+		return nil
+	}
+
+	toState, _ := to.(string)
+	fromState, _ := from.(string)
+
+	err := cw.allow(ctx, old, item, hasState, fromState, toState)
+	if err != nil {
+		return err
+	}
+
+	recordTransition(ctx, item, fromState, toState)
+
+	return nil
+}
+
+// recordTransition adds the transition to the transitions of the item, dropping the oldest ones past the limit.
+func recordTransition(ctx context.Context, item *core.Item, from, to string) {
+	item.Transitions = append(item.Transitions, core.TransitionRecord{From: from, To: to, Actor: actorOf(ctx), At: time.Now()})
+
+	if len(item.Transitions) > maxTransitionHistory {
+		item.Transitions = append([]core.TransitionRecord{}, item.Transitions[len(item.Transitions)-maxTransitionHistory:]...)
+	}
+}
+
+// allow finds a transition from the state to the other one that the roles of the request can make and whose guard is
+// satisfied. Items from before the workflow, which have no state, can only move to the initial state.
+func (cw *compiledWorkflow) allow(ctx context.Context, old, item *core.Item, hasState bool, from, to string) error {
+	invalid := newProblemError(http.StatusConflict, CodeTransitionInvalid,
+		fmt.Sprintf("%s '%s' can't move from state '%s' to '%s'", item.Type, item.Name, from, to),
+		InvalidParam{Name: cw.workflow.Field, Reason: fmt.Sprintf("can't move from '%s' to '%s'", from, to)})
+
+	if !hasState {
+		if to == cw.workflow.Initial {
+			return nil
+		}
+
+		return invalid
+	}
+
+	var res error = invalid
+
+	for i, transition := range cw.workflow.Transitions {
+		if transition.To != to || (len(transition.From) != 0 && !containsString(transition.From, from)) {
+			continue
+		}
+
+		if len(transition.Roles) != 0 && !hasAnyRole(rolesOf(ctx), transition.Roles) {
+			res = newProblemError(http.StatusForbidden, CodeTransitionForbidden,
+				fmt.Sprintf("moving %s '%s' from state '%s' to '%s' needs one of roles %s", item.Type, item.Name, from, to, strings.Join(transition.Roles, ", ")))
+
+			continue
+		}
+
+		if cw.guards[i] != nil {
+			out, _, err := cw.guards[i].ContextEval(ctx, map[string]interface{}{"self": item.Data, "oldSelf": old.Data})
+			if err != nil || out.Value() != true {
+				res = newProblemError(http.StatusConflict, CodeTransitionInvalid,
+					fmt.Sprintf("%s '%s' can't move from state '%s' to '%s', as it doesn't satisfy '%s'", item.Type, item.Name, from, to, transition.Guard),
+					InvalidParam{Name: cw.workflow.Field, Reason: fmt.Sprintf("should satisfy '%s'", transition.Guard)})
+
+				continue
+			}
+		}
+
+		return nil
+	}
+
+	return res
+}
+
+func hasAnyRole(roles, allowed []string) bool {
+	for _, role := range roles {
+		if containsString(allowed, role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// transitionItem moves the item to the state, like an update of its workflow field.
+func (h *Handler) transitionItem(ctx context.Context, typ, name, to string, fw fieldWriter) (*core.Item, error) {
+	cw := h.workflowOf(typ)
+	if cw == nil {
+		return nil, newProblemError(http.StatusNotFound, CodeWorkflowNotFound, fmt.Sprintf("type '%s' has no workflow", typ))
+	}
+
+	item, err := h.findItem(ctx, typ, name)
+	if err != nil {
+		return nil, err
+	}
+
+	original := item.DeepCopy()
+
+	data := item.DeepCopy().Data
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+
+	data[cw.workflow.Field] = to
+
+	err = fw.write(item, data)
+	if err != nil {
+		return nil, err
+	}
+
+	item.UpdatedAt = time.Now()
+
+	err = h.saveItem(ctx, original, item)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// TransitionItemHandler moves the item to the state of the to parameter.
+func (h *Handler) TransitionItemHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
+
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		name, ok := nameFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		// the workflow decides who changes the state, so transitions take the field over from its manager
+		fw := fieldWriter{manager: r.URL.Query().Get("fieldManager"), force: true}
+
+		to := r.URL.Query().Get("to")
+		if to == "" {
+			writeProblem(w, r, http.StatusBadRequest, CodeParamInvalid, "to parameter is required",
+				InvalidParam{Name: "to", Reason: "is required"})
+
+			return
+		}
+
+		item, err := h.transitionItem(r.Context(), typ, name, to, fw)
+		if err != nil {
+			writeError(w, r, err)
+
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, item)
+	}
+}
+
+func (h *Handler) ListTransitionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := acceptable(w, r); !ok {
+			return
+		}
+
+		typ, ok := typeFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		name, ok := nameFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		item, err := h.findItem(r.Context(), typ, name)
+		if err != nil {
+			writeError(w, r, err)
+
+			return
+		}
+
+		res := TransitionList{Items: item.Transitions}
+		if res.Items == nil {
+			res.Items = make([]core.TransitionRecord, 0)
+		}
+
+		writeResponse(w, r, http.StatusOK, res)
+	}
+}
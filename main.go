@@ -1,21 +1,186 @@
 package main
 
 import (
+	"context"
+	"io"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/nasermirzaei89/core/internal/repository"
+	"github.com/nasermirzaei89/core/internal/repository/fs"
 	"github.com/nasermirzaei89/core/internal/repository/memory"
 	"github.com/nasermirzaei89/core/internal/transport"
 	"github.com/nasermirzaei89/env"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc"
 )
 
 func main() {
-	repo := memory.NewItemRepository()
+	repo, err := newItemRepository()
+	if err != nil {
+		panic(errors.Wrap(err, "error on create item repository"))
+	}
+
+	defer func() { _ = repo.Close() }()
 
 	h := transport.New(repo)
 
-	err := http.ListenAndServe(env.GetString("API_ADDRESS", ":80"), h)
+	defer func() { _ = h.Close() }()
+
+	err = loadSchemas(h, env.GetString("SCHEMAS_FILE", ""))
 	if err != nil {
+		panic(errors.Wrap(err, "error on load schemas"))
+	}
+
+	srv := &http.Server{
+		Addr:    env.GetString("API_ADDRESS", ":80"),
+		Handler: h,
+	}
+
+	// the gRPC API is opt-in, so the REST API runs alone unless GRPC_ADDRESS is set
+	grpcSrv, err := serveGRPC(h, env.GetString("GRPC_ADDRESS", ""))
+	if err != nil {
+		panic(errors.Wrap(err, "error on serve grpc"))
+	}
+
+	shutdown := make(chan struct{})
+
+	go func() {
+		defer close(shutdown)
+
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		<-sig
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_ = srv.Shutdown(ctx)
+
+		if grpcSrv != nil {
+			stopGRPC(ctx, grpcSrv)
+		}
+	}()
+
+	err = srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		panic(errors.Wrap(err, "error on listen and serve http"))
 	}
+
+	<-shutdown
+}
+
+// serveGRPC serves the gRPC API of the handler on the address in the background. There is no server if the address is
+// empty.
+func serveGRPC(h *transport.Handler, addr string) (*grpc.Server, error) {
+	if addr == "" {
+		return nil, nil
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on listen grpc address")
+	}
+
+	srv := grpc.NewServer()
+	h.RegisterGRPC(srv)
+
+	go func() {
+		err := srv.Serve(lis)
+		if err != nil {
+			panic(errors.Wrap(err, "error on serve grpc"))
+		}
+	}()
+
+	return srv, nil
+}
+
+// stopGRPC stops the server gracefully, cutting open streams like watches when ctx is done.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		srv.GracefulStop()
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
+// loadSchemas sets the schemas of the YAML file at path, if there is one.
+func loadSchemas(h *transport.Handler, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "error on open schemas file")
+	}
+
+	defer func() { _ = f.Close() }()
+
+	return h.LoadSchemas(f)
+}
+
+type itemRepository interface {
+	repository.ItemRepository
+	io.Closer
+}
+
+func newItemRepository() (itemRepository, error) {
+	switch storage := env.GetString("STORAGE", "memory"); storage {
+	case "memory":
+		return newMemoryItemRepository()
+	case "fs":
+		repo, err := fs.NewItemRepository(fs.Options{
+			Root:       env.GetString("FS_ROOT", "data"),
+			WatchFiles: env.GetBool("FS_WATCH", false),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "error on open filesystem item repository")
+		}
+
+		return repo, nil
+	default:
+		return nil, errors.Errorf("invalid storage '%s'", storage)
+	}
+}
+
+func newMemoryItemRepository() (itemRepository, error) {
+	dataDir := env.GetString("DATA_DIR", "")
+	if dataDir == "" {
+		return memory.NewItemRepository(), nil
+	}
+
+	syncInterval, err := time.ParseDuration(env.GetString("DATA_SYNC_INTERVAL", "1s"))
+	if err != nil {
+		return nil, errors.Wrap(err, "error on parse DATA_SYNC_INTERVAL")
+	}
+
+	snapshotInterval, err := time.ParseDuration(env.GetString("DATA_SNAPSHOT_INTERVAL", "5m"))
+	if err != nil {
+		return nil, errors.Wrap(err, "error on parse DATA_SNAPSHOT_INTERVAL")
+	}
+
+	repo, err := memory.OpenItemRepository(memory.LogOptions{
+		Dir:              dataDir,
+		SyncPolicy:       memory.SyncPolicy(env.GetString("DATA_SYNC_POLICY", string(memory.SyncAlways))),
+		SyncInterval:     syncInterval,
+		SnapshotInterval: snapshotInterval,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error on open item repository")
+	}
+
+	return repo, nil
 }
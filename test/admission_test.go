@@ -0,0 +1,298 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestAdmissionWebhooks(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+
+	reviews := make([]transport.AdmissionReview, 0)
+
+	validator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var review transport.AdmissionReview
+
+		_ = json.NewDecoder(r.Body).Decode(&review)
+
+		mu.Lock()
+		reviews = append(reviews, review)
+		mu.Unlock()
+
+		rsp := transport.AdmissionResponse{Allowed: true}
+
+		if review.Object != nil {
+			if total, _ := review.Object.Data["total"].(float64); total < 0 {
+				rsp = transport.AdmissionResponse{Allowed: false, Message: "total should not be negative"}
+			}
+		}
+
+		if review.Operation == "DELETE" && strings.HasPrefix(review.Name, "keep-") {
+			rsp = transport.AdmissionResponse{Allowed: false, Message: "kept"}
+		}
+
+		_ = json.NewEncoder(w).Encode(rsp)
+	}))
+	defer validator.Close()
+
+	mutator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var review transport.AdmissionReview
+
+		_ = json.NewDecoder(r.Body).Decode(&review)
+
+		rsp := transport.AdmissionResponse{Allowed: true}
+
+		if _, ok := review.Object.Data["currency"]; !ok {
+			rsp.Patch = []interface{}{map[string]interface{}{"op": "add", "path": "/currency", "value": "EUR"}}
+		}
+
+		_ = json.NewEncoder(w).Encode(rsp)
+	}))
+	defer mutator.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1500 * time.Millisecond)
+
+		_ = json.NewEncoder(w).Encode(transport.AdmissionResponse{Allowed: false, Message: "too late"})
+	}))
+	defer slow.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	h := transport.New(memory.NewItemRepository())
+
+	err := h.LoadSchemas(strings.NewReader(fmt.Sprintf(`
+- type: order
+  webhooks:
+    - name: check-total
+      kind: Validating
+      url: %s
+    - name: default-currency
+      kind: Mutating
+      url: %s
+      operations: [CREATE]
+- type: note
+  webhooks:
+    - name: slow
+      kind: Validating
+      url: %s
+      timeoutSeconds: 1
+      failurePolicy: Ignore
+- type: invoice
+  webhooks:
+    - name: down
+      kind: Validating
+      url: %s
+      operations: [DELETE]
+- type: ticket
+  references:
+    account:
+      type: account
+      onDelete: Cascade
+  webhooks:
+    - name: check-total
+      kind: Validating
+      url: %[1]s
+- type: memo
+  references:
+    account:
+      type: account
+      onDelete: SetNull
+  webhooks:
+    - name: check-total
+      kind: Validating
+      url: %[1]s
+- type: task
+  webhooks:
+    - name: check-total
+      kind: Validating
+      url: %[1]s
+`, validator.URL, mutator.URL, slow.URL, down.URL)))
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	send := newSender(srv)
+
+	reviewed := func(op, typ, name string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, review := range reviews {
+			if string(review.Operation) == op && review.Type == typ && review.Name == name {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	t.Run("Invalid Webhook", func(t *testing.T) {
+		status, res := send(t, http.MethodPut, "/_schemas/carts", "application/json", `{"webhooks": [{"name": "check", "kind": "Validating", "url": "not-a-url"}]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "webhooks.0.url", gjson.GetBytes(res, "invalidParams.0.name").String())
+	})
+
+	t.Run("Mutate", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-0", "total": 10}`, "X-User", "shop")
+		require.Equal(t, http.StatusCreated, status, string(res))
+		assert.Equal(t, "EUR", gjson.GetBytes(res, "currency").String())
+
+		status, res = send(t, http.MethodGet, "/orders/order-0", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "EUR", gjson.GetBytes(res, "currency").String())
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		require.NotEmpty(t, reviews)
+
+		last := reviews[len(reviews)-1]
+		assert.Equal(t, "CREATE", string(last.Operation))
+		assert.Equal(t, "shop", last.Actor)
+		assert.Nil(t, last.OldObject)
+		// validating webhooks see the mutated item
+		assert.Equal(t, "EUR", last.Object.Data["currency"])
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		status, res := send(t, http.MethodPatch, "/orders/order-0", "application/merge-patch+json", `{"total": -1}`)
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, transport.CodeAdmissionDenied, gjson.GetBytes(res, "code").String())
+		assert.Contains(t, gjson.GetBytes(res, "detail").String(), "total should not be negative")
+
+		status, res = send(t, http.MethodGet, "/orders/order-0", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, int64(10), gjson.GetBytes(res, "total").Int())
+
+		mu.Lock()
+		last := reviews[len(reviews)-1]
+		mu.Unlock()
+
+		assert.Equal(t, "UPDATE", string(last.Operation))
+		require.NotNil(t, last.OldObject)
+		assert.Equal(t, 10.0, last.OldObject.Data["total"])
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		status, _ := send(t, http.MethodDelete, "/orders/order-0", "", "")
+		assert.Equal(t, http.StatusNoContent, status)
+
+		mu.Lock()
+		last := reviews[len(reviews)-1]
+		mu.Unlock()
+
+		assert.Equal(t, "DELETE", string(last.Operation))
+		assert.Nil(t, last.Object)
+		assert.Equal(t, "order-0", last.OldObject.Name)
+	})
+
+	t.Run("Fail Open On Timeout", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/notes", "application/json", `{"name": "note-0"}`)
+		assert.Equal(t, http.StatusCreated, status, string(res))
+	})
+
+	t.Run("Fail Closed", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/invoices", "application/json", `{"name": "invoice-0"}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+
+		status, res = send(t, http.MethodDelete, "/invoices/invoice-0", "", "")
+		assert.Equal(t, http.StatusBadGateway, status)
+		assert.Equal(t, transport.CodeAdmissionFailed, gjson.GetBytes(res, "code").String())
+
+		status, _ = send(t, http.MethodGet, "/invoices/invoice-0", "", "")
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("Import Prune", func(t *testing.T) {
+		// pruned items are admitted like deleted ones
+		status, res := send(t, http.MethodPost, "/invoices/_import?mode=replace-all", "application/x-ndjson", `{"name": "invoice-1"}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.EqualValues(t, 0, gjson.GetBytes(res, "deleted").Int())
+		assert.Equal(t, "invoice-0", gjson.GetBytes(res, "errors.0.name").String())
+		assert.Equal(t, transport.CodeAdmissionFailed, gjson.GetBytes(res, "errors.0.code").String())
+
+		status, _ = send(t, http.MethodGet, "/invoices/invoice-0", "", "")
+		assert.Equal(t, http.StatusOK, status)
+	})
+	t.Run("Cascades", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/accounts", "application/json", `{"name": "account-0"}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+
+		accountUUID := gjson.GetBytes(res, "uuid").String()
+
+		for _, item := range []struct{ path, body string }{
+			{"/tickets", `{"name": "ticket-0", "account": "account-0"}`},
+			{"/memos", `{"name": "memo-0", "account": "account-0"}`},
+			{"/tasks", `{"name": "task-0", "metadata": {"ownerReferences": [{"type": "account", "name": "account-0", "uuid": "` + accountUUID + `"}]}}`},
+		} {
+			status, res = send(t, http.MethodPost, item.path, "application/json", item.body)
+			require.Equal(t, http.StatusCreated, status, string(res))
+		}
+
+		status, _ = send(t, http.MethodDelete, "/accounts/account-0", "", "")
+		require.Equal(t, http.StatusNoContent, status)
+
+		// cascaded deletes, references set to null and collected dependents are admitted like other writes
+		assert.True(t, reviewed("DELETE", "ticket", "ticket-0"))
+		assert.True(t, reviewed("UPDATE", "memo", "memo-0"))
+		assert.Eventually(t, func() bool { return reviewed("DELETE", "task", "task-0") }, 5*time.Second, 10*time.Millisecond)
+
+		status, res = send(t, http.MethodGet, "/memos/memo-0", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "null", gjson.GetBytes(res, "account").Raw)
+	})
+
+	t.Run("Rejected Cascade", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/accounts", "application/json", `{"name": "account-1"}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+
+		status, res = send(t, http.MethodPost, "/tickets", "application/json", `{"name": "keep-0", "account": "account-1"}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+
+		status, res = send(t, http.MethodDelete, "/accounts/account-1", "", "")
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, transport.CodeAdmissionDenied, gjson.GetBytes(res, "code").String())
+
+		status, _ = send(t, http.MethodGet, "/accounts/account-1", "", "")
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("Finalized Delete", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/tasks", "application/json", `{"name": "task-1", "metadata": {"finalizers": ["example.com/audit"]}}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+
+		status, _ = send(t, http.MethodDelete, "/tasks/task-1", "", "")
+		require.Equal(t, http.StatusAccepted, status)
+
+		mu.Lock()
+		reviews = reviews[:0]
+		mu.Unlock()
+
+		// removing the last finalizer deletes the item, which is admitted as a delete
+		status, res = send(t, http.MethodPatch, "/tasks/task-1", "application/merge-patch+json", `{"metadata": {"finalizers": null}}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+
+		assert.True(t, reviewed("UPDATE", "task", "task-1"))
+		assert.True(t, reviewed("DELETE", "task", "task-1"))
+
+		status, _ = send(t, http.MethodGet, "/tasks/task-1", "", "")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
@@ -0,0 +1,139 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(transport.New(memory.NewItemRepository()))
+	defer srv.Close()
+
+	patch := func(t *testing.T, name, contentType, body string) (int, []byte) {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPatch, srv.URL+"/drinks/"+name, bytes.NewBufferString(body))
+		require.NoError(t, err)
+
+		req.Header.Set("Content-Type", contentType)
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		defer func() { _ = rsp.Body.Close() }()
+
+		res, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+
+		return rsp.StatusCode, res
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		reqBody := bytes.NewBufferString(`{"type": "drink", "name": "tea", "price": 2, "sizes": {"small": 1, "large": 2}, "tags": ["hot"]}`)
+		rsp, err := http.Post(srv.URL+"/_apply", "application/json", reqBody)
+		require.NoError(t, err)
+
+		defer func() { _ = rsp.Body.Close() }()
+
+		assert.Equal(t, http.StatusCreated, rsp.StatusCode)
+
+		res, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "tea", gjson.GetBytes(res, "name").String())
+		assert.Equal(t, 2.0, gjson.GetBytes(res, "price").Float())
+		assert.JSONEq(t, `{"price": 2, "sizes": {"small": 1, "large": 2}, "tags": ["hot"]}`, gjson.GetBytes(res, "metadata.lastApplied.data").Raw)
+	})
+
+	t.Run("Three Way Merge", func(t *testing.T) {
+		status, _ := patch(t, "tea", "application/merge-patch+json", `{"origin": "china", "sizes": {"medium": 1.5}}`)
+		require.Equal(t, http.StatusOK, status)
+
+		status, res := patch(t, "tea", "application/apply-patch+yaml", "type: drink\nname: tea\nprice: 3\nsizes:\n  small: 1\n")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 3.0, gjson.GetBytes(res, "price").Float())
+		assert.JSONEq(t, `{"small": 1, "medium": 1.5}`, gjson.GetBytes(res, "sizes").Raw)
+		assert.False(t, gjson.GetBytes(res, "tags").Exists())
+		assert.Equal(t, "china", gjson.GetBytes(res, "origin").String())
+		assert.JSONEq(t, `{"price": 3, "sizes": {"small": 1}}`, gjson.GetBytes(res, "metadata.lastApplied.data").Raw)
+
+		rsp, err := http.Get(srv.URL + "/drinks/tea")
+		require.NoError(t, err)
+
+		defer func() { _ = rsp.Body.Close() }()
+
+		res2, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(res), string(res2))
+	})
+
+	t.Run("Labels", func(t *testing.T) {
+		status, _ := patch(t, "juice", "application/apply-patch+yaml", "metadata:\n  labels:\n    tier: gold\n    season: summer\n  annotations:\n    note: fresh\n")
+		require.Equal(t, http.StatusCreated, status)
+
+		status, _ = patch(t, "juice", "application/merge-patch+json", `{"metadata": {"labels": {"owner": "bar"}}}`)
+		require.Equal(t, http.StatusOK, status)
+
+		status, res := patch(t, "juice", "application/apply-patch+yaml", "metadata:\n  labels:\n    tier: silver\n")
+		assert.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"tier": "silver", "owner": "bar"}`, gjson.GetBytes(res, "metadata.labels").Raw)
+		assert.False(t, gjson.GetBytes(res, "metadata.annotations").Exists())
+		assert.JSONEq(t, `{"tier": "silver"}`, gjson.GetBytes(res, "metadata.lastApplied.labels").Raw)
+	})
+
+	t.Run("Item Not Applied Before", func(t *testing.T) {
+		rsp, err := http.Post(srv.URL+"/drinks", "application/json", bytes.NewBufferString(`{"name": "coffee", "price": 3, "origin": "brazil"}`))
+		require.NoError(t, err)
+
+		_ = rsp.Body.Close()
+
+		status, res := patch(t, "coffee", "application/apply-patch+yaml", `{"price": 4}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 4.0, gjson.GetBytes(res, "price").Float())
+		assert.Equal(t, "brazil", gjson.GetBytes(res, "origin").String())
+
+		status, res = patch(t, "milk", "application/apply-patch+yaml", "price: 1\ncold: null\n")
+		assert.Equal(t, http.StatusCreated, status)
+		assert.Equal(t, 1.0, gjson.GetBytes(res, "price").Float())
+		assert.False(t, gjson.GetBytes(res, "cold").Exists())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		status, res := patch(t, "tea", "application/apply-patch+yaml", "name: coffee\n")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, transport.CodeBodyInvalid, gjson.GetBytes(res, "code").String())
+
+		status, res = patch(t, "tea", "application/apply-patch+yaml", "type: food\n")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, transport.CodeTypeMismatch, gjson.GetBytes(res, "code").String())
+
+		status, res = patch(t, "tea", "application/apply-patch+yaml", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, transport.CodeBodyInvalid, gjson.GetBytes(res, "code").String())
+
+		status, res = patch(t, "tea", "text/plain", "price: 1")
+		assert.Equal(t, http.StatusUnsupportedMediaType, status)
+		assert.Equal(t, transport.CodeMediaTypeUnsupported, gjson.GetBytes(res, "code").String())
+
+		rsp, err := http.Post(srv.URL+"/_apply", "application/json", bytes.NewBufferString(`{"type": "Drink", "name": "tea"}`))
+		require.NoError(t, err)
+
+		defer func() { _ = rsp.Body.Close() }()
+
+		assert.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+
+		res, err = io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, transport.CodeTypeInvalid, gjson.GetBytes(res, "code").String())
+	})
+}
@@ -0,0 +1,233 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.NewItemRepository()
+
+	h := transport.New(repo)
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	for _, body := range []string{
+		`{"name": "tea", "drinkType": "Hot Drinks"}`,
+		`{"name": "coffee", "drinkType": "Hot Drinks"}`,
+	} {
+		rsp, err := http.Post(srv.URL+"/drinks", "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		_ = rsp.Body.Close()
+	}
+
+	rsp, err := http.Post(srv.URL+"/foods", "application/json", bytes.NewBufferString(`{"name": "pizza"}`))
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+
+	t.Run("Type", func(t *testing.T) {
+		rsp, err := http.Get(srv.URL + "/drinks/_export")
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		assert.Equal(t, http.StatusOK, rsp.StatusCode)
+		assert.Equal(t, "application/x-ndjson", rsp.Header.Get("Content-Type"))
+
+		lines := readLines(t, rsp.Body)
+		require.Len(t, lines, 2)
+		assert.Equal(t, "coffee", gjson.Get(lines[0], "name").String())
+		assert.Equal(t, "tea", gjson.Get(lines[1], "name").String())
+		assert.Equal(t, "Hot Drinks", gjson.Get(lines[1], "drinkType").String())
+	})
+
+	t.Run("All", func(t *testing.T) {
+		rsp, err := http.Get(srv.URL + "/_export")
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		assert.Equal(t, http.StatusOK, rsp.StatusCode)
+
+		lines := readLines(t, rsp.Body)
+		require.Len(t, lines, 3)
+		assert.Equal(t, "drink", gjson.Get(lines[0], "type").String())
+		assert.Equal(t, "food", gjson.Get(lines[2], "type").String())
+	})
+
+	t.Run("Invalid type", func(t *testing.T) {
+		rsp, err := http.Get(srv.URL + "/drink/_export")
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		assert.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	})
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Create Only", func(t *testing.T) {
+		t.Parallel()
+
+		repo := memory.NewItemRepository()
+
+		h := transport.New(repo)
+
+		srv := httptest.NewServer(h)
+		defer srv.Close()
+
+		rsp, err := http.Post(srv.URL+"/drinks", "application/json", bytes.NewBufferString(`{"name": "tea"}`))
+		require.NoError(t, err)
+		_ = rsp.Body.Close()
+
+		reqBody := bytes.NewBufferString(`{"name": "tea", "drinkType": "Hot Drinks"}
+{"name": "coffee", "uuid": "5d1a4f3e-0c55-4c53-9d8e-3a4f0c1b2a10", "drinkType": "Hot Drinks"}
+
+not json
+{"name": "Herbal Tea"}
+{"name": "pizza", "type": "food"}
+`)
+		rsp2, err := http.Post(srv.URL+"/drinks/_import", "application/x-ndjson", reqBody)
+		require.NoError(t, err)
+		defer func() { _ = rsp2.Body.Close() }()
+
+		assert.Equal(t, http.StatusOK, rsp2.StatusCode)
+
+		res2, err := io.ReadAll(rsp2.Body)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, gjson.GetBytes(res2, "created").Int())
+		assert.EqualValues(t, 0, gjson.GetBytes(res2, "updated").Int())
+		assert.EqualValues(t, 4, gjson.GetBytes(res2, "failed").Int())
+		assert.EqualValues(t, 1, gjson.GetBytes(res2, "errors.0.line").Int())
+		assert.Equal(t, transport.CodeItemAlreadyExists, gjson.GetBytes(res2, "errors.0.code").String())
+		assert.EqualValues(t, 4, gjson.GetBytes(res2, "errors.1.line").Int())
+		assert.Equal(t, transport.CodeBodyInvalid, gjson.GetBytes(res2, "errors.1.code").String())
+		assert.EqualValues(t, 5, gjson.GetBytes(res2, "errors.2.line").Int())
+		assert.Equal(t, transport.CodeNameInvalid, gjson.GetBytes(res2, "errors.2.code").String())
+		assert.EqualValues(t, 6, gjson.GetBytes(res2, "errors.3.line").Int())
+		assert.Equal(t, transport.CodeTypeMismatch, gjson.GetBytes(res2, "errors.3.code").String())
+
+		rsp3, err := http.Get(srv.URL + "/drinks/coffee")
+		require.NoError(t, err)
+		defer func() { _ = rsp3.Body.Close() }()
+
+		res3, err := io.ReadAll(rsp3.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "5d1a4f3e-0c55-4c53-9d8e-3a4f0c1b2a10", gjson.GetBytes(res3, "uuid").String())
+	})
+
+	t.Run("Upsert", func(t *testing.T) {
+		t.Parallel()
+
+		repo := memory.NewItemRepository()
+
+		h := transport.New(repo)
+
+		srv := httptest.NewServer(h)
+		defer srv.Close()
+
+		rsp, err := http.Post(srv.URL+"/drinks", "application/json", bytes.NewBufferString(`{"name": "tea"}`))
+		require.NoError(t, err)
+		_ = rsp.Body.Close()
+
+		reqBody := bytes.NewBufferString(`{"name": "tea", "drinkType": "Hot Drinks"}
+{"name": "coffee", "drinkType": "Hot Drinks"}`)
+		rsp2, err := http.Post(srv.URL+"/drinks/_import?mode=upsert", "application/x-ndjson", reqBody)
+		require.NoError(t, err)
+		defer func() { _ = rsp2.Body.Close() }()
+
+		res2, err := io.ReadAll(rsp2.Body)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, gjson.GetBytes(res2, "created").Int())
+		assert.EqualValues(t, 1, gjson.GetBytes(res2, "updated").Int())
+		assert.EqualValues(t, 0, gjson.GetBytes(res2, "failed").Int())
+
+		rsp3, err := http.Get(srv.URL + "/drinks/tea")
+		require.NoError(t, err)
+		defer func() { _ = rsp3.Body.Close() }()
+
+		res3, err := io.ReadAll(rsp3.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "Hot Drinks", gjson.GetBytes(res3, "drinkType").String())
+	})
+
+	t.Run("Replace All", func(t *testing.T) {
+		t.Parallel()
+
+		repo := memory.NewItemRepository()
+
+		h := transport.New(repo)
+
+		srv := httptest.NewServer(h)
+		defer srv.Close()
+
+		for _, body := range []string{`{"name": "tea"}`, `{"name": "milk"}`} {
+			rsp, err := http.Post(srv.URL+"/drinks", "application/json", bytes.NewBufferString(body))
+			require.NoError(t, err)
+			_ = rsp.Body.Close()
+		}
+
+		reqBody := bytes.NewBufferString(`{"name": "tea", "drinkType": "Hot Drinks"}
+{"name": "coffee", "drinkType": "Hot Drinks"}
+`)
+		rsp2, err := http.Post(srv.URL+"/drinks/_import?mode=replace-all", "application/x-ndjson", reqBody)
+		require.NoError(t, err)
+		defer func() { _ = rsp2.Body.Close() }()
+
+		res2, err := io.ReadAll(rsp2.Body)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, gjson.GetBytes(res2, "created").Int())
+		assert.EqualValues(t, 1, gjson.GetBytes(res2, "updated").Int())
+		assert.EqualValues(t, 1, gjson.GetBytes(res2, "deleted").Int())
+
+		rsp3, err := http.Get(srv.URL + "/drinks/milk")
+		require.NoError(t, err)
+		defer func() { _ = rsp3.Body.Close() }()
+
+		assert.Equal(t, http.StatusNotFound, rsp3.StatusCode)
+	})
+
+	t.Run("Invalid mode", func(t *testing.T) {
+		t.Parallel()
+
+		repo := memory.NewItemRepository()
+
+		h := transport.New(repo)
+
+		srv := httptest.NewServer(h)
+		defer srv.Close()
+
+		rsp, err := http.Post(srv.URL+"/drinks/_import?mode=merge", "application/x-ndjson", bytes.NewBufferString(`{"name": "tea"}`))
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		assert.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	})
+}
+
+func readLines(t *testing.T, r io.Reader) []string {
+	t.Helper()
+
+	res := make([]string, 0)
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		res = append(res, sc.Text())
+	}
+
+	require.NoError(t, sc.Err())
+
+	return res
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestBulkWrite(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(transport.New(memory.NewItemRepository()))
+	defer srv.Close()
+
+	send := newSender(srv)
+
+	for _, body := range []string{
+		`{"name": "tea", "metadata": {"labels": {"kind": "hot"}}, "price": 2}`,
+		`{"name": "coffee", "metadata": {"labels": {"kind": "hot"}}, "price": 4}`,
+		`{"name": "lemonade", "metadata": {"labels": {"kind": "cold"}}, "price": 3}`,
+		`{"name": "water", "price": 1}`,
+	} {
+		status, _ := send(t, http.MethodPost, "/drinks", "application/json", body)
+		require.Equal(t, http.StatusCreated, status)
+	}
+
+	t.Run("Filter List", func(t *testing.T) {
+		status, res := send(t, http.MethodGet, "/drinks?filter=price:GTE:3", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `["coffee","lemonade"]`, gjson.GetBytes(res, "items.#.name").Raw)
+
+		status, res = send(t, http.MethodGet, "/drinks?filter=price:BETWEEN:3", "", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "filter", gjson.GetBytes(res, "invalidParams.0.name").String())
+	})
+
+	t.Run("Patch Dry Run", func(t *testing.T) {
+		status, res := send(t, http.MethodPatch, "/drinks?labelSelector=kind%3Dhot&dryRun=true", "application/merge-patch+json", `{"price": 5}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.True(t, gjson.GetBytes(res, "dryRun").Bool())
+		assert.Equal(t, int64(2), gjson.GetBytes(res, "matched").Int())
+		assert.Equal(t, int64(0), gjson.GetBytes(res, "succeeded").Int())
+		assert.Equal(t, `["coffee","tea"]`, gjson.GetBytes(res, "items").Raw)
+
+		_, res = send(t, http.MethodGet, "/drinks/tea", "", "")
+		assert.Equal(t, 2.0, gjson.GetBytes(res, "price").Float())
+	})
+
+	t.Run("Patch", func(t *testing.T) {
+		status, res := send(t, http.MethodPatch, "/drinks?labelSelector=kind%3Dhot", "application/merge-patch+json", `{"price": 5, "metadata": {"labels": {"reviewed": "true"}}}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, int64(2), gjson.GetBytes(res, "succeeded").Int())
+		assert.Equal(t, int64(0), gjson.GetBytes(res, "failed").Int())
+
+		_, res = send(t, http.MethodGet, "/drinks/tea", "", "")
+		assert.Equal(t, 5.0, gjson.GetBytes(res, "price").Float())
+		assert.Equal(t, "true", gjson.GetBytes(res, "metadata.labels.reviewed").String())
+
+		status, _ = send(t, http.MethodPatch, "/drinks", "application/json", `{"price": 5}`)
+		assert.Equal(t, http.StatusUnsupportedMediaType, status)
+	})
+
+	t.Run("Patch Failures", func(t *testing.T) {
+		status, res := send(t, http.MethodPatch, "/drinks", "application/json-patch+json", `[{"op": "remove", "path": "/metadata/labels"}]`)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, int64(4), gjson.GetBytes(res, "matched").Int())
+		assert.Equal(t, int64(3), gjson.GetBytes(res, "succeeded").Int())
+		assert.Equal(t, int64(1), gjson.GetBytes(res, "failed").Int())
+		assert.Equal(t, "water", gjson.GetBytes(res, "errors.0.name").String())
+		assert.Equal(t, transport.CodePatchFailed, gjson.GetBytes(res, "errors.0.code").String())
+	})
+
+	t.Run("Delete Needs Confirmation", func(t *testing.T) {
+		status, res := send(t, http.MethodDelete, "/drinks", "", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, transport.CodeConfirmationRequired, gjson.GetBytes(res, "code").String())
+
+		status, res = send(t, http.MethodDelete, "/drinks?dryRun=true", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, int64(4), gjson.GetBytes(res, "matched").Int())
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		status, res := send(t, http.MethodDelete, "/drinks?filter=price:LT:5", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `["lemonade","water"]`, gjson.GetBytes(res, "items").Raw)
+
+		status, res = send(t, http.MethodDelete, "/drinks?confirm=true", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, int64(2), gjson.GetBytes(res, "succeeded").Int())
+
+		_, res = send(t, http.MethodGet, "/drinks", "", "")
+		assert.Equal(t, `[]`, gjson.GetBytes(res, "items").Raw)
+	})
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+func TestContentNegotiation(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.NewItemRepository()
+
+	h := transport.New(repo)
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	reqBody := bytes.NewBufferString("name: tea\ndrinkType: Hot Drinks\nsizes:\n  - small\n  - large\n")
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/drinks", reqBody)
+	require.NoError(t, err)
+
+	req.Header.Set("Content-Type", "application/yaml")
+	req.Header.Set("Accept", "application/yaml")
+
+	rsp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = rsp.Body.Close() }()
+
+	assert.Equal(t, http.StatusCreated, rsp.StatusCode)
+	assert.Equal(t, "application/yaml", rsp.Header.Get("Content-Type"))
+
+	var created map[string]interface{}
+
+	require.NoError(t, yaml.NewDecoder(rsp.Body).Decode(&created))
+	assert.Equal(t, "tea", created["name"])
+	assert.Equal(t, "Hot Drinks", created["drinkType"])
+	assert.Equal(t, []interface{}{"small", "large"}, created["sizes"])
+
+	t.Run("CBOR", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/drinks/tea", nil)
+		require.NoError(t, err)
+
+		req.Header.Set("Accept", "application/cbor")
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		assert.Equal(t, http.StatusOK, rsp.StatusCode)
+		assert.Equal(t, "application/cbor", rsp.Header.Get("Content-Type"))
+
+		var res map[string]interface{}
+
+		require.NoError(t, cbor.NewDecoder(rsp.Body).Decode(&res))
+		assert.Equal(t, "tea", res["name"])
+	})
+
+	t.Run("MessagePack", func(t *testing.T) {
+		body, err := msgpack.Marshal(map[string]interface{}{"drinkType": "Cold Drinks"})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPut, srv.URL+"/drinks/tea", bytes.NewReader(body))
+		require.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/x-msgpack")
+		req.Header.Set("Accept", "application/json;q=0.5, application/msgpack")
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		assert.Equal(t, http.StatusOK, rsp.StatusCode)
+		assert.Equal(t, "application/msgpack", rsp.Header.Get("Content-Type"))
+
+		var res map[string]interface{}
+
+		require.NoError(t, msgpack.NewDecoder(rsp.Body).Decode(&res))
+		assert.Equal(t, "Cold Drinks", res["drinkType"])
+	})
+
+	t.Run("List", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/drinks", nil)
+		require.NoError(t, err)
+
+		req.Header.Set("Accept", "text/yaml")
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		assert.Equal(t, "application/yaml", rsp.Header.Get("Content-Type"))
+
+		var res struct {
+			Items []map[string]interface{} `yaml:"items"`
+		}
+
+		require.NoError(t, yaml.NewDecoder(rsp.Body).Decode(&res))
+		require.Len(t, res.Items, 1)
+		assert.Equal(t, "tea", res.Items[0]["name"])
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/drinks/coffee", nil)
+		require.NoError(t, err)
+
+		req.Header.Set("Accept", "application/yaml")
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		assert.Equal(t, http.StatusNotFound, rsp.StatusCode)
+		assert.Equal(t, "application/problem+yaml", rsp.Header.Get("Content-Type"))
+
+		res, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(res), "code: item.not_found")
+	})
+
+	t.Run("Not Acceptable", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/drinks/tea", nil)
+		require.NoError(t, err)
+
+		req.Header.Set("Accept", "application/xml")
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		assert.Equal(t, http.StatusNotAcceptable, rsp.StatusCode)
+		assert.Equal(t, "application/problem+json", rsp.Header.Get("Content-Type"))
+	})
+
+	t.Run("Unsupported Media Type", func(t *testing.T) {
+		rsp, err := http.Post(srv.URL+"/drinks", "application/xml", bytes.NewBufferString(`<drink><name>coffee</name></drink>`))
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rsp.StatusCode)
+
+		req, err := http.NewRequest(http.MethodPatch, srv.URL+"/drinks/tea", bytes.NewBufferString(`{}`))
+		require.NoError(t, err)
+
+		req.Header.Set("Content-Type", "text/plain")
+
+		rsp2, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = rsp2.Body.Close() }()
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rsp2.StatusCode)
+	})
+}
@@ -0,0 +1,126 @@
+package test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestListCSV(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.NewItemRepository()
+
+	h := transport.New(repo)
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	for _, body := range []string{
+		`{"name": "tea", "price": 2.5, "hot": true, "origin": {"country": "China"}}`,
+		`{"name": "coffee", "price": 3, "tags": ["strong", "black"]}`,
+	} {
+		rsp, err := http.Post(srv.URL+"/drinks", "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		_ = rsp.Body.Close()
+	}
+
+	t.Run("All columns", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/drinks", nil)
+		require.NoError(t, err)
+
+		req.Header.Set("Accept", "text/csv")
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		assert.Equal(t, http.StatusOK, rsp.StatusCode)
+		assert.Equal(t, "text/csv", rsp.Header.Get("Content-Type"))
+
+		records, err := csv.NewReader(rsp.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+
+		assert.Equal(t, []string{"uuid", "type", "name", "createdAt", "updatedAt", "hot", "origin.country", "price", "tags"}, records[0])
+		assert.Equal(t, "coffee", records[1][2])
+		assert.Equal(t, `["strong","black"]`, records[1][8])
+		assert.Equal(t, "tea", records[2][2])
+		assert.Equal(t, "true", records[2][5])
+		assert.Equal(t, "China", records[2][6])
+		assert.Equal(t, "2.5", records[2][7])
+	})
+
+	t.Run("Selected columns", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/drinks?fields=name,origin.country", nil)
+		require.NoError(t, err)
+
+		req.Header.Set("Accept", "text/csv;q=0.9, application/xml")
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		records, err := csv.NewReader(rsp.Body).ReadAll()
+		require.NoError(t, err)
+
+		assert.Equal(t, [][]string{{"name", "origin.country"}, {"coffee", ""}, {"tea", "China"}}, records)
+	})
+}
+
+func TestImportCSV(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.NewItemRepository()
+
+	h := transport.New(repo)
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	reqBody := bytes.NewBufferString(`Drink,price,hot,origin.country,zip,note
+tea,2.5,TRUE,China,0123,plain
+coffee,3,false,,,
+Herbal Tea,1,,,,
+`)
+	rsp, err := http.Post(srv.URL+"/drinks/_import?mode=upsert&map=Drink:name", "text/csv", reqBody)
+	require.NoError(t, err)
+	defer func() { _ = rsp.Body.Close() }()
+
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	res, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, gjson.GetBytes(res, "created").Int())
+	assert.EqualValues(t, 1, gjson.GetBytes(res, "failed").Int())
+	assert.EqualValues(t, 4, gjson.GetBytes(res, "errors.0.line").Int())
+
+	rsp2, err := http.Get(srv.URL + "/drinks/tea")
+	require.NoError(t, err)
+	defer func() { _ = rsp2.Body.Close() }()
+
+	res2, err := io.ReadAll(rsp2.Body)
+	require.NoError(t, err)
+	assert.Equal(t, 2.5, gjson.GetBytes(res2, "price").Value())
+	assert.Equal(t, true, gjson.GetBytes(res2, "hot").Value())
+	assert.Equal(t, "China", gjson.GetBytes(res2, "origin.country").Value())
+	assert.Equal(t, "0123", gjson.GetBytes(res2, "zip").Value())
+
+	rsp3, err := http.Get(srv.URL + "/drinks/coffee")
+	require.NoError(t, err)
+	defer func() { _ = rsp3.Body.Close() }()
+
+	res3, err := io.ReadAll(rsp3.Body)
+	require.NoError(t, err)
+	assert.Equal(t, false, gjson.GetBytes(res3, "hot").Value())
+	assert.False(t, gjson.GetBytes(res3, "origin").Exists())
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestExpand(t *testing.T) {
+	t.Parallel()
+
+	h := transport.New(memory.NewItemRepository())
+
+	err := h.LoadSchemas(strings.NewReader(`
+- type: customer
+  references:
+    company:
+      type: company
+- type: order
+  references:
+    customer:
+      type: customer
+    lines.product:
+      type: product
+- type: employee
+  references:
+    manager:
+      type: employee
+      onDelete: SetNull
+`))
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	send := newSender(srv)
+
+	status, res := send(t, http.MethodPost, "/companies", "application/json", `{"name": "acme-corp"}`)
+	require.Equal(t, http.StatusCreated, status)
+
+	companyUUID := gjson.GetBytes(res, "uuid").String()
+
+	for _, item := range []struct{ path, body string }{
+		{"/customers", `{"name": "wile", "company": "` + companyUUID + `"}`},
+		{"/products", `{"name": "anvil", "price": 10}`},
+		{"/products", `{"name": "rocket", "price": 99}`},
+		{"/orders", `{"name": "order-1", "customer": "wile", "lines": [{"product": "anvil"}, {"product": "rocket"}]}`},
+		{"/orders", `{"name": "order-2", "customer": "wile"}`},
+		{"/employees", `{"name": "alice"}`},
+		{"/employees", `{"name": "bob", "manager": "alice"}`},
+	} {
+		status, _ := send(t, http.MethodPost, item.path, "application/json", item.body)
+		require.Equal(t, http.StatusCreated, status)
+	}
+
+	status, _ = send(t, http.MethodPatch, "/employees/alice", "application/merge-patch+json", `{"manager": "bob"}`)
+	require.Equal(t, http.StatusOK, status)
+
+	t.Run("Read", func(t *testing.T) {
+		status, res := send(t, http.MethodGet, "/orders/order-1?expand=customer.company,lines.product", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "wile", gjson.GetBytes(res, "customer.name").String())
+		assert.Equal(t, "acme-corp", gjson.GetBytes(res, "customer.company.name").String())
+		assert.Equal(t, `[10,99]`, gjson.GetBytes(res, "lines.#.product.price").Raw)
+
+		status, res = send(t, http.MethodGet, "/orders/order-1", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "wile", gjson.GetBytes(res, "customer").String())
+	})
+
+	t.Run("List", func(t *testing.T) {
+		status, res := send(t, http.MethodGet, "/orders?expand=customer", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `["wile","wile"]`, gjson.GetBytes(res, "items.#.customer.name").Raw)
+		assert.Equal(t, companyUUID, gjson.GetBytes(res, "items.0.customer.company").String())
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		status, res := send(t, http.MethodGet, "/employees/alice?expand=manager.manager", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "bob", gjson.GetBytes(res, "manager.name").String())
+		assert.Equal(t, "alice", gjson.GetBytes(res, "manager.manager").String())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		status, res := send(t, http.MethodGet, "/orders/order-1?expand=price", "", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, transport.CodeParamInvalid, gjson.GetBytes(res, "code").String())
+		assert.Equal(t, "expand", gjson.GetBytes(res, "invalidParams.0.name").String())
+
+		status, _ = send(t, http.MethodGet, "/employees?expand=manager.manager.manager.manager", "", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
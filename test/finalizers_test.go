@@ -0,0 +1,133 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestFinalizers(t *testing.T) {
+	t.Parallel()
+
+	h := transport.New(memory.NewItemRepository())
+	defer func() { _ = h.Close() }()
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	send := newSender(srv)
+
+	t.Run("Invalid Finalizer", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-0", "metadata": {"finalizers": ["not valid"]}}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, transport.CodeFinalizerInvalid, gjson.GetBytes(res, "code").String())
+		assert.Equal(t, "metadata.finalizers.0", gjson.GetBytes(res, "invalidParams.0.name").String())
+	})
+
+	t.Run("Delete Without Finalizers", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-1"}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+
+		status, _ = send(t, http.MethodDelete, "/orders/order-1", "", "")
+		assert.Equal(t, http.StatusNoContent, status)
+
+		status, _ = send(t, http.MethodGet, "/orders/order-1", "", "")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("Delete With Finalizers", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/orders", "application/json",
+			`{"name": "order-2", "data": {"total": 10}, "metadata": {"finalizers": ["example.com/invoice", "example.com/shipment"]}}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+
+		status, res = send(t, http.MethodDelete, "/orders/order-2", "", "")
+		require.Equal(t, http.StatusAccepted, status, string(res))
+		assert.NotEmpty(t, gjson.GetBytes(res, "metadata.deletionTimestamp").String())
+
+		status, res = send(t, http.MethodGet, "/orders/order-2", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.NotEmpty(t, gjson.GetBytes(res, "metadata.deletionTimestamp").String())
+
+		// deleting again keeps it pending
+		status, _ = send(t, http.MethodDelete, "/orders/order-2", "", "")
+		assert.Equal(t, http.StatusAccepted, status)
+
+		status, res = send(t, http.MethodPatch, "/orders/order-2", "application/merge-patch+json", `{"data": {"total": 20}}`)
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, transport.CodeItemDeleting, gjson.GetBytes(res, "code").String())
+
+		status, res = send(t, http.MethodPut, "/orders/order-2", "application/json", `{"data": {"total": 10}, "metadata": {"finalizers": ["example.com/invoice", "example.com/other"]}}`)
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, transport.CodeItemDeleting, gjson.GetBytes(res, "code").String())
+
+		status, res = send(t, http.MethodPatch, "/orders/order-2", "application/merge-patch+json", `{"metadata": {"finalizers": ["example.com/shipment"]}}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.Equal(t, `["example.com/shipment"]`, gjson.GetBytes(res, "metadata.finalizers").Raw)
+
+		status, _ = send(t, http.MethodGet, "/orders/order-2", "", "")
+		assert.Equal(t, http.StatusOK, status)
+
+		status, res = send(t, http.MethodPatch, "/orders/order-2", "application/json-patch+json", `[{"op": "remove", "path": "/metadata/finalizers/0"}]`)
+		require.Equal(t, http.StatusOK, status, string(res))
+
+		status, _ = send(t, http.MethodGet, "/orders/order-2", "", "")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("Finalizers Before Deletion", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-3", "metadata": {"finalizers": ["example.com/invoice"]}}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+
+		status, res = send(t, http.MethodPatch, "/orders/order-3", "application/merge-patch+json", `{"data": {"total": 30}, "metadata": {"finalizers": null}}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.False(t, gjson.GetBytes(res, "metadata.finalizers").Exists())
+
+		status, _ = send(t, http.MethodGet, "/orders/order-3", "", "")
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-4", "metadata": {"finalizers": ["example.com/invoice"]}}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+
+		// pruned items with finalizers are only marked, like deleted ones
+		status, res = send(t, http.MethodPost, "/orders/_import?mode=replace-all", "application/x-ndjson", `{"name": "order-3"}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.EqualValues(t, 1, gjson.GetBytes(res, "deleted").Int())
+
+		status, res = send(t, http.MethodGet, "/orders/order-4", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.NotEmpty(t, gjson.GetBytes(res, "metadata.deletionTimestamp").String())
+
+		status, res = send(t, http.MethodPost, "/orders/_import?mode=upsert", "application/x-ndjson", `{"name": "order-4", "total": 40}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.Equal(t, transport.CodeItemDeleting, gjson.GetBytes(res, "errors.0.code").String())
+
+		status, res = send(t, http.MethodPost, "/orders/_import?mode=upsert", "application/x-ndjson", `{"name": "order-4", "metadata": {"finalizers": []}}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.EqualValues(t, 1, gjson.GetBytes(res, "updated").Int())
+
+		status, _ = send(t, http.MethodGet, "/orders/order-4", "", "")
+		assert.Equal(t, http.StatusNotFound, status)
+
+		status, res = send(t, http.MethodPost, "/orders/_import", "application/x-ndjson",
+			`{"name": "order-5", "metadata": {"deletionTimestamp": "2024-01-01T00:00:00Z"}}`+"\n"+
+				`{"name": "order-6", "metadata": {"deletionTimestamp": "2024-01-01T00:00:00Z", "finalizers": ["example.com/invoice"]}}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.EqualValues(t, 2, gjson.GetBytes(res, "created").Int())
+
+		status, res = send(t, http.MethodGet, "/orders/order-5", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.False(t, gjson.GetBytes(res, "metadata.deletionTimestamp").Exists())
+
+		status, res = send(t, http.MethodGet, "/orders/order-6", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "2024-01-01T00:00:00Z", gjson.GetBytes(res, "metadata.deletionTimestamp").String())
+	})
+}
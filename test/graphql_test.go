@@ -0,0 +1,229 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func graphQL(t *testing.T, srvURL, query string, variables map[string]interface{}) gjson.Result {
+	t.Helper()
+
+	body, err := json.Marshal(transport.GraphQLRequest{Query: query, Variables: variables})
+	require.NoError(t, err)
+
+	rsp, err := http.Post(srvURL+"/graphql", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer func() { _ = rsp.Body.Close() }()
+
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	var buf bytes.Buffer
+
+	_, err = buf.ReadFrom(rsp.Body)
+	require.NoError(t, err)
+
+	return gjson.ParseBytes(buf.Bytes())
+}
+
+func TestGraphQL(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.NewItemRepository()
+
+	h := transport.New(repo)
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	res := graphQL(t, srv.URL, `mutation { createItem(type: "line-item", name: "first", data: {price: 10, tags: ["a"]}) { uuid name type } }`, nil)
+	require.False(t, res.Get("errors").Exists(), res.Raw)
+	assert.Equal(t, "line-item", res.Get("data.createItem.type").String())
+
+	for _, name := range []string{"second", "third"} {
+		res = graphQL(t, srv.URL, `mutation($name: String!, $data: JSON) { createLineItem(name: $name, data: $data) { name } }`,
+			map[string]interface{}{"name": name, "data": map[string]interface{}{"price": len(name), "origin": map[string]interface{}{"country": "IR"}}})
+		require.False(t, res.Get("errors").Exists(), res.Raw)
+	}
+
+	t.Run("Query", func(t *testing.T) {
+		res := graphQL(t, srv.URL, `{ lineItem(name: "first") { name data } missing: lineItem(name: "missing") { name } }`, nil)
+		require.False(t, res.Get("errors").Exists(), res.Raw)
+		assert.Equal(t, float64(10), res.Get("data.lineItem.data.price").Float())
+		assert.Equal(t, "a", res.Get("data.lineItem.data.tags.0").String())
+		assert.True(t, res.Get("data.missing").Type == gjson.Null)
+	})
+
+	t.Run("FilterSortAndPagination", func(t *testing.T) {
+		query := `query($after: String) {
+			lineItems(filter: [{field: "origin.country", value: "IR"}], sort: [{field: "price", direction: DESC}], first: 1, after: $after) {
+				totalCount items { name } pageInfo { hasNextPage endCursor }
+			}
+		}`
+
+		res := graphQL(t, srv.URL, query, nil)
+		require.False(t, res.Get("errors").Exists(), res.Raw)
+		assert.Equal(t, int64(2), res.Get("data.lineItems.totalCount").Int())
+		assert.Equal(t, "second", res.Get("data.lineItems.items.0.name").String())
+		assert.True(t, res.Get("data.lineItems.pageInfo.hasNextPage").Bool())
+
+		res = graphQL(t, srv.URL, query, map[string]interface{}{"after": res.Get("data.lineItems.pageInfo.endCursor").String()})
+		require.False(t, res.Get("errors").Exists(), res.Raw)
+		assert.Equal(t, "third", res.Get("data.lineItems.items.0.name").String())
+		assert.False(t, res.Get("data.lineItems.pageInfo.hasNextPage").Bool())
+
+		res = graphQL(t, srv.URL, `{ items(type: "line-item", filter: [{field: "price", op: GT, value: 8}]) { items { name } } }`, nil)
+		require.False(t, res.Get("errors").Exists(), res.Raw)
+		assert.Equal(t, `["first"]`, res.Get("data.items.items.#.name").Raw)
+	})
+
+	t.Run("Mutations", func(t *testing.T) {
+		res := graphQL(t, srv.URL, `mutation { replaceLineItem(name: "third", data: {price: 1}) { data } }`, nil)
+		require.False(t, res.Get("errors").Exists(), res.Raw)
+		assert.Equal(t, `{"price":1}`, res.Get("data.replaceLineItem.data").Raw)
+
+		res = graphQL(t, srv.URL, `mutation($patch: JSON!) { patchLineItem(name: "third", patch: $patch) { data } }`,
+			map[string]interface{}{"patch": map[string]interface{}{"color": "red", "price": nil}})
+		require.False(t, res.Get("errors").Exists(), res.Raw)
+		assert.Equal(t, `{"color":"red"}`, res.Get("data.patchLineItem.data").Raw)
+
+		res = graphQL(t, srv.URL, `mutation { patchLineItem(name: "third", patch: [{op: "add", path: "/size", value: "L"}]) { data } }`, nil)
+		require.False(t, res.Get("errors").Exists(), res.Raw)
+		assert.Equal(t, "L", res.Get("data.patchLineItem.data.size").String())
+
+		res = graphQL(t, srv.URL, `mutation { deleteLineItem(name: "third") { name } }`, nil)
+		require.False(t, res.Get("errors").Exists(), res.Raw)
+		assert.Equal(t, "third", res.Get("data.deleteLineItem.name").String())
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		res := graphQL(t, srv.URL, `mutation { createLineItem(name: "fifth", labels: {tier: "gold"}, annotations: {note: "fragile"}, finalizers: ["example.com/audit"]) { uuid labels annotations finalizers } }`, nil)
+		require.False(t, res.Get("errors").Exists(), res.Raw)
+		assert.JSONEq(t, `{"tier": "gold"}`, res.Get("data.createLineItem.labels").Raw)
+		assert.JSONEq(t, `{"note": "fragile"}`, res.Get("data.createLineItem.annotations").Raw)
+		assert.Equal(t, `["example.com/audit"]`, res.Get("data.createLineItem.finalizers").Raw)
+
+		ownerUUID := res.Get("data.createLineItem.uuid").String()
+
+		res = graphQL(t, srv.URL, `mutation($owners: [OwnerReferenceInput!]) { createLineItem(name: "sixth", ownerReferences: $owners) { ownerReferences } }`,
+			map[string]interface{}{"owners": []interface{}{map[string]interface{}{"type": "line-item", "name": "fifth", "uuid": ownerUUID}}})
+		require.False(t, res.Get("errors").Exists(), res.Raw)
+		assert.Equal(t, ownerUUID, res.Get("data.createLineItem.ownerReferences.0.uuid").String())
+
+		res = graphQL(t, srv.URL, `mutation { replaceLineItem(name: "fifth", labels: {tier: "silver"}, finalizers: []) { labels annotations finalizers } }`, nil)
+		require.False(t, res.Get("errors").Exists(), res.Raw)
+		assert.JSONEq(t, `{"tier": "silver"}`, res.Get("data.replaceLineItem.labels").Raw)
+		assert.JSONEq(t, `{"note": "fragile"}`, res.Get("data.replaceLineItem.annotations").Raw)
+		assert.False(t, res.Get("data.replaceLineItem.finalizers.0").Exists())
+
+		res = graphQL(t, srv.URL, `mutation { createLineItem(name: "seventh", labels: {tier: 1}) { name } }`, nil)
+		assert.Equal(t, transport.CodeBodyInvalid, res.Get("errors.0.extensions.code").String())
+
+		res = graphQL(t, srv.URL, `mutation($labels: JSON) { createLineItem(name: "seventh", labels: $labels) { name } }`,
+			map[string]interface{}{"labels": map[string]interface{}{"bad key": "x"}})
+		assert.Equal(t, transport.CodeLabelInvalid, res.Get("errors.0.extensions.code").String())
+	})
+
+	t.Run("Schema Types", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPut, srv.URL+"/_schemas/gift-cards", strings.NewReader(`{}`))
+		require.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/json")
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = rsp.Body.Close()
+
+		require.Equal(t, http.StatusCreated, rsp.StatusCode)
+
+		res := graphQL(t, srv.URL, `{ giftCards { totalCount } }`, nil)
+		require.False(t, res.Get("errors").Exists(), res.Raw)
+		assert.Equal(t, int64(0), res.Get("data.giftCards.totalCount").Int())
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		res := graphQL(t, srv.URL, `mutation { createLineItem(name: "first") { name } }`, nil)
+		assert.Equal(t, transport.CodeItemAlreadyExists, res.Get("errors.0.extensions.code").String())
+
+		res = graphQL(t, srv.URL, `mutation { createLineItem(name: "Invalid") { name } }`, nil)
+		assert.Equal(t, transport.CodeNameInvalid, res.Get("errors.0.extensions.code").String())
+
+		res = graphQL(t, srv.URL, `mutation { replaceLineItem(name: "missing", data: {}) { name } }`, nil)
+		assert.Equal(t, transport.CodeItemNotFound, res.Get("errors.0.extensions.code").String())
+
+		res = graphQL(t, srv.URL, `mutation { createLineItem(name: "fourth", data: {name: "other"}) { name } }`, nil)
+		assert.Equal(t, transport.CodeBodyInvalid, res.Get("errors.0.extensions.code").String())
+
+		rsp, err := http.Get(srv.URL + "/graphql?query=" + url.QueryEscape(`mutation { deleteLineItem(name: "first") { name } }`))
+		require.NoError(t, err)
+		_ = rsp.Body.Close()
+
+		assert.Equal(t, http.StatusMethodNotAllowed, rsp.StatusCode)
+	})
+
+	t.Run("Subscription", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		query := url.QueryEscape(`subscription { lineItemEvents { type item { name } } }`)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/graphql?query="+query, nil)
+		require.NoError(t, err)
+
+		req.Header.Set("Accept", "text/event-stream")
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = rsp.Body.Close() }()
+
+		require.Equal(t, http.StatusOK, rsp.StatusCode)
+		assert.Equal(t, "text/event-stream", rsp.Header.Get("Content-Type"))
+
+		// the subscription starts watching after the response headers are sent, so keep changing the item until an event arrives
+		go func() {
+			ticker := time.NewTicker(10 * time.Millisecond)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					body := `{"query": "mutation { replaceLineItem(name: \"first\", data: {}) { name } }"}`
+
+					rsp, err := http.Post(srv.URL+"/graphql", "application/json", strings.NewReader(body))
+					if err == nil {
+						_ = rsp.Body.Close()
+					}
+				}
+			}
+		}()
+
+		sc := bufio.NewScanner(rsp.Body)
+
+		for sc.Scan() {
+			if data := strings.TrimPrefix(sc.Text(), "data: "); data != sc.Text() {
+				event := gjson.Parse(data)
+				assert.Equal(t, "MODIFIED", event.Get("data.lineItemEvents.type").String())
+				assert.Equal(t, "first", event.Get("data.lineItemEvents.item.name").String())
+
+				break
+			}
+		}
+
+		require.NoError(t, sc.Err())
+	})
+}
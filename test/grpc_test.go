@@ -0,0 +1,326 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "github.com/nasermirzaei89/core/api/core/v1"
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func newGRPCClient(t *testing.T, h *transport.Handler) corev1.ItemServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+
+	srv := grpc.NewServer()
+	h.RegisterGRPC(srv)
+
+	go func() { _ = srv.Serve(lis) }()
+
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return corev1.NewItemServiceClient(conn)
+}
+
+func errorReason(t *testing.T, err error) (codes.Code, string) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return st.Code(), info.GetReason()
+		}
+	}
+
+	return st.Code(), ""
+}
+
+func TestGRPC(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	client := newGRPCClient(t, transport.New(memory.NewItemRepository()))
+
+	for i, name := range []string{"tea", "coffee", "water"} {
+		data, err := structpb.NewStruct(map[string]interface{}{"price": i, "hot": name != "water"})
+		require.NoError(t, err)
+
+		item, err := client.CreateItem(ctx, &corev1.CreateItemRequest{Type: "drink", Name: name, Data: data})
+		require.NoError(t, err)
+		assert.Equal(t, "drink", item.GetType())
+		assert.NotEmpty(t, item.GetUuid())
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		item, err := client.GetItem(ctx, &corev1.GetItemRequest{Type: "drink", Name: "coffee"})
+		require.NoError(t, err)
+		assert.Equal(t, float64(1), item.GetData().AsMap()["price"])
+
+		_, err = client.GetItem(ctx, &corev1.GetItemRequest{Type: "drink", Name: "milk"})
+		code, reason := errorReason(t, err)
+		assert.Equal(t, codes.NotFound, code)
+		assert.Equal(t, transport.CodeItemNotFound, reason)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		req := &corev1.ListItemsRequest{
+			Type:     "drink",
+			Filters:  []*corev1.Filter{{Field: "hot", Value: structpb.NewBoolValue(true)}},
+			Sort:     []*corev1.Sort{{Field: "price", Desc: true}},
+			PageSize: 1,
+		}
+
+		res, err := client.ListItems(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, res.GetItems(), 1)
+		assert.Equal(t, int32(2), res.GetTotalCount())
+		assert.Equal(t, "coffee", res.GetItems()[0].GetName())
+		require.NotEmpty(t, res.GetNextPageToken())
+
+		req.PageToken = res.GetNextPageToken()
+
+		res, err = client.ListItems(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, res.GetItems(), 1)
+		assert.Equal(t, "tea", res.GetItems()[0].GetName())
+		assert.Empty(t, res.GetNextPageToken())
+
+		_, err = client.ListItems(ctx, &corev1.ListItemsRequest{Type: "drink", PageToken: "invalid"})
+		code, reason := errorReason(t, err)
+		assert.Equal(t, codes.InvalidArgument, code)
+		assert.Equal(t, transport.CodeParamInvalid, reason)
+	})
+
+	t.Run("ReplaceAndPatch", func(t *testing.T) {
+		data, err := structpb.NewStruct(map[string]interface{}{"size": "small"})
+		require.NoError(t, err)
+
+		item, err := client.ReplaceItem(ctx, &corev1.ReplaceItemRequest{Type: "drink", Name: "water", Data: data})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"size": "small"}, item.GetData().AsMap())
+
+		patch, err := structpb.NewStruct(map[string]interface{}{"size": nil, "cold": true})
+		require.NoError(t, err)
+
+		item, err = client.PatchItem(ctx, &corev1.PatchItemRequest{Type: "drink", Name: "water", Patch: &corev1.PatchItemRequest_MergePatch{MergePatch: patch}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"cold": true}, item.GetData().AsMap())
+
+		item, err = client.PatchItem(ctx, &corev1.PatchItemRequest{Type: "drink", Name: "water", Patch: &corev1.PatchItemRequest_JsonPatch{JsonPatch: `[{"op": "remove", "path": "/cold"}]`}})
+		require.NoError(t, err)
+		assert.Empty(t, item.GetData().AsMap())
+
+		_, err = client.PatchItem(ctx, &corev1.PatchItemRequest{Type: "drink", Name: "water", Patch: &corev1.PatchItemRequest_JsonPatch{JsonPatch: `[{"op": "remove", "path": "/cold"}]`}})
+		code, reason := errorReason(t, err)
+		assert.Equal(t, codes.FailedPrecondition, code)
+		assert.Equal(t, transport.CodePatchFailed, reason)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		_, err := client.CreateItem(ctx, &corev1.CreateItemRequest{Type: "drink", Name: "tea"})
+		code, reason := errorReason(t, err)
+		assert.Equal(t, codes.AlreadyExists, code)
+		assert.Equal(t, transport.CodeItemAlreadyExists, reason)
+
+		_, err = client.CreateItem(ctx, &corev1.CreateItemRequest{Type: "Drink", Name: "tea"})
+		code, reason = errorReason(t, err)
+		assert.Equal(t, codes.InvalidArgument, code)
+		assert.Equal(t, transport.CodeTypeInvalid, reason)
+
+		_, err = client.CreateItem(ctx, &corev1.CreateItemRequest{Type: "drink"})
+		code, reason = errorReason(t, err)
+		assert.Equal(t, codes.InvalidArgument, code)
+		assert.Equal(t, transport.CodeNameRequired, reason)
+	})
+}
+
+func TestGRPCMetadata(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	client := newGRPCClient(t, transport.New(memory.NewItemRepository()))
+
+	order, err := client.CreateItem(ctx, &corev1.CreateItemRequest{Type: "order", Name: "order-0", Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+
+	line, err := client.CreateItem(ctx, &corev1.CreateItemRequest{
+		Type:            "line",
+		Name:            "line-0",
+		Labels:          map[string]string{"env": "dev"},
+		Annotations:     map[string]string{"note": "first"},
+		OwnerReferences: []*corev1.OwnerReference{{Type: "order", Name: "order-0", Uuid: order.GetUuid()}},
+		Finalizers:      []string{"example.com/invoice"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "dev"}, line.GetLabels())
+	assert.Equal(t, map[string]string{"note": "first"}, line.GetAnnotations())
+	require.Len(t, line.GetOwnerReferences(), 1)
+	assert.Equal(t, order.GetUuid(), line.GetOwnerReferences()[0].GetUuid())
+	assert.Equal(t, []string{"example.com/invoice"}, line.GetFinalizers())
+
+	_, err = client.CreateItem(ctx, &corev1.CreateItemRequest{
+		Type:            "line",
+		Name:            "line-1",
+		OwnerReferences: []*corev1.OwnerReference{{Type: "order", Name: "order-1", Uuid: order.GetUuid()}},
+	})
+	code, reason := errorReason(t, err)
+	assert.Equal(t, codes.FailedPrecondition, code)
+	assert.Equal(t, transport.CodeOwnerInvalid, reason)
+
+	t.Run("List", func(t *testing.T) {
+		_, err := client.CreateItem(ctx, &corev1.CreateItemRequest{Type: "line", Name: "line-2", Labels: map[string]string{"env": "prod"}})
+		require.NoError(t, err)
+
+		res, err := client.ListItems(ctx, &corev1.ListItemsRequest{Type: "line", LabelSelector: "env=prod"})
+		require.NoError(t, err)
+		require.Len(t, res.GetItems(), 1)
+		assert.Equal(t, "line-2", res.GetItems()[0].GetName())
+
+		_, err = client.ListItems(ctx, &corev1.ListItemsRequest{Type: "line", LabelSelector: "env in prod"})
+		code, reason := errorReason(t, err)
+		assert.Equal(t, codes.InvalidArgument, code)
+		assert.Equal(t, transport.CodeParamInvalid, reason)
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		// empty metadata keeps the metadata of the item
+		item, err := client.ReplaceItem(ctx, &corev1.ReplaceItemRequest{Type: "line", Name: "line-0", Annotations: map[string]string{"note": "second"}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"env": "dev"}, item.GetLabels())
+		assert.Equal(t, map[string]string{"note": "second"}, item.GetAnnotations())
+		assert.Equal(t, []string{"example.com/invoice"}, item.GetFinalizers())
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		item, err := client.DeleteItem(ctx, &corev1.DeleteItemRequest{Type: "line", Name: "line-0"})
+		require.NoError(t, err)
+		assert.NotNil(t, item.GetDeletionTimestamp())
+	})
+}
+
+func TestGRPCErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	h := transport.New(memory.NewItemRepository())
+
+	err := h.LoadSchemas(strings.NewReader(fmt.Sprintf(`
+- type: order
+  workflow:
+    field: status
+    states: [draft, approved, shipped]
+    initial: draft
+    transitions:
+      - from: [draft]
+        to: approved
+        roles: [manager]
+- type: invoice
+  webhooks:
+    - name: down
+      kind: Validating
+      url: %s
+      operations: [DELETE]
+`, down.URL)))
+	require.NoError(t, err)
+
+	client := newGRPCClient(t, h)
+
+	t.Run("Permission Denied", func(t *testing.T) {
+		_, err := client.CreateItem(ctx, &corev1.CreateItemRequest{Type: "order", Name: "order-0"})
+		require.NoError(t, err)
+
+		data, err := structpb.NewStruct(map[string]interface{}{"status": "approved"})
+		require.NoError(t, err)
+
+		_, err = client.ReplaceItem(ctx, &corev1.ReplaceItemRequest{Type: "order", Name: "order-0", Data: data})
+		code, reason := errorReason(t, err)
+		assert.Equal(t, codes.PermissionDenied, code)
+		assert.Equal(t, transport.CodeTransitionForbidden, reason)
+	})
+
+	t.Run("Failed Precondition", func(t *testing.T) {
+		data, err := structpb.NewStruct(map[string]interface{}{"status": "shipped"})
+		require.NoError(t, err)
+
+		_, err = client.ReplaceItem(ctx, &corev1.ReplaceItemRequest{Type: "order", Name: "order-0", Data: data})
+		code, reason := errorReason(t, err)
+		assert.Equal(t, codes.FailedPrecondition, code)
+		assert.Equal(t, transport.CodeTransitionInvalid, reason)
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		_, err := client.CreateItem(ctx, &corev1.CreateItemRequest{Type: "invoice", Name: "invoice-0"})
+		require.NoError(t, err)
+
+		_, err = client.DeleteItem(ctx, &corev1.DeleteItemRequest{Type: "invoice", Name: "invoice-0"})
+		code, reason := errorReason(t, err)
+		assert.Equal(t, codes.Unavailable, code)
+		assert.Equal(t, transport.CodeAdmissionFailed, reason)
+	})
+}
+
+func TestGRPCWatch(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := newGRPCClient(t, transport.New(memory.NewItemRepository()))
+
+	stream, err := client.WatchItems(ctx, &corev1.WatchItemsRequest{Type: "drink"})
+	require.NoError(t, err)
+
+	// the watch starts when the server handles the stream, so keep creating items until an event arrives
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, _ = client.CreateItem(ctx, &corev1.CreateItemRequest{Type: "drink", Name: "tea"})
+				_, _ = client.DeleteItem(ctx, &corev1.DeleteItemRequest{Type: "drink", Name: "tea"})
+			}
+		}
+	}()
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Contains(t, []corev1.Event_Type{corev1.Event_ADDED, corev1.Event_DELETED}, event.GetType())
+	assert.Equal(t, "tea", event.GetItem().GetName())
+}
@@ -0,0 +1,42 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// sender sends a request to a test server, and returns the status code and body of its response.
+type sender func(t *testing.T, method, path, contentType, body string, headers ...string) (int, []byte)
+
+// newSender returns a sender to the server. Headers are pairs of names and values, like "X-Roles", "manager".
+func newSender(srv *httptest.Server) sender {
+	return func(t *testing.T, method, path, contentType, body string, headers ...string) (int, []byte) {
+		t.Helper()
+
+		req, err := http.NewRequest(method, srv.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		defer func() { _ = rsp.Body.Close() }()
+
+		res, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+
+		return rsp.StatusCode, res
+	}
+}
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestLabels(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(transport.New(memory.NewItemRepository()))
+	defer srv.Close()
+
+	send := newSender(srv)
+
+	list := func(t *testing.T, selector string) []string {
+		t.Helper()
+
+		status, res := send(t, http.MethodGet, "/drinks?labelSelector="+url.QueryEscape(selector), "", "")
+		require.Equal(t, http.StatusOK, status)
+
+		names := make([]string, 0)
+
+		for _, name := range gjson.GetBytes(res, "items.#.name").Array() {
+			names = append(names, name.String())
+		}
+
+		return names
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/drinks", "application/json",
+			`{"name": "tea", "metadata": {"labels": {"kind": "hot", "example.com/origin": "china"}, "annotations": {"note": "any text, even with spaces"}}, "price": 2}`)
+		require.Equal(t, http.StatusCreated, status)
+		assert.JSONEq(t, `{"kind": "hot", "example.com/origin": "china"}`, gjson.GetBytes(res, "metadata.labels").Raw)
+		assert.Equal(t, "any text, even with spaces", gjson.GetBytes(res, "metadata.annotations.note").String())
+		assert.False(t, gjson.GetBytes(res, "data.labels").Exists())
+
+		status, _ = send(t, http.MethodPost, "/drinks", "application/json", `{"name": "coffee", "metadata": {"labels": {"kind": "hot", "caffeine": "high"}}}`)
+		require.Equal(t, http.StatusCreated, status)
+
+		status, _ = send(t, http.MethodPost, "/drinks", "application/json", `{"name": "lemonade", "metadata": {"labels": {"kind": "cold"}}}`)
+		require.Equal(t, http.StatusCreated, status)
+
+		status, _ = send(t, http.MethodPost, "/drinks", "application/json", `{"name": "water"}`)
+		require.Equal(t, http.StatusCreated, status)
+	})
+
+	t.Run("Invalid Label", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/drinks", "application/json", `{"name": "juice", "metadata": {"labels": {"bad key": "x"}}}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, transport.CodeLabelInvalid, gjson.GetBytes(res, "code").String())
+		assert.Equal(t, "metadata.labels.bad key", gjson.GetBytes(res, "invalidParams.0.name").String())
+
+		status, res = send(t, http.MethodPost, "/drinks", "application/json", `{"name": "juice", "metadata": {"labels": {"kind": "`+strings.Repeat("a", 64)+`"}}}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, transport.CodeLabelInvalid, gjson.GetBytes(res, "code").String())
+
+		status, _ = send(t, http.MethodPatch, "/drinks/tea", "application/merge-patch+json", `{"metadata": {"labels": {"-kind": "hot"}}}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("Data Fields", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/snacks", "application/json", `{"name": "chips", "labels": ["salty"], "finalizers": 2}`)
+		assert.Equal(t, http.StatusCreated, status)
+		assert.Equal(t, `["salty"]`, gjson.GetBytes(res, "labels").Raw)
+		assert.Equal(t, int64(2), gjson.GetBytes(res, "finalizers").Int())
+		assert.False(t, gjson.GetBytes(res, "metadata").Exists())
+
+		status, res = send(t, http.MethodPost, "/snacks", "application/json", `{"name": "nuts", "metadata": {"label": {"kind": "salty"}}}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, transport.CodeBodyInvalid, gjson.GetBytes(res, "code").String())
+	})
+
+	t.Run("Select", func(t *testing.T) {
+		assert.Equal(t, []string{"coffee", "lemonade", "tea", "water"}, list(t, ""))
+		assert.Equal(t, []string{"coffee", "tea"}, list(t, "kind=hot"))
+		assert.Equal(t, []string{"coffee", "tea"}, list(t, "kind==hot"))
+		assert.Equal(t, []string{"lemonade", "water"}, list(t, "kind!=hot"))
+		assert.Equal(t, []string{"coffee", "lemonade", "tea"}, list(t, "kind in (hot, cold)"))
+		assert.Equal(t, []string{"water"}, list(t, "kind notin (hot,cold)"))
+		assert.Equal(t, []string{"coffee"}, list(t, "caffeine"))
+		assert.Equal(t, []string{"lemonade", "tea", "water"}, list(t, "!caffeine"))
+		assert.Equal(t, []string{"tea"}, list(t, "kind=hot,example.com/origin in (china,india),!caffeine"))
+	})
+
+	t.Run("Invalid Selector", func(t *testing.T) {
+		for _, selector := range []string{"kind in (hot", "kind=hot,", "bad key=x", "kind in ((hot))"} {
+			status, res := send(t, http.MethodGet, "/drinks?labelSelector="+url.QueryEscape(selector), "", "")
+			assert.Equal(t, http.StatusBadRequest, status, selector)
+			assert.Equal(t, transport.CodeParamInvalid, gjson.GetBytes(res, "code").String(), selector)
+			assert.Equal(t, "labelSelector", gjson.GetBytes(res, "invalidParams.0.name").String(), selector)
+		}
+	})
+
+	t.Run("Patch", func(t *testing.T) {
+		status, res := send(t, http.MethodPatch, "/drinks/coffee", "application/merge-patch+json", `{"metadata": {"labels": {"kind": "cold", "caffeine": null}}, "price": 3}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"kind": "cold"}`, gjson.GetBytes(res, "metadata.labels").Raw)
+		assert.Equal(t, 3.0, gjson.GetBytes(res, "price").Float())
+
+		assert.Equal(t, []string{"coffee", "lemonade"}, list(t, "kind=cold"))
+	})
+
+	t.Run("Replace Keeps Labels", func(t *testing.T) {
+		status, res := send(t, http.MethodPut, "/drinks/tea", "application/json", `{"price": 3}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "hot", gjson.GetBytes(res, "metadata.labels.kind").String())
+
+		status, res = send(t, http.MethodPut, "/drinks/tea", "application/json", `{"metadata": {"labels": {"kind": "iced"}}, "price": 3}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"kind": "iced"}`, gjson.GetBytes(res, "metadata.labels").Raw)
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		status, res := send(t, http.MethodGet, "/drinks/_export?labelSelector=kind%3Dcold", "", "")
+		require.Equal(t, http.StatusOK, status)
+
+		lines := strings.Split(strings.TrimSpace(string(res)), "\n")
+		require.Len(t, lines, 2)
+		assert.Equal(t, "coffee", gjson.Get(lines[0], "name").String())
+		assert.Equal(t, "lemonade", gjson.Get(lines[1], "name").String())
+
+		status, _ = send(t, http.MethodGet, "/_export?labelSelector=kind+in+(", "", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("GraphQL", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/graphql", "application/json", `{"query": "{ item(type: \"drink\", name: \"lemonade\") { labels } }"}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"kind": "cold"}`, gjson.GetBytes(res, "data.item.labels").Raw)
+	})
+}
@@ -0,0 +1,146 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestOwnerReferences(t *testing.T) {
+	t.Parallel()
+
+	h := transport.New(memory.NewItemRepository())
+	defer func() { _ = h.Close() }()
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	send := newSender(srv)
+
+	create := func(t *testing.T, path, body string) string {
+		t.Helper()
+
+		status, res := send(t, http.MethodPost, path, "application/json", body)
+		require.Equal(t, http.StatusCreated, status, string(res))
+
+		return gjson.GetBytes(res, "uuid").String()
+	}
+
+	owner := func(typ, name, uuid string) string {
+		return `{"type": "` + typ + `", "name": "` + name + `", "uuid": "` + uuid + `"}`
+	}
+
+	exists := func(t *testing.T, path string) bool {
+		t.Helper()
+
+		status, _ := send(t, http.MethodGet, path, "", "")
+
+		return status == http.StatusOK
+	}
+
+	t.Run("Invalid Owner", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/lines", "application/json", `{"name": "line-0", "metadata": {"ownerReferences": [{"type": "order", "name": "order-0", "uuid": "nope"}]}}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, transport.CodeOwnerInvalid, gjson.GetBytes(res, "code").String())
+
+		status, res = send(t, http.MethodPost, "/lines", "application/json", `{"name": "line-0", "metadata": {"ownerReferences": [`+owner("order", "order-0", "2f1c7e4a-6b0e-4a8e-9b1e-1f0b6c7d8e9f")+`]}}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "metadata.ownerReferences.0", gjson.GetBytes(res, "invalidParams.0.name").String())
+	})
+
+	t.Run("Background", func(t *testing.T) {
+		orderUUID := create(t, "/orders", `{"name": "order-1"}`)
+		lineUUID := create(t, "/lines", `{"name": "line-1", "metadata": {"ownerReferences": [`+owner("order", "order-1", orderUUID)+`]}}`)
+		create(t, "/notes", `{"name": "note-1", "metadata": {"ownerReferences": [`+owner("line", "line-1", lineUUID)+`]}}`)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/notes?watch=true", nil)
+		require.NoError(t, err)
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		defer func() { _ = rsp.Body.Close() }()
+
+		status, _ := send(t, http.MethodDelete, "/orders/order-1", "", "")
+		require.Equal(t, http.StatusNoContent, status)
+
+		line, err := bufio.NewReader(rsp.Body).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "DELETED", gjson.Get(line, "type").String())
+		assert.Equal(t, "note-1", gjson.Get(line, "item.name").String())
+
+		assert.Eventually(t, func() bool { return !exists(t, "/lines/line-1") }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Foreground", func(t *testing.T) {
+		orderUUID := create(t, "/orders", `{"name": "order-2"}`)
+		create(t, "/lines", `{"name": "line-2", "metadata": {"ownerReferences": [`+owner("order", "order-2", orderUUID)+`]}}`)
+
+		status, _ := send(t, http.MethodDelete, "/orders/order-2?propagationPolicy=Foreground", "", "")
+		require.Equal(t, http.StatusNoContent, status)
+
+		assert.False(t, exists(t, "/lines/line-2"))
+
+		status, _ = send(t, http.MethodDelete, "/orders/order-2?propagationPolicy=Orphan", "", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("Foreground With Finalizers", func(t *testing.T) {
+		orderUUID := create(t, "/orders", `{"name": "order-5", "metadata": {"finalizers": ["example.com/invoice"]}}`)
+		create(t, "/lines", `{"name": "line-5", "metadata": {"ownerReferences": [`+owner("order", "order-5", orderUUID)+`]}}`)
+
+		status, res := send(t, http.MethodDelete, "/orders/order-5?propagationPolicy=Foreground", "", "")
+		require.Equal(t, http.StatusAccepted, status)
+		assert.NotEmpty(t, gjson.GetBytes(res, "metadata.deletionTimestamp").String())
+
+		assert.False(t, exists(t, "/lines/line-5"))
+		assert.True(t, exists(t, "/orders/order-5"))
+	})
+
+	t.Run("Other Owners", func(t *testing.T) {
+		orderUUID := create(t, "/orders", `{"name": "order-3"}`)
+		cartUUID := create(t, "/carts", `{"name": "cart-3"}`)
+		create(t, "/lines", `{"name": "line-3", "metadata": {"ownerReferences": [`+owner("order", "order-3", orderUUID)+`, `+owner("cart", "cart-3", cartUUID)+`]}}`)
+
+		status, _ := send(t, http.MethodDelete, "/orders/order-3?propagationPolicy=Foreground", "", "")
+		require.Equal(t, http.StatusNoContent, status)
+
+		status, res := send(t, http.MethodGet, "/lines/line-3", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `["cart-3"]`, gjson.GetBytes(res, "metadata.ownerReferences.#.name").Raw)
+
+		status, _ = send(t, http.MethodDelete, "/carts/cart-3", "", "")
+		require.Equal(t, http.StatusNoContent, status)
+
+		assert.Eventually(t, func() bool { return !exists(t, "/lines/line-3") }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/lines/_import", "application/x-ndjson", `{"name": "line-4", "metadata": {"ownerReferences": [`+owner("order", "order-4", "2f1c7e4a-6b0e-4a8e-9b1e-1f0b6c7d8e9f")+`]}}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.EqualValues(t, 1, gjson.GetBytes(res, "failed").Int())
+		assert.Equal(t, transport.CodeOwnerInvalid, gjson.GetBytes(res, "errors.0.code").String())
+
+		orderUUID := create(t, "/orders", `{"name": "order-4"}`)
+		create(t, "/lines", `{"name": "line-4", "metadata": {"ownerReferences": [`+owner("order", "order-4", orderUUID)+`]}}`)
+
+		// pruned owners are deleted like by the delete endpoint, so their dependents are collected
+		status, res = send(t, http.MethodPost, "/orders/_import?mode=replace-all", "application/x-ndjson", `{"name": "order-5"}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.EqualValues(t, 1, gjson.GetBytes(res, "deleted").Int())
+
+		assert.Eventually(t, func() bool { return !exists(t, "/lines/line-4") }, time.Second, 10*time.Millisecond)
+	})
+}
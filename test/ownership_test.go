@@ -0,0 +1,87 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestFieldOwnership(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(transport.New(memory.NewItemRepository()))
+	defer srv.Close()
+
+	send := newSender(srv)
+
+	t.Run("Create", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/drinks?fieldManager=agent-a", "application/json", `{"name": "tea", "price": 2, "sizes": {"small": 1}}`)
+		require.Equal(t, http.StatusCreated, status)
+		assert.JSONEq(t, `{"price": "agent-a", "sizes.small": "agent-a"}`, gjson.GetBytes(res, "metadata.managedFields").Raw)
+	})
+
+	t.Run("Patch Other Fields", func(t *testing.T) {
+		status, res := send(t, http.MethodPatch, "/drinks/tea?fieldManager=agent-b", "application/merge-patch+json", `{"origin": "china", "sizes": {"large": 2}}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"price": "agent-a", "sizes.small": "agent-a", "sizes.large": "agent-b", "origin": "agent-b"}`, gjson.GetBytes(res, "metadata.managedFields").Raw)
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		status, res := send(t, http.MethodPatch, "/drinks/tea?fieldManager=agent-b", "application/merge-patch+json", `{"price": 3, "sizes": {"small": 2}}`)
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, transport.CodeFieldConflict, gjson.GetBytes(res, "code").String())
+		assert.Equal(t, `["price","sizes.small"]`, gjson.GetBytes(res, "invalidParams.#.name").Raw)
+		assert.Equal(t, "is managed by 'agent-a'", gjson.GetBytes(res, "invalidParams.0.reason").String())
+
+		status, _ = send(t, http.MethodPatch, "/drinks/tea?fieldManager=agent-a", "application/json-patch+json", `[{"op": "remove", "path": "/origin"}]`)
+		assert.Equal(t, http.StatusConflict, status)
+
+		status, _ = send(t, http.MethodPut, "/drinks/tea?fieldManager=agent-a", "application/json", `{"price": 2, "sizes": {"small": 1}}`)
+		assert.Equal(t, http.StatusConflict, status)
+
+		// writing a field with its current value doesn't conflict
+		status, _ = send(t, http.MethodPatch, "/drinks/tea?fieldManager=agent-b", "application/merge-patch+json", `{"price": 2}`)
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("Force", func(t *testing.T) {
+		status, res := send(t, http.MethodPatch, "/drinks/tea?fieldManager=agent-b&force=true", "application/merge-patch+json", `{"price": 3}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 3.0, gjson.GetBytes(res, "price").Float())
+		assert.Equal(t, "agent-b", gjson.GetBytes(res, "metadata.managedFields.price").String())
+
+		status, res = send(t, http.MethodPatch, "/drinks/tea?force=maybe", "application/merge-patch+json", `{"price": 3}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, transport.CodeParamInvalid, gjson.GetBytes(res, "code").String())
+	})
+
+	t.Run("Without Field Manager", func(t *testing.T) {
+		// plain writes, even from different clients, are neither checked nor recorded
+		status, _ := send(t, http.MethodPatch, "/drinks/tea", "application/merge-patch+json", `{"origin": "kenya", "sizes": null}`, "User-Agent", "curl/8.5.0")
+		require.Equal(t, http.StatusOK, status)
+
+		status, _ = send(t, http.MethodPatch, "/drinks/tea", "application/merge-patch+json", `{"origin": "india"}`)
+		require.Equal(t, http.StatusOK, status)
+
+		status, res := send(t, http.MethodGet, "/drinks/tea", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"price": "agent-b"}`, gjson.GetBytes(res, "metadata.managedFields").Raw)
+	})
+
+	t.Run("GraphQL", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/graphql", "application/json",
+			`{"query": "mutation { patchItem(type: \"drink\", name: \"tea\", patch: {price: 4}, fieldManager: \"agent-a\") { price: data } }"}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, transport.CodeFieldConflict, gjson.GetBytes(res, "errors.0.extensions.code").String())
+
+		status, res = send(t, http.MethodPost, "/graphql", "application/json", `{"query": "{ item(type: \"drink\", name: \"tea\") { managedFields } }"}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"price": "agent-b"}`, gjson.GetBytes(res, "data.item.managedFields").Raw)
+	})
+}
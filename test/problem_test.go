@@ -0,0 +1,89 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestProblem(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.NewItemRepository()
+
+	h := transport.New(repo)
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	rsp, err := http.Post(srv.URL+"/drinks", "application/json", bytes.NewBufferString(`{"name": "tea"}`))
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		contentType  string
+		body         string
+		status       int
+		code         string
+		invalidParam string
+	}{
+		{name: "Not plural", method: http.MethodGet, path: "/drink", status: http.StatusBadRequest, code: "type.not_plural", invalidParam: "typePlural"},
+		{name: "Invalid name", method: http.MethodGet, path: "/drinks/Tea", status: http.StatusBadRequest, code: "name.invalid", invalidParam: "name"},
+		{name: "Not found", method: http.MethodGet, path: "/drinks/coffee", status: http.StatusNotFound, code: "item.not_found"},
+		{name: "Name required", method: http.MethodPost, path: "/drinks", contentType: "application/json", body: `{}`, status: http.StatusBadRequest, code: "name.required", invalidParam: "name"},
+		{name: "Already exists", method: http.MethodPost, path: "/drinks", contentType: "application/json", body: `{"name": "tea"}`, status: http.StatusConflict, code: "item.already_exists"},
+		{name: "Invalid body", method: http.MethodPut, path: "/drinks/tea", contentType: "application/json", body: `{`, status: http.StatusBadRequest, code: "body.invalid"},
+		{name: "Unsupported patch", method: http.MethodPatch, path: "/drinks/tea", body: `{}`, status: http.StatusUnsupportedMediaType, code: "media_type.unsupported"},
+		{name: "Failed patch", method: http.MethodPatch, path: "/drinks/tea", contentType: "application/json-patch+json", body: `[{"op": "remove", "path": "/missing"}]`, status: http.StatusUnprocessableEntity, code: "patch.failed"},
+		{name: "Invalid limit", method: http.MethodGet, path: "/drinks?limit=0", status: http.StatusBadRequest, code: "param.invalid", invalidParam: "limit"},
+		{name: "Invalid cursor", method: http.MethodGet, path: "/drinks?cursor=invalid", status: http.StatusBadRequest, code: "param.invalid", invalidParam: "cursor"},
+		{name: "Invalid watch", method: http.MethodGet, path: "/drinks?watch=maybe", status: http.StatusBadRequest, code: "param.invalid", invalidParam: "watch"},
+		{name: "Invalid import mode", method: http.MethodPost, path: "/drinks/_import?mode=merge", status: http.StatusBadRequest, code: "param.invalid", invalidParam: "mode"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, bytes.NewBufferString(tt.body))
+			require.NoError(t, err)
+
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			rsp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer func() { _ = rsp.Body.Close() }()
+
+			assert.Equal(t, tt.status, rsp.StatusCode)
+			assert.Equal(t, "application/problem+json", rsp.Header.Get("Content-Type"))
+
+			res, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.code, gjson.GetBytes(res, "code").String())
+			assert.Equal(t, "urn:core:problem:"+tt.code, gjson.GetBytes(res, "type").String())
+			assert.EqualValues(t, tt.status, gjson.GetBytes(res, "status").Int())
+			assert.Equal(t, http.StatusText(tt.status), gjson.GetBytes(res, "title").String())
+			assert.Equal(t, tt.path, gjson.GetBytes(res, "instance").String())
+			assert.NotEmpty(t, gjson.GetBytes(res, "detail").String())
+
+			if tt.invalidParam != "" {
+				assert.Equal(t, tt.invalidParam, gjson.GetBytes(res, "invalidParams.0.name").String())
+				assert.NotEmpty(t, gjson.GetBytes(res, "invalidParams.0.reason").String())
+			}
+		})
+	}
+}
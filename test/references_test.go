@@ -0,0 +1,153 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestReferences(t *testing.T) {
+	t.Parallel()
+
+	h := transport.New(memory.NewItemRepository())
+
+	err := h.LoadSchemas(strings.NewReader(`
+- type: order
+  references:
+    customer:
+      type: customer
+    lines.product:
+      type: product
+      onDelete: Cascade
+- type: review
+  references:
+    customer:
+      type: customer
+      onDelete: SetNull
+`))
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	send := newSender(srv)
+
+	for _, item := range []struct{ path, body string }{
+		{"/customers", `{"name": "acme"}`},
+		{"/customers", `{"name": "globex"}`},
+		{"/products", `{"name": "anvil"}`},
+		{"/products", `{"name": "rocket"}`},
+	} {
+		status, _ := send(t, http.MethodPost, item.path, "application/json", item.body)
+		require.Equal(t, http.StatusCreated, status)
+	}
+
+	t.Run("Schemas", func(t *testing.T) {
+		status, res := send(t, http.MethodGet, "/_schemas", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `["order","review"]`, gjson.GetBytes(res, "items.#.type").Raw)
+
+		status, res = send(t, http.MethodGet, "/_schemas/orders", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "Cascade", gjson.GetBytes(res, "references.lines\\.product.onDelete").String())
+
+		status, res = send(t, http.MethodPut, "/_schemas/invoices", "application/json", `{"references": {"order": {"type": "order", "onDelete": "Ignore"}}}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, transport.CodeSchemaInvalid, gjson.GetBytes(res, "code").String())
+		assert.Equal(t, "references.order.onDelete", gjson.GetBytes(res, "invalidParams.0.name").String())
+
+		status, _ = send(t, http.MethodPut, "/_schemas/invoices", "application/json", `{"references": {"order": {"type": "order"}}}`)
+		assert.Equal(t, http.StatusCreated, status)
+
+		status, _ = send(t, http.MethodDelete, "/_schemas/invoices", "", "")
+		assert.Equal(t, http.StatusNoContent, status)
+
+		status, res = send(t, http.MethodGet, "/_schemas/invoices", "", "")
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, transport.CodeSchemaNotFound, gjson.GetBytes(res, "code").String())
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-1", "customer": "initech"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, transport.CodeReferenceInvalid, gjson.GetBytes(res, "code").String())
+		assert.Equal(t, "customer", gjson.GetBytes(res, "invalidParams.0.name").String())
+
+		status, res = send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-1", "customer": "acme", "lines": [{"product": "anvil"}, {"product": 7}]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "lines.product", gjson.GetBytes(res, "invalidParams.0.name").String())
+
+		status, _ = send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-1", "customer": "acme", "lines": [{"product": "anvil"}, {"product": "rocket"}]}`)
+		require.Equal(t, http.StatusCreated, status)
+
+		status, _ = send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-2", "customer": "globex", "lines": [{"product": "rocket"}]}`)
+		require.Equal(t, http.StatusCreated, status)
+
+		status, _ = send(t, http.MethodPost, "/reviews", "application/json", `{"name": "review-1", "customer": "acme", "stars": 5}`)
+		require.Equal(t, http.StatusCreated, status)
+
+		status, _ = send(t, http.MethodPatch, "/orders/order-1", "application/merge-patch+json", `{"customer": "initech"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+
+		status, _ = send(t, http.MethodPut, "/orders/order-1", "application/json", `{"customer": "initech"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+
+		// references are optional
+		status, _ = send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-3", "customer": null}`)
+		assert.Equal(t, http.StatusCreated, status)
+	})
+
+	t.Run("Restrict", func(t *testing.T) {
+		status, res := send(t, http.MethodDelete, "/customers/acme", "", "")
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, transport.CodeItemReferenced, gjson.GetBytes(res, "code").String())
+		assert.Equal(t, `["order/order-1"]`, gjson.GetBytes(res, "invalidParams.#.name").Raw)
+
+		status, _ = send(t, http.MethodGet, "/reviews/review-1", "", "")
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("Cascade", func(t *testing.T) {
+		status, _ := send(t, http.MethodDelete, "/products/rocket", "", "")
+		require.Equal(t, http.StatusNoContent, status)
+
+		status, _ = send(t, http.MethodGet, "/orders/order-1", "", "")
+		assert.Equal(t, http.StatusNotFound, status)
+
+		status, _ = send(t, http.MethodGet, "/orders/order-2", "", "")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("Set Null", func(t *testing.T) {
+		status, _ := send(t, http.MethodDelete, "/customers/acme", "", "")
+		require.Equal(t, http.StatusNoContent, status)
+
+		status, res := send(t, http.MethodGet, "/reviews/review-1", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "null", gjson.GetBytes(res, "customer").Raw)
+		assert.Equal(t, 5.0, gjson.GetBytes(res, "stars").Float())
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		status, _ := send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-4", "customer": "globex"}`)
+		require.Equal(t, http.StatusCreated, status)
+
+		// replace-all prunes like deletes, so referenced items are kept
+		status, res := send(t, http.MethodPost, "/customers/_import?mode=replace-all", "application/x-ndjson", `{"name": "initech"}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.EqualValues(t, 0, gjson.GetBytes(res, "deleted").Int())
+		assert.EqualValues(t, 1, gjson.GetBytes(res, "failed").Int())
+		assert.Equal(t, "globex", gjson.GetBytes(res, "errors.0.name").String())
+		assert.Equal(t, transport.CodeItemReferenced, gjson.GetBytes(res, "errors.0.code").String())
+
+		status, _ = send(t, http.MethodGet, "/customers/globex", "", "")
+		assert.Equal(t, http.StatusOK, status)
+	})
+}
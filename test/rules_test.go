@@ -0,0 +1,85 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestRules(t *testing.T) {
+	t.Parallel()
+
+	h := transport.New(memory.NewItemRepository())
+
+	err := h.LoadSchemas(strings.NewReader(`
+- type: promotion
+  rules:
+    - name: endAfterStart
+      rule: timestamp(self.endDate) > timestamp(self.startDate)
+      message: endDate should be after startDate
+    - name: priceDecreasesOnSale
+      rule: "!has(self.status) || self.status != 'sale' || self.price <= oldSelf.price"
+      message: price can only decrease when status is sale
+`))
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	send := newSender(srv)
+
+	t.Run("Invalid Rule", func(t *testing.T) {
+		status, res := send(t, http.MethodPut, "/_schemas/carts", "application/json", `{"rules": [{"name": "broken", "rule": "self.total >"}]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, transport.CodeSchemaInvalid, gjson.GetBytes(res, "code").String())
+		assert.Equal(t, "rules.0.rule", gjson.GetBytes(res, "invalidParams.0.name").String())
+
+		status, res = send(t, http.MethodPut, "/_schemas/carts", "application/json", `{"rules": [{"name": "notBool", "rule": "1 + 2"}]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Contains(t, gjson.GetBytes(res, "invalidParams.0.reason").String(), "bool")
+
+		status, _ = send(t, http.MethodPut, "/_schemas/carts", "application/json", `{"rules": [{"name": "unknown", "rule": "other.total > 0"}]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/promotions", "application/json",
+			`{"name": "promo-0", "startDate": "2024-02-01T00:00:00Z", "endDate": "2024-01-01T00:00:00Z", "price": 10}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, transport.CodeRuleFailed, gjson.GetBytes(res, "code").String())
+		assert.Equal(t, "endAfterStart", gjson.GetBytes(res, "invalidParams.0.name").String())
+		assert.Equal(t, "endDate should be after startDate", gjson.GetBytes(res, "invalidParams.0.reason").String())
+
+		// rules with oldSelf are skipped on creates
+		status, res = send(t, http.MethodPost, "/promotions", "application/json",
+			`{"name": "promo-0", "startDate": "2024-01-01T00:00:00Z", "endDate": "2024-02-01T00:00:00Z", "price": 10, "status": "sale"}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		status, res := send(t, http.MethodPatch, "/promotions/promo-0", "application/merge-patch+json", `{"price": 12}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "priceDecreasesOnSale", gjson.GetBytes(res, "invalidParams.0.name").String())
+		assert.Contains(t, gjson.GetBytes(res, "detail").String(), "price can only decrease when status is sale")
+
+		status, res = send(t, http.MethodPatch, "/promotions/promo-0", "application/merge-patch+json", `{"price": 8}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+
+		status, res = send(t, http.MethodPatch, "/promotions/promo-0", "application/merge-patch+json", `{"price": 12, "status": "regular"}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+	})
+
+	t.Run("Evaluation Error", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/promotions", "application/json", `{"name": "promo-1", "price": 10}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, transport.CodeRuleFailed, gjson.GetBytes(res, "code").String())
+		assert.Equal(t, "endAfterStart", gjson.GetBytes(res, "invalidParams.0.name").String())
+	})
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestScripts(t *testing.T) {
+	t.Parallel()
+
+	h := transport.New(memory.NewItemRepository())
+
+	err := h.LoadSchemas(strings.NewReader(`
+- type: order
+  script: |
+    def beforeCreate(item):
+        if item["data"].get("quantity", 0) <= 0:
+            fail("quantity should be positive")
+        item["data"].setdefault("currency", "EUR")
+
+    def beforeUpdate(item, old):
+        if item["data"]["currency"] != old["data"]["currency"]:
+            fail("currency can't change")
+
+    def beforeDelete(item):
+        if item["data"].get("paid"):
+            fail("paid orders can't be deleted")
+
+    def afterWrite(item):
+        data = item["data"]
+        data["total"] = data["quantity"] * data.get("price", 0)
+- type: tally
+  script: |
+    def afterWrite(item):
+        if item["data"].get("boom"):
+            fail("boom")
+        item["data"]["count"] = len(item["data"].get("entries", []))
+  rules:
+    - name: fewEntries
+      rule: "!('count' in self) || self.count <= 3"
+- type: loop
+  script: |
+    def beforeCreate(item):
+        for i in range(100000000):
+            pass
+- type: hog
+  script: |
+    def beforeCreate(item):
+        chunks = []
+        for i in range(8):
+            chunks.append("a" * (1 << 28))
+`))
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	send := newSender(srv)
+
+	t.Run("Invalid Script", func(t *testing.T) {
+		status, res := send(t, http.MethodPut, "/_schemas/carts", "application/json", `{"script": "def beforeCreate(item)\n  pass"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, transport.CodeSchemaInvalid, gjson.GetBytes(res, "code").String())
+		assert.Equal(t, "script", gjson.GetBytes(res, "invalidParams.0.name").String())
+
+		status, _ = send(t, http.MethodPut, "/_schemas/carts", "application/json", `{"script": "beforeCreate = 1"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+
+		status, _ = send(t, http.MethodPut, "/_schemas/carts", "application/json", `{"script": "load('other.star', 'x')"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+	})
+
+	t.Run("Reject Create", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-0", "quantity": 0}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, transport.CodeScriptRejected, gjson.GetBytes(res, "code").String())
+		assert.Contains(t, gjson.GetBytes(res, "detail").String(), "quantity should be positive")
+
+		status, _ = send(t, http.MethodGet, "/orders/order-0", "", "")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("Defaults And Derived Fields", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-0", "quantity": 2, "price": 1.5}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+		assert.Equal(t, "EUR", gjson.GetBytes(res, "currency").String())
+		assert.Equal(t, 3.0, gjson.GetBytes(res, "total").Float())
+
+		status, res = send(t, http.MethodPatch, "/orders/order-0", "application/merge-patch+json", `{"quantity": 4}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.Equal(t, 6.0, gjson.GetBytes(res, "total").Float())
+
+		status, res = send(t, http.MethodGet, "/orders/order-0", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 6.0, gjson.GetBytes(res, "total").Float())
+	})
+
+	t.Run("After Write", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/tallies", "application/json", `{"name": "tally-0", "entries": ["a", "b"]}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+		assert.EqualValues(t, 2, gjson.GetBytes(res, "count").Int())
+
+		// afterWrite runs once the item is stored, so its failures don't fail the write
+		status, res = send(t, http.MethodPatch, "/tallies/tally-0", "application/merge-patch+json", `{"entries": ["a"], "boom": true}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.True(t, gjson.GetBytes(res, "boom").Bool())
+		assert.EqualValues(t, 2, gjson.GetBytes(res, "count").Int())
+
+		status, res = send(t, http.MethodGet, "/tallies/tally-0", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.True(t, gjson.GetBytes(res, "boom").Bool())
+		assert.EqualValues(t, 2, gjson.GetBytes(res, "count").Int())
+
+		// derived data is checked by rules, so it isn't saved if they reject it
+		status, res = send(t, http.MethodPost, "/tallies", "application/json", `{"name": "tally-1", "entries": ["a", "b", "c", "d"]}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+		assert.False(t, gjson.GetBytes(res, "count").Exists())
+
+		status, res = send(t, http.MethodGet, "/tallies/tally-1", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.False(t, gjson.GetBytes(res, "count").Exists())
+	})
+
+	t.Run("Reject Update", func(t *testing.T) {
+		status, res := send(t, http.MethodPatch, "/orders/order-0", "application/merge-patch+json", `{"currency": "USD"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Contains(t, gjson.GetBytes(res, "detail").String(), "currency can't change")
+	})
+
+	t.Run("Reject Delete", func(t *testing.T) {
+		status, res := send(t, http.MethodPatch, "/orders/order-0", "application/merge-patch+json", `{"paid": true}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+
+		status, res = send(t, http.MethodDelete, "/orders/order-0", "", "")
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Contains(t, gjson.GetBytes(res, "detail").String(), "paid orders can't be deleted")
+
+		status, res = send(t, http.MethodPatch, "/orders/order-0", "application/merge-patch+json", `{"paid": false}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+
+		status, _ = send(t, http.MethodDelete, "/orders/order-0", "", "")
+		assert.Equal(t, http.StatusNoContent, status)
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/loops", "application/json", `{"name": "loop-0"}`)
+		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.Equal(t, transport.CodeScriptFailed, gjson.GetBytes(res, "code").String())
+
+		status, res = send(t, http.MethodPost, "/hogs", "application/json", `{"name": "hog-0"}`)
+		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.Equal(t, transport.CodeScriptFailed, gjson.GetBytes(res, "code").String())
+		assert.Contains(t, gjson.GetBytes(res, "detail").String(), "memory")
+	})
+}
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	h := transport.New(memory.NewItemRepository())
+	defer func() { _ = h.Close() }()
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	send := newSender(srv)
+
+	t.Run("Create Ignores Status", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/jobs", "application/json", `{"name": "job-0", "image": "alpine", "_status": {"phase": "Done"}}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+		assert.False(t, gjson.GetBytes(res, "_status").Exists())
+		assert.Equal(t, "alpine", gjson.GetBytes(res, "image").String())
+	})
+
+	t.Run("Replace Status", func(t *testing.T) {
+		status, res := send(t, http.MethodPut, "/jobs/job-0/status", "application/json", `{"image": "busybox", "_status": {"phase": "Running", "progress": 10}}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.Equal(t, "Running", gjson.GetBytes(res, "_status.phase").String())
+		assert.Equal(t, "alpine", gjson.GetBytes(res, "image").String())
+
+		status, res = send(t, http.MethodGet, "/jobs/job-0/status", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, int64(10), gjson.GetBytes(res, "_status.progress").Int())
+	})
+
+	t.Run("Item Endpoints Ignore Status", func(t *testing.T) {
+		status, res := send(t, http.MethodPut, "/jobs/job-0", "application/json", `{"image": "busybox", "_status": {"phase": "Failed"}}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.Equal(t, "busybox", gjson.GetBytes(res, "image").String())
+		assert.Equal(t, "Running", gjson.GetBytes(res, "_status.phase").String())
+
+		status, res = send(t, http.MethodPatch, "/jobs/job-0", "application/merge-patch+json", `{"retries": 2, "_status": null}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.Equal(t, int64(2), gjson.GetBytes(res, "retries").Int())
+		assert.Equal(t, "Running", gjson.GetBytes(res, "_status.phase").String())
+	})
+
+	t.Run("Patch Status", func(t *testing.T) {
+		status, res := send(t, http.MethodPatch, "/jobs/job-0/status", "application/merge-patch+json", `{"retries": 5, "_status": {"progress": 50}}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.Equal(t, "Running", gjson.GetBytes(res, "_status.phase").String())
+		assert.Equal(t, int64(50), gjson.GetBytes(res, "_status.progress").Int())
+		assert.Equal(t, int64(2), gjson.GetBytes(res, "retries").Int())
+
+		status, res = send(t, http.MethodPatch, "/jobs/job-0/status", "application/json-patch+json", `[{"op": "replace", "path": "/_status/phase", "value": "Done"}]`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.Equal(t, "Done", gjson.GetBytes(res, "_status.phase").String())
+
+		status, _ = send(t, http.MethodPatch, "/jobs/job-0/status", "application/apply-patch+yaml", `_status: {}`)
+		assert.Equal(t, http.StatusUnsupportedMediaType, status)
+	})
+
+	t.Run("Status Data Field", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/jobs", "application/json", `{"name": "job-2", "status": "draft"}`)
+		require.Equal(t, http.StatusCreated, status, string(res))
+		assert.Equal(t, "draft", gjson.GetBytes(res, "status").String())
+
+		status, res = send(t, http.MethodPut, "/jobs/job-2/status", "application/json", `{"_status": {"phase": "Running"}}`)
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.Equal(t, "draft", gjson.GetBytes(res, "status").String())
+		assert.Equal(t, "Running", gjson.GetBytes(res, "_status.phase").String())
+	})
+
+	t.Run("Missing Item", func(t *testing.T) {
+		status, _ := send(t, http.MethodGet, "/jobs/job-1/status", "", "")
+		assert.Equal(t, http.StatusNotFound, status)
+
+		status, _ = send(t, http.MethodPut, "/jobs/job-1/status", "application/json", `{"_status": {}}`)
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nasermirzaei89/core/internal/repository/memory"
+	"github.com/nasermirzaei89/core/internal/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestWorkflows(t *testing.T) {
+	t.Parallel()
+
+	h := transport.New(memory.NewItemRepository())
+
+	err := h.LoadSchemas(strings.NewReader(`
+- type: order
+  workflow:
+    field: status
+    states: [draft, submitted, approved, shipped]
+    initial: draft
+    transitions:
+      - from: [draft]
+        to: submitted
+        guard: size(self.lines) > 0
+      - from: [submitted]
+        to: approved
+        roles: [manager]
+      - from: [submitted]
+        to: draft
+      - from: [approved]
+        to: shipped
+  script: |
+    def beforeCreate(item):
+        item["data"]["createdAs"] = item["data"]["status"]
+`))
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	send := newSender(srv)
+
+	t.Run("Invalid Workflow", func(t *testing.T) {
+		status, res := send(t, http.MethodPut, "/_schemas/tickets", "application/json",
+			`{"workflow": {"field": "state", "states": ["open", "closed"], "initial": "open", "transitions": [{"from": ["open"], "to": "done"}]}}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, transport.CodeSchemaInvalid, gjson.GetBytes(res, "code").String())
+		assert.Equal(t, "workflow.transitions.0.to", gjson.GetBytes(res, "invalidParams.0.name").String())
+
+		status, res = send(t, http.MethodPut, "/_schemas/tickets", "application/json",
+			`{"workflow": {"field": "state", "states": ["open", "closed"], "initial": "open", "transitions": [{"from": ["open"], "to": "closed", "guard": "self.done +"}]}}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "workflow.transitions.0.guard", gjson.GetBytes(res, "invalidParams.0.name").String())
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-0", "status": "approved", "lines": []}`)
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, transport.CodeTransitionInvalid, gjson.GetBytes(res, "code").String())
+
+		status, res = send(t, http.MethodPost, "/orders", "application/json", `{"name": "order-0", "lines": []}`, "X-User", "alice")
+		require.Equal(t, http.StatusCreated, status, string(res))
+		assert.Equal(t, "draft", gjson.GetBytes(res, "status").String())
+		// hooks and webhooks see the initial state of new items
+		assert.Equal(t, "draft", gjson.GetBytes(res, "createdAs").String())
+	})
+
+	t.Run("Invalid Transition", func(t *testing.T) {
+		status, res := send(t, http.MethodPatch, "/orders/order-0", "application/merge-patch+json", `{"status": "shipped"}`)
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, transport.CodeTransitionInvalid, gjson.GetBytes(res, "code").String())
+
+		status, _ = send(t, http.MethodPut, "/orders/order-0", "application/json", `{"name": "order-0", "status": "approved", "lines": []}`)
+		assert.Equal(t, http.StatusConflict, status)
+
+		status, _ = send(t, http.MethodPost, "/orders/order-0:transition?to=lost", "", "")
+		assert.Equal(t, http.StatusConflict, status)
+
+		status, _ = send(t, http.MethodPost, "/orders/order-0:transition", "", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("Guard", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/orders/order-0:transition?to=submitted", "", "")
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Contains(t, gjson.GetBytes(res, "detail").String(), "size(self.lines) > 0")
+
+		status, res = send(t, http.MethodPatch, "/orders/order-0", "application/merge-patch+json", `{"status": "submitted", "lines": [{"sku": "pen"}]}`, "X-User", "alice")
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.Equal(t, "submitted", gjson.GetBytes(res, "status").String())
+	})
+
+	t.Run("Roles", func(t *testing.T) {
+		status, res := send(t, http.MethodPost, "/orders/order-0:transition?to=approved", "", "", "X-Roles", "clerk")
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, transport.CodeTransitionForbidden, gjson.GetBytes(res, "code").String())
+
+		status, res = send(t, http.MethodPost, "/orders/order-0:transition?to=approved", "", "", "X-Roles", "clerk,manager", "X-User", "bob")
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.Equal(t, "approved", gjson.GetBytes(res, "status").String())
+	})
+
+	t.Run("History", func(t *testing.T) {
+		status, res := send(t, http.MethodGet, "/orders/order-0/transitions", "", "")
+		require.Equal(t, http.StatusOK, status, string(res))
+		assert.Equal(t, int64(3), gjson.GetBytes(res, "items.#").Int())
+		assert.Equal(t, "", gjson.GetBytes(res, "items.0.from").String())
+		assert.Equal(t, "draft", gjson.GetBytes(res, "items.0.to").String())
+		assert.Equal(t, "draft", gjson.GetBytes(res, "items.1.from").String())
+		assert.Equal(t, "submitted", gjson.GetBytes(res, "items.1.to").String())
+		assert.Equal(t, "alice", gjson.GetBytes(res, "items.1.actor").String())
+		assert.Equal(t, "approved", gjson.GetBytes(res, "items.2.to").String())
+		assert.Equal(t, "bob", gjson.GetBytes(res, "items.2.actor").String())
+
+		status, res = send(t, http.MethodGet, "/orders/order-0", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, int64(3), gjson.GetBytes(res, "metadata.transitions.#").Int())
+
+		// updates that keep the state aren't transitions
+		status, _ = send(t, http.MethodPatch, "/orders/order-0", "application/merge-patch+json", `{"note": "fragile"}`)
+		require.Equal(t, http.StatusOK, status)
+
+		status, res = send(t, http.MethodGet, "/orders/order-0/transitions", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, int64(3), gjson.GetBytes(res, "items.#").Int())
+	})
+
+	t.Run("No Workflow", func(t *testing.T) {
+		status, _ := send(t, http.MethodPost, "/products", "application/json", `{"name": "product-0"}`)
+		require.Equal(t, http.StatusCreated, status)
+
+		status, res := send(t, http.MethodPost, "/products/product-0:transition?to=sold", "", "")
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, transport.CodeWorkflowNotFound, gjson.GetBytes(res, "code").String())
+	})
+}